@@ -30,4 +30,28 @@ zip ../../../lambda/break_egg.zip bootstrap
 rm bootstrap
 cd ../../..
 
+cd cmd/lambda/egg_history
+GOOS=linux GOARCH=amd64 CGO_ENABLED=0 go build -o bootstrap main.go
+zip ../../../lambda/egg_history.zip bootstrap
+rm bootstrap
+cd ../../..
+
+cd cmd/lambda/teams
+GOOS=linux GOARCH=amd64 CGO_ENABLED=0 go build -o bootstrap main.go
+zip ../../../lambda/teams.zip bootstrap
+rm bootstrap
+cd ../../..
+
+cd cmd/lambda/audit
+GOOS=linux GOARCH=amd64 CGO_ENABLED=0 go build -o bootstrap main.go
+zip ../../../lambda/audit.zip bootstrap
+rm bootstrap
+cd ../../..
+
+cd cmd/lambda/discovery
+GOOS=linux GOARCH=amd64 CGO_ENABLED=0 go build -o bootstrap main.go
+zip ../../../lambda/discovery.zip bootstrap
+rm bootstrap
+cd ../../..
+
 echo "Lambda functions built successfully!"
\ No newline at end of file
@@ -0,0 +1,147 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+// MaxBatchSize is the most secrets the API takes in one batch request.
+// PutEggs and BreakEggs split larger batches into several requests.
+const MaxBatchSize = 100
+
+// BatchPutItem is one secret for PutEggs
+type BatchPutItem struct {
+	Key   string
+	Value string
+	PutOptions
+}
+
+// BatchResult is the outcome for one secret of a batch. Status is what the
+// single secret request would have returned.
+type BatchResult struct {
+	SecretID  string `json:"secret_id"`
+	Status    int    `json:"status"`
+	Version   int    `json:"version,omitempty"`
+	PurgeAt   string `json:"purge_at,omitempty"`
+	Code      string `json:"code,omitempty"`
+	Message   string `json:"message,omitempty"`
+	Retryable bool   `json:"retryable,omitempty"`
+}
+
+// Err returns the secret's failure as an *APIError, or nil if it succeeded
+func (r BatchResult) Err() error {
+	if r.Status < 300 {
+		return nil
+	}
+	code := r.Code
+	if code == "" {
+		code = codeForStatus(r.Status)
+	}
+	return &APIError{StatusCode: r.Status, Code: code, Message: r.Message, Retryable: r.Retryable}
+}
+
+// batchResponse is the body of a batch response
+type batchResponse struct {
+	Results []BatchResult `json:"results"`
+}
+
+type batchPutItem struct {
+	SecretID  string `json:"secret_id"`
+	Plaintext string `json:"plaintext"`
+	ExpiresAt string `json:"expires_at,omitempty"`
+	IfAbsent  bool   `json:"if_absent,omitempty"`
+	IfMatch   int    `json:"if_match,omitempty"`
+}
+
+type batchRequest struct {
+	Project   string         `json:"project,omitempty"`
+	Env       string         `json:"env,omitempty"`
+	Team      string         `json:"team,omitempty"`
+	Eggs      []batchPutItem `json:"eggs,omitempty"`
+	SecretIDs []string       `json:"secret_ids,omitempty"`
+}
+
+func newBatchRequest(owner string, namespace Namespace) batchRequest {
+	request := batchRequest{Project: namespace.Project, Env: namespace.Env}
+	if team, ok := strings.CutPrefix(owner, teamOwnerPrefix); ok {
+		request.Team = team
+	}
+	return request
+}
+
+// PutEggs stores several secrets in one vault, each with its own options,
+// in as few requests as possible. The results line up with items; each
+// secret succeeds or fails on its own (see BatchResult.Err). An error means
+// a whole request failed, and the results cover the secrets before it.
+func (c *Client) PutEggs(owner string, namespace Namespace, items []BatchPutItem) ([]BatchResult, error) {
+	var results []BatchResult
+	for chunk := range slices.Chunk(items, MaxBatchSize) {
+		request := newBatchRequest(owner, namespace)
+		for _, item := range chunk {
+			egg := batchPutItem{
+				SecretID:  item.Key,
+				Plaintext: item.Value,
+				IfAbsent:  item.IfAbsent,
+				IfMatch:   item.IfMatch,
+			}
+			if !item.ExpiresAt.IsZero() {
+				egg.ExpiresAt = item.ExpiresAt.UTC().Format(time.RFC3339)
+			}
+			request.Eggs = append(request.Eggs, egg)
+		}
+		chunkResults, err := c.batch("POST", "/eggs:batch", request)
+		if err != nil {
+			return results, fmt.Errorf("failed to put eggs: %w", err)
+		}
+		results = append(results, chunkResults...)
+	}
+	return results, nil
+}
+
+// BreakEggs moves several secrets of one vault to the trash, or deletes
+// them permanently if purge is set. Results and errors are as for PutEggs.
+func (c *Client) BreakEggs(owner string, namespace Namespace, keys []string, purge bool) ([]BatchResult, error) {
+	path := "/eggs:batch"
+	if purge {
+		path += "?" + url.Values{"purge": {"true"}}.Encode()
+	}
+	var results []BatchResult
+	for chunk := range slices.Chunk(keys, MaxBatchSize) {
+		request := newBatchRequest(owner, namespace)
+		request.SecretIDs = chunk
+		chunkResults, err := c.batch("DELETE", path, request)
+		if err != nil {
+			return results, fmt.Errorf("failed to break eggs: %w", err)
+		}
+		results = append(results, chunkResults...)
+	}
+	return results, nil
+}
+
+func (c *Client) batch(method, path string, request batchRequest) ([]BatchResult, error) {
+	data, err := json.Marshal(request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+	resp, err := c.doRequest(method, path, bytes.NewBuffer(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, newAPIError(resp)
+	}
+
+	var response batchResponse
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return response.Results, nil
+}
@@ -7,7 +7,10 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"strconv"
 	"strings"
+	"time"
 )
 
 // Client represents the API client for Lambda functions
@@ -26,67 +29,243 @@ func NewClient(baseURL, accessToken string) *Client {
 	}
 }
 
+// Namespace scopes secrets to a project and environment. The zero Namespace
+// is the flat list of secrets that don't belong to any project.
+type Namespace struct {
+	Project string
+	Env     string
+}
+
+// IsZero reports whether n is the unscoped namespace
+func (n Namespace) IsZero() bool {
+	return n.Project == "" && n.Env == ""
+}
+
+// String formats n as project/env
+func (n Namespace) String() string {
+	return n.Project + "/" + n.Env
+}
+
+// query returns the query string selecting n, merged with extra
+func (n Namespace) query(extra url.Values) string {
+	values := url.Values{}
+	for k, v := range extra {
+		values[k] = v
+	}
+	if n.Project != "" {
+		values.Set("project", n.Project)
+	}
+	if n.Env != "" {
+		values.Set("env", n.Env)
+	}
+	if len(values) == 0 {
+		return ""
+	}
+	return "?" + values.Encode()
+}
+
 // PutEggRequest represents the request body for storing a secret
 type PutEggRequest struct {
 	SecretID  string `json:"secret_id"`
 	Plaintext string `json:"plaintext"`
+	Project   string `json:"project,omitempty"`
+	Env       string `json:"env,omitempty"`
+	Team      string `json:"team,omitempty"`
+	ExpiresAt string `json:"expires_at,omitempty"`
 }
 
 // GetEggResponse represents the response from getting a secret
 type GetEggResponse struct {
 	Owner     string `json:"owner"`
 	SecretID  string `json:"secret_id"`
+	Project   string `json:"project,omitempty"`
+	Env       string `json:"env,omitempty"`
 	Plaintext string `json:"plaintext"`
+	Version   int    `json:"version"`
 	CreatedAt string `json:"created_at"`
+	ExpiresAt string `json:"expires_at,omitempty"`
 }
 
 // GetEggsResponse represents the response containing multiple secrets
 type GetEggsResponse struct {
-	Eggs []GetEggResponse `json:"eggs"`
+	Eggs       []GetEggResponse `json:"eggs"`
+	NextCursor string           `json:"next_cursor,omitempty"` // Empty on the last page
+}
+
+// PutEggResponse represents the response from storing a secret
+type PutEggResponse struct {
+	Message   string `json:"message"`
+	Owner     string `json:"owner"`
+	SecretID  string `json:"secret_id"`
+	Version   int    `json:"version"`
+	CreatedAt string `json:"created_at"`
+	ExpiresAt string `json:"expires_at,omitempty"`
+}
+
+// PutOptions are the optional parts of storing a secret
+type PutOptions struct {
+	ExpiresAt time.Time // Zero means the secret never expires
+	IfAbsent  bool      // Fail with ErrConflict if the secret already exists
+	IfMatch   int       // If set, fail with ErrConflict unless the secret is at this version
 }
 
 // PutEgg stores a secret by calling POST /eggs endpoint
-// Note: owner is extracted from the JWT token by the Lambda function
-func (c *Client) PutEgg(owner, key, value string) error {
+// Note: a personal owner is extracted from the JWT token by the Lambda
+// function; a team owner (see TeamOwner) is sent as the team name.
+func (c *Client) PutEgg(owner string, namespace Namespace, key, value string, opts PutOptions) (*PutEggResponse, error) {
 	request := PutEggRequest{
 		SecretID:  key,
 		Plaintext: value,
+		Project:   namespace.Project,
+		Env:       namespace.Env,
+	}
+	if !opts.ExpiresAt.IsZero() {
+		request.ExpiresAt = opts.ExpiresAt.UTC().Format(time.RFC3339)
+	}
+	if team, ok := strings.CutPrefix(owner, teamOwnerPrefix); ok {
+		request.Team = team
 	}
 
 	data, err := json.Marshal(request)
 	if err != nil {
-		return fmt.Errorf("failed to marshal request: %w", err)
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	req, err := c.doRequest("POST", "/eggs", bytes.NewBuffer(data))
+	headers := preconditions(opts.IfMatch)
+	if opts.IfAbsent {
+		headers.Set("If-None-Match", "*")
+	}
+	req, err := c.doRequestWithHeaders("POST", "/eggs", bytes.NewBuffer(data), headers)
 	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
+		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 	defer req.Body.Close()
 
 	if req.StatusCode != http.StatusOK && req.StatusCode != http.StatusCreated {
-		body, _ := io.ReadAll(req.Body)
-		return fmt.Errorf("failed to put egg (status %d) %s", req.StatusCode, body)
+		return nil, fmt.Errorf("failed to put egg: %w", newAPIError(req))
 	}
 
-	return nil
+	var response PutEggResponse
+	if err := json.NewDecoder(req.Body).Decode(&response); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return &response, nil
+}
+
+// preconditions returns the headers that make a write conditional on the
+// secret being at version, or none if version is 0.
+func preconditions(version int) http.Header {
+	headers := http.Header{}
+	if version > 0 {
+		headers.Set("If-Match", strconv.Quote(strconv.Itoa(version)))
+	}
+	return headers
+}
+
+// GetEgg retrieves all secrets for an owner, or only those in namespace if
+// it isn't zero. See Eggs to handle them a page at a time.
+func (c *Client) GetEgg(owner string, namespace Namespace) ([]GetEggResponse, error) {
+	var eggs []GetEggResponse
+	for egg, err := range c.Eggs(owner, namespace) {
+		if err != nil {
+			return nil, err
+		}
+		eggs = append(eggs, egg)
+	}
+	return eggs, nil
+}
+
+// GetEggByID retrieves and decrypts a single secret
+func (c *Client) GetEggByID(owner string, namespace Namespace, secretID string) (*GetEggResponse, error) {
+	resp, err := c.doRequest("GET", fmt.Sprintf("/eggs/%s/%s%s", owner, url.PathEscape(secretID), namespace.query(nil)), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("secret '%s': %w", secretID, newAPIError(resp))
+	}
+
+	var response GetEggResponse
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return &response, nil
+}
+
+// BreakEggResponse represents the response from breaking a secret
+type BreakEggResponse struct {
+	Message  string `json:"message"`
+	Owner    string `json:"owner"`
+	SecretID string `json:"secret_id"`
+	PurgeAt  string `json:"purge_at,omitempty"`
+}
+
+// BreakOptions are the optional parts of breaking a secret
+type BreakOptions struct {
+	Purge   bool // Delete permanently instead of moving to the trash
+	IfMatch int  // If set, fail with ErrConflict unless the secret is at this version
+}
+
+// BreakEgg moves a specific secret to the trash, or deletes it permanently
+// if opts.Purge is set
+func (c *Client) BreakEgg(owner string, namespace Namespace, secretID string, opts BreakOptions) (*BreakEggResponse, error) {
+	var extra url.Values
+	if opts.Purge {
+		extra = url.Values{"purge": {"true"}}
+	}
+	resp, err := c.doRequestWithHeaders("DELETE", fmt.Sprintf("/eggs/%s/%s%s", owner, url.PathEscape(secretID), namespace.query(extra)), nil, preconditions(opts.IfMatch))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("secret '%s': %w", secretID, newAPIError(resp))
+	}
+
+	var response BreakEggResponse
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return &response, nil
 }
 
-// GetEgg retrieves all secrets for an owner
-func (c *Client) GetEgg(owner string) ([]GetEggResponse, error) {
-	resp, err := c.doRequest("GET", fmt.Sprintf("/eggs/%s", owner), nil)
+// TrashedEgg describes a secret in the trash
+type TrashedEgg struct {
+	Owner     string `json:"owner"`
+	SecretID  string `json:"secret_id"`
+	Project   string `json:"project,omitempty"`
+	Env       string `json:"env,omitempty"`
+	Version   int    `json:"version"`
+	DeletedAt string `json:"deleted_at"`
+	DeletedBy string `json:"deleted_by"`
+	PurgeAt   string `json:"purge_at"`
+}
 
+// ListTrashResponse represents the secrets in a vault's trash
+type ListTrashResponse struct {
+	Eggs []TrashedEgg `json:"eggs"`
+}
+
+// ListTrash lists the secrets in an owner's trash, only those in namespace
+// if it isn't zero
+func (c *Client) ListTrash(owner string, namespace Namespace) ([]TrashedEgg, error) {
+	resp, err := c.doRequest("GET", fmt.Sprintf("/trash/%s%s", owner, namespace.query(nil)), nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("failed to get egg (status %d) %s", resp.StatusCode, body)
+		return nil, fmt.Errorf("failed to list trash: %w", newAPIError(resp))
 	}
 
-	var response GetEggsResponse
+	var response ListTrashResponse
 	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
 		return nil, fmt.Errorf("failed to decode response: %w", err)
 	}
@@ -94,31 +273,344 @@ func (c *Client) GetEgg(owner string) ([]GetEggResponse, error) {
 	return response.Eggs, nil
 }
 
-// BreakEgg deletes a specific secret
-func (c *Client) BreakEgg(owner, secretID string) error {
-	resp, err := c.doRequest("DELETE", fmt.Sprintf("/eggs/%s/%s", owner, secretID), nil)
+// RestoreTrashedEgg takes a secret back out of the trash
+func (c *Client) RestoreTrashedEgg(owner string, namespace Namespace, secretID string) error {
+	resp, err := c.doRequest("POST", fmt.Sprintf("/trash/%s/%s/restore%s", owner, url.PathEscape(secretID), namespace.query(nil)), nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("secret '%s': %w", secretID, newAPIError(resp))
+	}
+
+	return nil
+}
+
+// EggVersion describes one stored version of a secret. Plaintext is only
+// populated by GetEggVersion.
+type EggVersion struct {
+	Owner     string `json:"owner"`
+	SecretID  string `json:"secret_id"`
+	Version   int    `json:"version"`
+	CreatedAt string `json:"created_at"`
+	CreatedBy string `json:"created_by"`
+	ExpiresAt string `json:"expires_at,omitempty"`
+	Plaintext string `json:"plaintext,omitempty"`
+}
+
+// ListEggVersionsResponse represents the version history of a secret
+type ListEggVersionsResponse struct {
+	Versions []EggVersion `json:"versions"`
+}
+
+// RestoreEggVersionResponse represents the result of a rollback
+type RestoreEggVersionResponse struct {
+	Owner           string `json:"owner"`
+	SecretID        string `json:"secret_id"`
+	RestoredVersion int    `json:"restored_version"`
+	Version         int    `json:"version"`
+	CreatedAt       string `json:"created_at"`
+}
+
+// ListEggVersions lists the version history of a secret, oldest first
+func (c *Client) ListEggVersions(owner string, namespace Namespace, secretID string) ([]EggVersion, error) {
+	resp, err := c.doRequest("GET", fmt.Sprintf("/eggs/%s/%s/versions%s", owner, url.PathEscape(secretID), namespace.query(nil)), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to list versions: %w", newAPIError(resp))
+	}
+
+	var response ListEggVersionsResponse
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return response.Versions, nil
+}
+
+// GetEggVersion retrieves and decrypts one specific version of a secret
+func (c *Client) GetEggVersion(owner string, namespace Namespace, secretID string, version int) (*EggVersion, error) {
+	resp, err := c.doRequest("GET", fmt.Sprintf("/eggs/%s/%s/versions/%d%s", owner, url.PathEscape(secretID), version, namespace.query(nil)), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("version %d of '%s': %w", version, secretID, newAPIError(resp))
+	}
+
+	var response EggVersion
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return &response, nil
+}
+
+// RestoreEggVersion makes an older version of a secret current again
+func (c *Client) RestoreEggVersion(owner string, namespace Namespace, secretID string, version int) (*RestoreEggVersionResponse, error) {
+	resp, err := c.doRequest("POST", fmt.Sprintf("/eggs/%s/%s/versions/%d/restore%s", owner, url.PathEscape(secretID), version, namespace.query(nil)), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to restore version: %w", newAPIError(resp))
+	}
+
+	var response RestoreEggVersionResponse
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return &response, nil
+}
+
+// EggMetadata describes a secret without its value
+type EggMetadata struct {
+	Owner     string `json:"owner"`
+	SecretID  string `json:"secret_id"`
+	Project   string `json:"project,omitempty"`
+	Env       string `json:"env,omitempty"`
+	Version   int    `json:"version"`
+	Size      int    `json:"size"`
+	CreatedAt string `json:"created_at"`
+	ExpiresAt string `json:"expires_at,omitempty"`
+}
+
+// ListEggsResponse represents the metadata of a page of secrets in a vault
+type ListEggsResponse struct {
+	Eggs       []EggMetadata `json:"eggs"`
+	NextCursor string        `json:"next_cursor,omitempty"` // Empty on the last page
+}
+
+// ListEggs lists the secrets of an owner without decrypting any of them,
+// only those in namespace if it isn't zero. See EggMetadata to handle them a
+// page at a time.
+func (c *Client) ListEggs(owner string, namespace Namespace) ([]EggMetadata, error) {
+	var eggs []EggMetadata
+	for egg, err := range c.EggsMetadata(owner, namespace) {
+		if err != nil {
+			return nil, err
+		}
+		eggs = append(eggs, egg)
+	}
+	return eggs, nil
+}
+
+// teamOwnerPrefix marks the owner of a team vault
+const teamOwnerPrefix = "team:"
+
+// TeamOwner returns the vault owner of a team, for use wherever an owner is
+// expected
+func TeamOwner(team string) string {
+	return teamOwnerPrefix + team
+}
+
+// Team is a team the caller belongs to
+type Team struct {
+	Team  string `json:"team"`
+	Owner string `json:"owner"`
+	Role  string `json:"role"`
+}
+
+// TeamMember is one member's role in a team
+type TeamMember struct {
+	Team    string `json:"team"`
+	Member  string `json:"member"`
+	Role    string `json:"role"`
+	AddedBy string `json:"added_by,omitempty"`
+	AddedAt string `json:"added_at"`
+}
+
+// ListTeamsResponse represents the teams the caller belongs to
+type ListTeamsResponse struct {
+	Teams []Team `json:"teams"`
+}
+
+// ListTeamMembersResponse represents the members of a team
+type ListTeamMembersResponse struct {
+	Members []TeamMember `json:"members"`
+}
+
+// CreateTeam creates a team with the caller as its first admin
+func (c *Client) CreateTeam(team string) (*Team, error) {
+	data, err := json.Marshal(map[string]string{"team": team})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	resp, err := c.doRequest("POST", "/teams", bytes.NewBuffer(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated {
+		return nil, fmt.Errorf("failed to create team: %w", newAPIError(resp))
+	}
+
+	var response Team
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return &response, nil
+}
+
+// ListTeams lists the teams the caller belongs to
+func (c *Client) ListTeams() ([]Team, error) {
+	resp, err := c.doRequest("GET", "/teams", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to list teams: %w", newAPIError(resp))
+	}
+
+	var response ListTeamsResponse
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return response.Teams, nil
+}
+
+// ListTeamMembers lists the members of a team and their roles
+func (c *Client) ListTeamMembers(team string) ([]TeamMember, error) {
+	resp, err := c.doRequest("GET", fmt.Sprintf("/teams/%s/members", url.PathEscape(team)), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to list members: %w", newAPIError(resp))
+	}
+
+	var response ListTeamMembersResponse
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return response.Members, nil
+}
+
+// SetTeamMember adds a member to a team or changes their role
+func (c *Client) SetTeamMember(team, member, role string) error {
+	data, err := json.Marshal(map[string]string{"role": role})
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	resp, err := c.doRequest("PUT", fmt.Sprintf("/teams/%s/members/%s", url.PathEscape(team), url.PathEscape(member)), bytes.NewBuffer(data))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to set member: %w", newAPIError(resp))
+	}
+
+	return nil
+}
+
+// RemoveTeamMember removes a member from a team
+func (c *Client) RemoveTeamMember(team, member string) error {
+	resp, err := c.doRequest("DELETE", fmt.Sprintf("/teams/%s/members/%s", url.PathEscape(team), url.PathEscape(member)), nil)
 	if err != nil {
 		return fmt.Errorf("failed to create request: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("failed to break egg (status %d) %s", resp.StatusCode, body)
+		return fmt.Errorf("failed to remove member: %w", newAPIError(resp))
 	}
 
 	return nil
 }
 
-// TODO (Optional for EC-14): Implement ListEggs
-// Should call GET /eggs endpoint to list all secrets for a user
-// You may need to add a new Lambda function for this
-func (c *Client) ListEggs(owner string) (map[string]string, error) {
-	// TODO:
-	// This might require a new Lambda function that scans DynamoDB for all eggs belonging to owner
-	// For now, return not implemented
+// AuditEvent is one recorded access to a vault
+type AuditEvent struct {
+	Vault     string `json:"vault"`
+	Seq       int    `json:"seq"`
+	Time      string `json:"time"`
+	Actor     string `json:"actor"`
+	Action    string `json:"action"`
+	SecretID  string `json:"secret_id,omitempty"` // Stored ID, including any project#env# prefix
+	Version   int    `json:"version,omitempty"`
+	SourceIP  string `json:"source_ip,omitempty"`
+	UserAgent string `json:"user_agent,omitempty"`
+	RequestID string `json:"request_id,omitempty"`
+	Outcome   string `json:"outcome"`
+	PrevHash  string `json:"prev_hash"`
+	Hash      string `json:"hash"`
+	MAC       string `json:"mac,omitempty"` // Signature of Hash under the server's audit key
+}
+
+// AuditLog represents the audit events of a vault and whether its hash
+// chain verified
+type AuditLog struct {
+	Vault      string       `json:"vault"`
+	Events     []AuditEvent `json:"events"`
+	ChainValid bool         `json:"chain_valid"`
+	ChainError string       `json:"chain_error,omitempty"`
+}
+
+// AuditFilter narrows an audit log query; zero fields match everything
+type AuditFilter struct {
+	SecretID string // Name within the namespace
+	Since    time.Time
+	Until    time.Time
+}
+
+// ListAuditEvents retrieves the audit log of an owner's vault, filtered to
+// one secret in namespace and/or a time range
+func (c *Client) ListAuditEvents(owner string, namespace Namespace, filter AuditFilter) (*AuditLog, error) {
+	values := url.Values{}
+	if team, ok := strings.CutPrefix(owner, teamOwnerPrefix); ok {
+		values.Set("team", team)
+	}
+	if filter.SecretID != "" {
+		values.Set("secret", filter.SecretID)
+	} else {
+		// The namespace only matters when naming a secret
+		namespace = Namespace{}
+	}
+	if !filter.Since.IsZero() {
+		values.Set("since", filter.Since.UTC().Format(time.RFC3339))
+	}
+	if !filter.Until.IsZero() {
+		values.Set("until", filter.Until.UTC().Format(time.RFC3339))
+	}
+
+	resp, err := c.doRequest("GET", "/audit"+namespace.query(values), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to get audit log: %w", newAPIError(resp))
+	}
 
-	return nil, fmt.Errorf("not implemented - may need new Lambda endpoint")
+	var response AuditLog
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return &response, nil
 }
 
 // Should decode JWT and extract the 'sub' claim (user ID)
@@ -152,14 +644,23 @@ func ExtractOwnerFromToken(accessToken string) (string, error) {
 
 // function to make authenticated requests
 func (c *Client) doRequest(method, path string, body io.Reader) (*http.Response, error) {
+	return c.doRequestWithHeaders(method, path, body, nil)
+}
+
+// doRequestWithHeaders is doRequest with extra request headers
+func (c *Client) doRequestWithHeaders(method, path string, body io.Reader, headers http.Header) (*http.Response, error) {
 	url := c.baseURL + path
 	req, err := http.NewRequest(method, url, body)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
+	for name, values := range headers {
+		req.Header[name] = values
+	}
 	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.token))
 	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(APIVersionHeader, strconv.Itoa(APIVersion))
 
 	resp, err := c.client.Do(req)
 	if err != nil {
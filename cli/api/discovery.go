@@ -0,0 +1,113 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Versions of the API this CLI speaks: APIVersion is the newest, and
+// MinAPIVersion the oldest it can still talk to. A deployment advertises its
+// own range in the discovery document, and the two must overlap.
+const (
+	APIVersion    = 1
+	MinAPIVersion = 1
+)
+
+// APIVersionHeader tells the deployment which API version a request is
+// written against, so one that no longer serves it refuses the request with
+// CodeIncompatible instead of misreading it.
+const APIVersionHeader = "X-Egg-Api-Version"
+
+// DiscoveryPath is where a deployment serves its discovery document
+const DiscoveryPath = "/.well-known/eggcarton"
+
+// ErrIncompatible means the deployment and this CLI share no API version
+var ErrIncompatible = errors.New("incompatible API version")
+
+// Discovery is a deployment's discovery document
+type Discovery struct {
+	Service       string            `json:"service"`
+	APIVersion    int               `json:"api_version"`
+	MinAPIVersion int               `json:"min_api_version"`
+	APIEndpoint   string            `json:"api_endpoint"`
+	Cognito       *DiscoveryCognito `json:"cognito,omitempty"` // Absent if tokens come from elsewhere, e.g. a local server
+	Features      []string          `json:"features"`
+}
+
+// DiscoveryCognito is the user pool a deployment logs users in with
+type DiscoveryCognito struct {
+	UserPoolID string `json:"user_pool_id"`
+	ClientID   string `json:"client_id"`
+	Domain     string `json:"domain"`
+	Region     string `json:"region"`
+}
+
+// Discover fetches and checks the discovery document of the deployment at
+// endpoint, the API's base URL. The document's own URL is accepted too.
+func Discover(endpoint string) (*Discovery, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return nil, fmt.Errorf("endpoint must be an http or https URL, e.g. https://abc123.execute-api.us-west-1.amazonaws.com/dev")
+	}
+	documentURL := DiscoveryURL(endpoint)
+
+	client := &http.Client{Timeout: 15 * time.Second}
+	resp, err := client.Get(documentURL)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("no discovery document at %s (HTTP %d); is it an egg-carton API new enough to serve one?", documentURL, resp.StatusCode)
+	}
+	var discovery Discovery
+	if err := json.NewDecoder(resp.Body).Decode(&discovery); err != nil || discovery.Service != "eggcarton" {
+		return nil, fmt.Errorf("%s is not an egg-carton discovery document", documentURL)
+	}
+	if err := discovery.Check(); err != nil {
+		return nil, err
+	}
+	return &discovery, nil
+}
+
+// DiscoveryURL is the URL of the discovery document of the deployment at
+// endpoint, which may already be that URL
+func DiscoveryURL(endpoint string) string {
+	return strings.TrimSuffix(strings.TrimSuffix(endpoint, "/"), DiscoveryPath) + DiscoveryPath
+}
+
+// Check validates the document and that this CLI can talk to the deployment
+func (d *Discovery) Check() error {
+	if d.APIVersion < 1 || d.MinAPIVersion < 1 || d.MinAPIVersion > d.APIVersion {
+		return fmt.Errorf("discovery document has invalid API versions %d to %d", d.MinAPIVersion, d.APIVersion)
+	}
+	if d.MinAPIVersion > APIVersion {
+		return fmt.Errorf("%w: the deployment needs API version %d or later, but this egg CLI only speaks up to version %d; upgrade egg and run egg init again",
+			ErrIncompatible, d.MinAPIVersion, APIVersion)
+	}
+	if d.APIVersion < MinAPIVersion {
+		return fmt.Errorf("%w: the deployment only serves API version %d, but this egg CLI needs version %d or later; redeploy the backend or use an older egg",
+			ErrIncompatible, d.APIVersion, MinAPIVersion)
+	}
+
+	u, err := url.Parse(d.APIEndpoint)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return fmt.Errorf("discovery document has an invalid api_endpoint %q", d.APIEndpoint)
+	}
+	if c := d.Cognito; c != nil && (c.UserPoolID == "" || c.ClientID == "" || c.Domain == "" || c.Region == "") {
+		return fmt.Errorf("discovery document has incomplete Cognito settings")
+	}
+	return nil
+}
+
+// Version is the API version the CLI and the deployment will use, the
+// newest both speak. Only meaningful once Check has passed.
+func (d *Discovery) Version() int {
+	return min(d.APIVersion, APIVersion)
+}
@@ -0,0 +1,50 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestDiscover(t *testing.T) {
+	var document Discovery
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != DiscoveryPath {
+			http.NotFound(w, r)
+			return
+		}
+		json.NewEncoder(w).Encode(document)
+	}))
+	defer server.Close()
+
+	document = Discovery{Service: "eggcarton", APIVersion: APIVersion, MinAPIVersion: 1, APIEndpoint: server.URL + "/dev"}
+	for _, endpoint := range []string{server.URL, server.URL + "/", server.URL + DiscoveryPath} {
+		discovery, err := Discover(endpoint)
+		if err != nil {
+			t.Fatalf("%s: %v", endpoint, err)
+		}
+		if discovery.APIEndpoint != server.URL+"/dev" || discovery.Version() != APIVersion {
+			t.Errorf("unexpected discovery %+v", discovery)
+		}
+	}
+
+	// A deployment that no longer serves the version this CLI speaks
+	document.MinAPIVersion, document.APIVersion = APIVersion+1, APIVersion+2
+	if _, err := Discover(server.URL); !errors.Is(err, ErrIncompatible) {
+		t.Errorf("expected ErrIncompatible, got %v", err)
+	}
+
+	document = Discovery{Service: "eggcarton", APIVersion: 1, MinAPIVersion: 1, APIEndpoint: server.URL, Cognito: &DiscoveryCognito{ClientID: "abc"}}
+	if _, err := Discover(server.URL); err == nil {
+		t.Error("incomplete Cognito settings accepted")
+	}
+	document = Discovery{Service: "something-else", APIVersion: 1, MinAPIVersion: 1, APIEndpoint: server.URL}
+	if _, err := Discover(server.URL); err == nil {
+		t.Error("another service's document accepted")
+	}
+	if _, err := Discover(server.URL + "/teams"); err == nil {
+		t.Error("missing document accepted")
+	}
+}
@@ -0,0 +1,134 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Error codes sent by the API; see Error Responses in the README
+const (
+	CodeInvalidRequest = "invalid_request"
+	CodeUnauthorized   = "unauthorized"
+	CodeForbidden      = "forbidden"
+	CodeNotFound       = "not_found"
+	CodeConflict       = "conflict"
+	CodeTrashed        = "trashed"
+	CodeExpired        = "expired"
+	CodeThrottled      = "throttled"
+	CodeIncompatible   = "incompatible"
+	CodeInternal       = "internal_error"
+)
+
+// Classes of API error. An *APIError matches the class of its code with
+// errors.Is, e.g. errors.Is(err, api.ErrNotFound).
+var (
+	ErrInvalidRequest = errors.New("invalid request")
+	ErrUnauthorized   = errors.New("unauthorized")
+	ErrForbidden      = errors.New("forbidden")
+	ErrNotFound       = errors.New("not found")
+	ErrConflict       = errors.New("conflict")
+	ErrTrashed        = errors.New("trashed")
+	ErrExpired        = errors.New("expired")
+	ErrThrottled      = errors.New("throttled")
+)
+
+var errorClasses = map[string]error{
+	CodeInvalidRequest: ErrInvalidRequest,
+	CodeUnauthorized:   ErrUnauthorized,
+	CodeForbidden:      ErrForbidden,
+	CodeNotFound:       ErrNotFound,
+	CodeConflict:       ErrConflict,
+	CodeTrashed:        ErrTrashed,
+	CodeExpired:        ErrExpired,
+	CodeThrottled:      ErrThrottled,
+	CodeIncompatible:   ErrIncompatible,
+}
+
+// APIError is an error response from the API
+type APIError struct {
+	StatusCode int
+	Code       string
+	Message    string
+	RequestID  string
+	Retryable  bool // Whether sending the same request again may succeed
+}
+
+func (e *APIError) Error() string {
+	if e.RequestID != "" {
+		return fmt.Sprintf("%s (request %s)", e.Message, e.RequestID)
+	}
+	return e.Message
+}
+
+// Is matches the class of the error's code
+func (e *APIError) Is(target error) bool {
+	class, ok := errorClasses[e.Code]
+	return ok && class == target
+}
+
+// errorBody is the API's error envelope
+type errorBody struct {
+	Code      string `json:"code"`
+	Message   string `json:"message"`
+	RequestID string `json:"request_id"`
+	Retryable bool   `json:"retryable"`
+	Error     string `json:"error"` // Older deployments only send this
+}
+
+// newAPIError reads an error response. Responses that don't carry a code,
+// from older deployments or API Gateway itself, are classified by status.
+func newAPIError(resp *http.Response) *APIError {
+	data, _ := io.ReadAll(resp.Body)
+	var body errorBody
+	json.Unmarshal(data, &body)
+
+	apiErr := &APIError{
+		StatusCode: resp.StatusCode,
+		Code:       body.Code,
+		Message:    body.Message,
+		RequestID:  body.RequestID,
+		Retryable:  body.Retryable,
+	}
+	if apiErr.RequestID == "" {
+		apiErr.RequestID = resp.Header.Get("X-Request-Id")
+	}
+	if apiErr.Message == "" {
+		apiErr.Message = body.Error
+	}
+	if apiErr.Message == "" {
+		apiErr.Message = strings.TrimSpace(string(data))
+	}
+	if apiErr.Message == "" {
+		apiErr.Message = http.StatusText(resp.StatusCode)
+	}
+	if apiErr.Code == "" {
+		apiErr.Code = codeForStatus(resp.StatusCode)
+		apiErr.Retryable = resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable
+	}
+	return apiErr
+}
+
+func codeForStatus(statusCode int) string {
+	switch statusCode {
+	case http.StatusBadRequest:
+		return CodeInvalidRequest
+	case http.StatusUnauthorized:
+		return CodeUnauthorized
+	case http.StatusForbidden:
+		return CodeForbidden
+	case http.StatusNotFound:
+		return CodeNotFound
+	case http.StatusConflict:
+		return CodeConflict
+	case http.StatusGone:
+		return CodeExpired
+	case http.StatusTooManyRequests:
+		return CodeThrottled
+	default:
+		return CodeInternal
+	}
+}
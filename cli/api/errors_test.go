@@ -0,0 +1,65 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+func TestAPIErrors(t *testing.T) {
+	responses := map[string]struct {
+		status int
+		body   string
+	}{
+		"/eggs/alice/GONE":    {404, `{"code":"not_found","message":"Egg not found","request_id":"r1","retryable":false,"error":"Egg not found"}`},
+		"/eggs/alice/OLD":     {410, `{"error":"Egg has expired"}`},     // Older deployments
+		"/eggs/alice/LOCKED":  {403, `{"message":"Forbidden"}`},         // API Gateway
+		"/eggs/alice/CROWDED": {429, `{"message":"Too Many Requests"}`}, // API Gateway
+		"/eggs/alice/RACE":    {409, `{"code":"conflict","message":"Egg was modified concurrently","retryable":true}`},
+		"/eggs/alice/NEWER":   {400, `{"code":"incompatible","message":"API version \"1\" isn't served here"}`},
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get(APIVersionHeader) != strconv.Itoa(APIVersion) {
+			t.Errorf("%s sent API version %q", r.URL.Path, r.Header.Get(APIVersionHeader))
+		}
+		response := responses[r.URL.Path]
+		w.WriteHeader(response.status)
+		w.Write([]byte(response.body))
+	}))
+	defer server.Close()
+	client := NewClient(server.URL, "token")
+
+	tests := []struct {
+		key       string
+		class     error
+		message   string
+		retryable bool
+	}{
+		{"GONE", ErrNotFound, "Egg not found", false},
+		{"OLD", ErrExpired, "Egg has expired", false},
+		{"LOCKED", ErrForbidden, "Forbidden", false},
+		{"CROWDED", ErrThrottled, "Too Many Requests", true},
+		{"RACE", ErrConflict, "Egg was modified concurrently", true},
+		{"NEWER", ErrIncompatible, `API version "1" isn't served here`, false},
+	}
+	for _, tt := range tests {
+		_, err := client.GetEggByID("alice", Namespace{}, tt.key)
+		var apiErr *APIError
+		if !errors.As(err, &apiErr) {
+			t.Fatalf("%s: not an APIError: %v", tt.key, err)
+		}
+		if !errors.Is(err, tt.class) || apiErr.Message != tt.message || apiErr.Retryable != tt.retryable {
+			t.Errorf("%s: unexpected error %+v", tt.key, apiErr)
+		}
+		if errors.Is(err, ErrUnauthorized) {
+			t.Errorf("%s: matched the wrong class", tt.key)
+		}
+	}
+
+	_, err := client.GetEggByID("alice", Namespace{}, "GONE")
+	if err.Error() != "secret 'GONE': Egg not found (request r1)" {
+		t.Errorf("unexpected message %q", err)
+	}
+}
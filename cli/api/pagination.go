@@ -0,0 +1,109 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"iter"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// pageSize is how many secrets the iterators ask for at a time
+const pageSize = 100
+
+// Eggs iterates over the decrypted secrets of an owner, only those in
+// namespace if it isn't zero, fetching them a page at a time. An error ends
+// the iteration.
+func (c *Client) Eggs(owner string, namespace Namespace) iter.Seq2[GetEggResponse, error] {
+	return paginate(func(cursor string) ([]GetEggResponse, string, error) {
+		page, err := c.GetEggsPage(owner, namespace, cursor, pageSize)
+		if err != nil {
+			return nil, "", err
+		}
+		return page.Eggs, page.NextCursor, nil
+	})
+}
+
+// EggsMetadata is Eggs without the values, so nothing is decrypted
+func (c *Client) EggsMetadata(owner string, namespace Namespace) iter.Seq2[EggMetadata, error] {
+	return paginate(func(cursor string) ([]EggMetadata, string, error) {
+		page, err := c.ListEggsPage(owner, namespace, cursor, pageSize)
+		if err != nil {
+			return nil, "", err
+		}
+		return page.Eggs, page.NextCursor, nil
+	})
+}
+
+// GetEggsPage retrieves and decrypts up to limit secrets, starting from
+// cursor (empty for the first page). Pages can come back short, or even
+// empty, before the last one; only an empty NextCursor ends the listing.
+func (c *Client) GetEggsPage(owner string, namespace Namespace, cursor string, limit int) (*GetEggsResponse, error) {
+	var response GetEggsResponse
+	if err := c.getPage(owner, namespace, pageQuery(cursor, limit), &response); err != nil {
+		return nil, fmt.Errorf("failed to get egg: %w", err)
+	}
+	return &response, nil
+}
+
+// ListEggsPage is GetEggsPage without the values
+func (c *Client) ListEggsPage(owner string, namespace Namespace, cursor string, limit int) (*ListEggsResponse, error) {
+	query := pageQuery(cursor, limit)
+	query.Set("fields", "meta")
+	var response ListEggsResponse
+	if err := c.getPage(owner, namespace, query, &response); err != nil {
+		return nil, fmt.Errorf("failed to list eggs: %w", err)
+	}
+	return &response, nil
+}
+
+func pageQuery(cursor string, limit int) url.Values {
+	query := url.Values{"limit": {strconv.Itoa(limit)}}
+	if cursor != "" {
+		query.Set("cursor", cursor)
+	}
+	return query
+}
+
+func (c *Client) getPage(owner string, namespace Namespace, query url.Values, response any) error {
+	resp, err := c.doRequest("GET", fmt.Sprintf("/eggs/%s%s", owner, namespace.query(query)), nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return newAPIError(resp)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(response); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
+
+// paginate turns a page fetcher into an iterator over every item. Older
+// deployments ignore the cursor and send everything without a NextCursor,
+// which is just a listing of one page.
+func paginate[T any](fetch func(cursor string) ([]T, string, error)) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		cursor := ""
+		for {
+			items, next, err := fetch(cursor)
+			if err != nil {
+				var zero T
+				yield(zero, err)
+				return
+			}
+			for _, item := range items {
+				if !yield(item, nil) {
+					return
+				}
+			}
+			if next == "" {
+				return
+			}
+			cursor = next
+		}
+	}
+}
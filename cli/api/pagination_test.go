@@ -0,0 +1,51 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+func TestEggsPaginates(t *testing.T) {
+	// Five secrets, served two to a page with the offset as the cursor
+	names := []string{"A", "B", "C", "D", "E"}
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.URL.Query().Get("fields") != "meta" {
+			t.Errorf("metadata listing without fields=meta: %s", r.URL)
+		}
+		offset, _ := strconv.Atoi(r.URL.Query().Get("cursor"))
+		end := min(offset+2, len(names))
+		response := ListEggsResponse{}
+		for _, name := range names[offset:end] {
+			response.Eggs = append(response.Eggs, EggMetadata{SecretID: name})
+		}
+		if end < len(names) {
+			response.NextCursor = strconv.Itoa(end)
+		}
+		json.NewEncoder(w).Encode(response)
+	}))
+	defer server.Close()
+
+	eggs, err := NewClient(server.URL, "token").ListEggs("alice", Namespace{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(eggs) != 5 || eggs[4].SecretID != "E" || requests != 3 {
+		t.Errorf("got %d eggs in %d requests, want 5 in 3", len(eggs), requests)
+	}
+
+	// Stopping early doesn't fetch the rest
+	requests = 0
+	for egg, err := range NewClient(server.URL, "token").EggsMetadata("alice", Namespace{}) {
+		if err != nil || egg.SecretID == "A" {
+			break
+		}
+	}
+	if requests != 1 {
+		t.Errorf("made %d requests after stopping on the first egg, want 1", requests)
+	}
+}
@@ -1,11 +1,13 @@
 package commands
 
 import (
+	"errors"
 	"fmt"
+	"io"
+	"strconv"
+	"time"
 
 	"github.com/owenHochwald/egg-carton/cli/api"
-	"github.com/owenHochwald/egg-carton/cli/auth"
-	"github.com/owenHochwald/egg-carton/cli/config"
 	"github.com/spf13/cobra"
 )
 
@@ -14,58 +16,83 @@ var AddCmd = &cobra.Command{
 	Use:     "lay [key] [value]",
 	Aliases: []string{"add"},
 	Short:   "Store a secret (lay an egg)",
-	Long:    `Encrypt and store a secret in your EggCarton vault.`,
-	Args:    cobra.ExactArgs(2),
-	RunE:    runAdd,
+	Long: `Encrypt and store a secret in your EggCarton vault.
+
+Secrets can be given an expiry with --ttl (a duration from now) or --expires
+(a date or RFC 3339 time). Expired secrets are no longer returned and are
+deleted from the vault some time afterwards.
+
+Each lay stores a new version of the secret. --if-absent only creates the
+secret, failing if it already exists; --if-match only replaces the given
+version, failing if someone has laid it since. Both exit with status 6 on
+a conflict.
+
+Example:
+  egg lay --project api --env prod DATABASE_URL postgres://...
+  egg lay --ttl 24h DEPLOY_TOKEN ghp_...
+  egg lay --if-absent SESSION_SECRET s3cr3t`,
+	Args: cobra.ExactArgs(2),
+	RunE: runAdd,
+}
+
+func init() {
+	AddCmd.Flags().Duration("ttl", 0, "expire the secret after this long, e.g. 24h")
+	AddCmd.Flags().String("expires", "", "expire the secret at this date or RFC 3339 time")
+	AddCmd.Flags().Bool("if-absent", false, "only lay the secret if it doesn't exist yet")
+	AddCmd.Flags().Int("if-match", 0, "only lay the secret if it is still at this version")
+	AddCmd.MarkFlagsMutuallyExclusive("if-absent", "if-match")
+	addNamespaceFlags(AddCmd)
 }
 
 func runAdd(cmd *cobra.Command, args []string) error {
 	key := args[0]
 	value := args[1]
+	ttl, _ := cmd.Flags().GetDuration("ttl")
+	expires, _ := cmd.Flags().GetString("expires")
+	ifAbsent, _ := cmd.Flags().GetBool("if-absent")
+	ifMatch, _ := cmd.Flags().GetInt("if-match")
 
-	fmt.Printf("🐔 Laying egg: %s\n", key)
-
-	config, err := config.LoadConfig()
+	expiresAt, err := parseExpiry(ttl, expires)
 	if err != nil {
-		return fmt.Errorf("failed to load config: %w", err)
+		return err
 	}
 
-	tokens, err := config.LoadTokens()
+	client, owner, err := newAuthenticatedClient()
 	if err != nil {
-		return fmt.Errorf("failed to load tokens: %w", err)
-	}
-	// 3. Check if token is valid (refresh if needed)
-	if tokens == nil {
-		return fmt.Errorf("you are not logged in. Please run 'egg login' first")
-	}
-	if !tokens.IsTokenValid() {
-		fmt.Println("⏰ Token expired, refreshing...")
-		newTokens, err := auth.RefreshAccessToken(config.GetTokenURL(), config.CognitoConfig.ClientID, tokens.RefreshToken)
-		if err != nil {
-			return fmt.Errorf("failed to refresh token: %w", err)
-		}
-		if err := config.SaveTokens(newTokens); err != nil {
-			return fmt.Errorf("failed to save refreshed tokens: %w", err)
-		}
-		tokens = newTokens
+		return err
 	}
 
-	// 4. Extract owner from token
-	owner, err := config.GetOwner()
+	vault, err := scopeFromFlags(cmd, owner)
 	if err != nil {
-		return fmt.Errorf("failed to extract owner from token: %w", err)
+		return err
 	}
 
-	// 5. Create API client
-	client := api.NewClient(config.GetAPIBaseURL(), tokens.AccessToken)
+	status("🐔 Laying egg: %s\n", vault.describe(key))
 
-	// 6. Call PutEgg(owner, key, value)
-	if err := client.PutEgg(owner, key, value); err != nil {
+	// Call PutEgg(owner, namespace, key, value, opts)
+	laid, err := client.PutEgg(vault.owner, vault.namespace, key, value, api.PutOptions{
+		ExpiresAt: expiresAt,
+		IfAbsent:  ifAbsent,
+		IfMatch:   ifMatch,
+	})
+	if errors.Is(err, api.ErrConflict) && ifAbsent {
+		return reword(err, "secret '%s' already exists", vault.describe(key))
+	}
+	if errors.Is(err, api.ErrConflict) && ifMatch > 0 {
+		return reword(err, "secret '%s' is no longer at version %d", vault.describe(key), ifMatch)
+	}
+	if err != nil {
 		return fmt.Errorf("failed to lay egg: %w", err)
 	}
 
-	// 7. Print success message
-	fmt.Printf("✅ Successfully laid egg: %s\n", key)
-
-	return nil
+	return render(cmd, result{
+		value: laid,
+		table: func(w io.Writer) {
+			fmt.Fprintf(w, "✅ Successfully laid egg: %s (version %d)\n", vault.describe(key), laid.Version)
+			if !expiresAt.IsZero() {
+				fmt.Fprintf(w, "⏳ Expires: %s\n", expiresAt.Format(time.RFC3339))
+			}
+		},
+		raw: lines(strconv.Itoa(laid.Version)),
+	})
 }
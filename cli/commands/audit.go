@@ -0,0 +1,114 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/owenHochwald/egg-carton/cli/api"
+	"github.com/spf13/cobra"
+)
+
+// AuditCmd represents the audit command
+var AuditCmd = &cobra.Command{
+	Use:   "audit",
+	Short: "Show who accessed a vault",
+	Long: `Show the audit log of your vault, or of a team vault with --team (admins
+only). Every lay, get, break, history and rollback is recorded, including
+denied attempts.
+
+Each event is hash-chained to the one before it, so any tampering with the
+stored log is reported.
+
+Times for --since and --until are RFC 3339 (2026-01-02T15:04:05Z), a date
+(2026-01-02), or a duration back from now (24h).`,
+	Args: cobra.NoArgs,
+	RunE: runAudit,
+}
+
+func init() {
+	AuditCmd.Flags().String("secret", "", "only show events for this secret")
+	AuditCmd.Flags().String("since", "", "only show events at or after this time")
+	AuditCmd.Flags().String("until", "", "only show events at or before this time")
+	addNamespaceFlags(AuditCmd)
+}
+
+func runAudit(cmd *cobra.Command, args []string) error {
+	secret, _ := cmd.Flags().GetString("secret")
+	sinceFlag, _ := cmd.Flags().GetString("since")
+	untilFlag, _ := cmd.Flags().GetString("until")
+
+	filter := api.AuditFilter{SecretID: secret}
+	var err error
+	if filter.Since, err = parseAuditTime(sinceFlag); err != nil {
+		return fmt.Errorf("invalid --since: %w", err)
+	}
+	if filter.Until, err = parseAuditTime(untilFlag); err != nil {
+		return fmt.Errorf("invalid --until: %w", err)
+	}
+
+	client, owner, err := newAuthenticatedClient()
+	if err != nil {
+		return err
+	}
+
+	vault, err := scopeFromFlags(cmd, owner)
+	if err != nil {
+		return err
+	}
+
+	auditLog, err := client.ListAuditEvents(vault.owner, vault.namespace, filter)
+	if err != nil {
+		return err
+	}
+
+	if !auditLog.ChainValid {
+		status("⚠️  The audit log has been tampered with: %s\n\n", auditLog.ChainError)
+	}
+
+	auditLog.Events = nonNil(auditLog.Events)
+	err = render(cmd, result{
+		value: auditLog,
+		table: func(w io.Writer) {
+			fmt.Fprintf(w, "🔍 %d audit event(s) for %s:\n\n", len(auditLog.Events), auditLog.Vault)
+			for _, e := range auditLog.Events {
+				fmt.Fprintf(w, "Time: %s\n", e.Time)
+				fmt.Fprintf(w, "Actor: %s\n", e.Actor)
+				fmt.Fprintf(w, "Action: %s (%s)\n", e.Action, e.Outcome)
+				if e.SecretID != "" {
+					fmt.Fprintf(w, "Secret: %s\n", strings.ReplaceAll(e.SecretID, "#", "/"))
+				}
+				if e.Version > 0 {
+					fmt.Fprintf(w, "Version: %d\n", e.Version)
+				}
+				fmt.Fprintf(w, "Source: %s %s\n", e.SourceIP, e.UserAgent)
+				fmt.Fprintf(w, "Request: %s\n", e.RequestID)
+				fmt.Fprintln(w, "---")
+			}
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	if !auditLog.ChainValid {
+		return fmt.Errorf("audit log failed verification")
+	}
+	return nil
+}
+
+// parseAuditTime accepts an RFC 3339 time, a date, or a duration before now.
+// An empty string is the zero time.
+func parseAuditTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if d, err := time.ParseDuration(value); err == nil {
+		return time.Now().Add(-d), nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.DateOnly, value)
+}
@@ -1,69 +1,187 @@
 package commands
 
 import (
+	"errors"
 	"fmt"
+	"io"
+	"net/http"
 
 	"github.com/owenHochwald/egg-carton/cli/api"
-	"github.com/owenHochwald/egg-carton/cli/auth"
-	"github.com/owenHochwald/egg-carton/cli/config"
 	"github.com/spf13/cobra"
 )
 
 // BreakCmd represents the break command
 var BreakCmd = &cobra.Command{
-	Use:   "break [key]",
-	Short: "Delete a secret",
-	Long:  `Permanently delete a secret from your EggCarton vault.`,
-	Args:  cobra.ExactArgs(1),
-	RunE:  runBreak,
+	Use:   "break [key]...",
+	Short: "Delete secrets",
+	Long: `Move secrets and their version history to the trash.
+
+Trashed secrets can be brought back with 'egg restore' until the vault's
+retention window (30 days by default) runs out, then they are purged.
+Use --purge to delete secrets permanently straight away, and --if-match to
+only delete a secret if nobody has laid a newer version since you read it.
+
+Several keys are broken together in batches; each one succeeds or fails on
+its own.
+
+Example:
+  egg break OLD_TOKEN
+  egg break --project api --env dev CACHE_URL QUEUE_URL`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runBreak,
+}
+
+func init() {
+	BreakCmd.Flags().Bool("purge", false, "delete permanently instead of moving to the trash")
+	BreakCmd.Flags().BoolP("yes", "y", false, "don't ask for confirmation")
+	BreakCmd.Flags().Int("if-match", 0, "only delete the secret if it is still at this version")
+	addNamespaceFlags(BreakCmd)
 }
 
 func runBreak(cmd *cobra.Command, args []string) error {
 	key := args[0]
+	purge, _ := cmd.Flags().GetBool("purge")
+	yes, _ := cmd.Flags().GetBool("yes")
+	ifMatch, _ := cmd.Flags().GetInt("if-match")
+	if len(args) > 1 && ifMatch > 0 {
+		return fmt.Errorf("--if-match can only be used when breaking a single secret")
+	}
+	if err := canConfirm(yes); err != nil {
+		return err
+	}
 
-	fmt.Printf("💥 Breaking egg: %s\n", key)
-
-	// 1. Load config
-	cfg, err := config.LoadConfig()
+	client, owner, err := newAuthenticatedClient()
 	if err != nil {
-		return fmt.Errorf("failed to load config: %w", err)
+		return err
 	}
 
-	// 2. Load tokens (check if logged in)
-	tokens, err := cfg.LoadTokens()
+	vault, err := scopeFromFlags(cmd, owner)
 	if err != nil {
-		return fmt.Errorf("you are not logged in. Please run 'egg login' first: %w", err)
+		return err
 	}
 
-	// 3. Check if token is valid (refresh if needed)
-	if !tokens.IsTokenValid() {
-		fmt.Println("⏰ Token expired, refreshing...")
-		newTokens, err := auth.RefreshAccessToken(cfg.GetTokenURL(), cfg.CognitoConfig.ClientID, tokens.RefreshToken)
-		if err != nil {
-			return fmt.Errorf("failed to refresh token: %w", err)
-		}
-		if err := cfg.SaveTokens(newTokens); err != nil {
-			return fmt.Errorf("failed to save refreshed tokens: %w", err)
-		}
-		tokens = newTokens
+	if len(args) > 1 {
+		return breakMany(cmd, client, vault, args, purge, yes)
 	}
 
-	// 4. Extract owner from token
-	owner, err := cfg.GetOwner()
-	if err != nil {
-		return fmt.Errorf("failed to extract owner from token: %w", err)
+	question := fmt.Sprintf("Move %s to the trash?", vault.describe(key))
+	if purge {
+		question = fmt.Sprintf("Permanently delete %s and its whole history? This can't be undone.", vault.describe(key))
+	}
+	if err := confirm(question, yes); err != nil {
+		return err
 	}
 
-	// 5. Create API client
-	client := api.NewClient(cfg.GetAPIBaseURL(), tokens.AccessToken)
+	status("💥 Breaking egg: %s\n", vault.describe(key))
 
-	// 6. Call BreakEgg(owner, secretID)
-	if err := client.BreakEgg(owner, key); err != nil {
+	// Call BreakEgg(owner, namespace, secretID, opts)
+	broken, err := client.BreakEgg(vault.owner, vault.namespace, key, api.BreakOptions{Purge: purge, IfMatch: ifMatch})
+	if errors.Is(err, api.ErrNotFound) {
+		return reword(err, "secret '%s' not found", vault.describe(key))
+	}
+	if errors.Is(err, api.ErrConflict) && ifMatch > 0 {
+		return reword(err, "secret '%s' is no longer at version %d", vault.describe(key), ifMatch)
+	}
+	if err != nil {
 		return fmt.Errorf("failed to break egg: %w", err)
 	}
 
-	// 7. Print confirmation message
-	fmt.Printf("✅ Successfully deleted secret: %s\n", key)
+	results := []api.BatchResult{{SecretID: key, Status: http.StatusOK, PurgeAt: broken.PurgeAt}}
+	return render(cmd, result{
+		value: newBreakOutput(vault, purge, results),
+		table: func(w io.Writer) {
+			if purge {
+				fmt.Fprintf(w, "✅ Permanently deleted secret: %s\n", vault.describe(key))
+				return
+			}
+			fmt.Fprintf(w, "🗑️  Moved %s to the trash\n", vault.describe(key))
+			if broken.PurgeAt != "" {
+				fmt.Fprintf(w, "It will be purged at %s; undo with 'egg restore %s'.\n", broken.PurgeAt, key)
+			}
+		},
+		raw: lines(key),
+	})
+}
+
+// breakOutput is the result of egg break, whether of one secret or several
+type breakOutput struct {
+	Owner   string            `json:"owner"`
+	Project string            `json:"project,omitempty"`
+	Env     string            `json:"env,omitempty"`
+	Purged  bool              `json:"purged"`
+	Results []api.BatchResult `json:"results"`
+}
+
+func newBreakOutput(vault scope, purge bool, results []api.BatchResult) breakOutput {
+	return breakOutput{
+		Owner:   vault.owner,
+		Project: vault.namespace.Project,
+		Env:     vault.namespace.Env,
+		Purged:  purge,
+		Results: nonNil(results),
+	}
+}
+
+// breakMany breaks several secrets with the batch API, reporting on each.
+func breakMany(cmd *cobra.Command, client *api.Client, vault scope, keys []string, purge, yes bool) error {
+	question := fmt.Sprintf("Move %d secrets to the trash?", len(keys))
+	if purge {
+		question = fmt.Sprintf("Permanently delete %d secrets and their whole history? This can't be undone.", len(keys))
+	}
+	if err := confirm(question, yes); err != nil {
+		return err
+	}
+
+	status("💥 Breaking %d eggs\n", len(keys))
+	results, err := client.BreakEggs(vault.owner, vault.namespace, keys, purge)
+	if err != nil {
+		return fmt.Errorf("failed to break eggs: %w", err)
+	}
+
+	var firstErr error
+	var broken []string
+	failures := make(map[string]error)
+	for _, r := range results {
+		err := r.Err()
+		if err == nil {
+			broken = append(broken, r.SecretID)
+			continue
+		}
+		if errors.Is(err, api.ErrNotFound) {
+			err = reword(err, "not found")
+		}
+		failures[r.SecretID] = err
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
 
+	err = render(cmd, result{
+		value: newBreakOutput(vault, purge, results),
+		table: func(w io.Writer) {
+			for _, r := range results {
+				if err, failed := failures[r.SecretID]; failed {
+					fmt.Fprintf(w, "❌ %s: %v\n", vault.describe(r.SecretID), err)
+				} else {
+					fmt.Fprintf(w, "✅ %s\n", vault.describe(r.SecretID))
+				}
+			}
+			switch {
+			case len(failures) > 0:
+			case purge:
+				fmt.Fprintf(w, "Permanently deleted %d secrets\n", len(results))
+			default:
+				fmt.Fprintf(w, "🗑️  Moved %d secrets to the trash; undo with 'egg restore'\n", len(results))
+			}
+		},
+		raw: lines(broken...),
+	})
+	if err != nil {
+		return err
+	}
+
+	if len(failures) > 0 {
+		return reword(firstErr, "%d of %d secrets couldn't be broken", len(failures), len(results))
+	}
 	return nil
 }
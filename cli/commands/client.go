@@ -0,0 +1,52 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/owenHochwald/egg-carton/cli/api"
+	"github.com/owenHochwald/egg-carton/cli/auth"
+	"github.com/owenHochwald/egg-carton/cli/config"
+)
+
+// newAuthenticatedClient loads the config and stored tokens, refreshing the
+// access token if it has expired, and returns an API client together with
+// the owner (user ID) the tokens belong to.
+func newAuthenticatedClient() (*api.Client, string, error) {
+	// 1. Load config
+	cfg, err := loadConfig()
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to load config: %w", err)
+	}
+
+	// 2. Load tokens (check if logged in)
+	tokens, err := cfg.LoadTokens()
+	if errors.Is(err, config.ErrNoTokens) {
+		return nil, "", reword(api.ErrUnauthorized, "you are not logged in. Please run 'egg login' first")
+	}
+	if err != nil {
+		return nil, "", reword(api.ErrUnauthorized, "failed to load your tokens from %s: %v", cfg.TokenStore.Name(), err)
+	}
+
+	// 3. Check if token is valid (refresh if needed)
+	if !tokens.IsTokenValid() {
+		status("⏰ Token expired, refreshing...\n")
+		newTokens, err := auth.RefreshAccessToken(cfg.GetTokenURL(), cfg.CognitoConfig.ClientID, tokens.RefreshToken)
+		if err != nil {
+			return nil, "", reword(api.ErrUnauthorized, "failed to refresh token: %v", err)
+		}
+		if err := cfg.SaveTokens(newTokens); err != nil {
+			return nil, "", fmt.Errorf("failed to save refreshed tokens: %w", err)
+		}
+		tokens = newTokens
+	}
+
+	// 4. Extract owner from token
+	owner, err := tokens.Owner()
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to extract owner from token: %w", err)
+	}
+
+	// 5. Create API client
+	return api.NewClient(cfg.GetAPIBaseURL(), tokens.AccessToken), owner, nil
+}
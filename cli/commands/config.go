@@ -0,0 +1,221 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/owenHochwald/egg-carton/cli/config"
+	"github.com/spf13/cobra"
+)
+
+// ConfigCmd groups the commands for configuration profiles
+var ConfigCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Manage configuration profiles",
+	Long: `Show and change the settings in ~/.eggcarton/` + config.ProfilesFile + `.
+
+Each named profile points the CLI at one deployment and can set a default
+project and env. Select a profile with --profile, EGG_PROFILE, or
+'egg config use'; each one keeps its own login. Settings:
+  ` + strings.Join(config.Settings(), "\n  ") + `
+
+Every setting can be overridden by an environment variable, e.g.
+EGG_API_ENDPOINT or EGG_COGNITO_CLIENT_ID, and anything left unset comes
+from the built-in deployment. The project and env defaults apply after any
+` + config.ProjectConfigFile + ` file.
+
+Example:
+  egg config set --profile local api_endpoint http://localhost:8787
+  egg config use local
+  egg config get api_endpoint`,
+}
+
+var configListCmd = &cobra.Command{
+	Use:     "list",
+	Aliases: []string{"ls"},
+	Short:   "Show every profile and where its settings come from",
+	Args:    cobra.NoArgs,
+	RunE:    runConfigList,
+}
+
+var configGetCmd = &cobra.Command{
+	Use:   "get [setting]",
+	Short: "Print the value of a setting for the selected profile",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runConfigGet,
+}
+
+var configSetCmd = &cobra.Command{
+	Use:   "set [setting] [value]",
+	Short: "Change a setting of the selected profile, creating it if need be",
+	Long: `Change a setting of the selected profile, creating the profile if it
+doesn't exist yet. An empty value removes the setting.`,
+	Args: cobra.ExactArgs(2),
+	RunE: runConfigSet,
+}
+
+var configUseCmd = &cobra.Command{
+	Use:   "use [profile]",
+	Short: "Make a profile the current one",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runConfigUse,
+}
+
+func init() {
+	ConfigCmd.AddCommand(configListCmd, configGetCmd, configSetCmd, configUseCmd)
+}
+
+// configSetting is a setting's value for a profile and where it came from:
+// env, profile, builtin or unset
+type configSetting struct {
+	Profile string `json:"profile,omitempty"`
+	Key     string `json:"key"`
+	Value   string `json:"value"`
+	Source  string `json:"source,omitempty"`
+}
+
+// configProfile is one profile in egg config list
+type configProfile struct {
+	Name     string          `json:"name"`
+	Current  bool            `json:"current"`
+	Settings []configSetting `json:"settings"`
+}
+
+// configList is the result of egg config list
+type configList struct {
+	Path     string          `json:"path"`
+	Profiles []configProfile `json:"profiles"`
+}
+
+// configUse is the result of egg config use
+type configUse struct {
+	CurrentProfile string `json:"current_profile"`
+}
+
+func runConfigList(cmd *cobra.Command, args []string) error {
+	profiles, err := config.LoadProfiles()
+	if err != nil {
+		return err
+	}
+	selected, err := profiles.Selected(profile)
+	if err != nil {
+		return err
+	}
+
+	list := configList{Path: profiles.Path}
+	for _, name := range profiles.Names() {
+		p := configProfile{Name: name, Current: name == selected}
+		for _, key := range config.Settings() {
+			value, source, err := profiles.Resolve(name, key)
+			if err != nil {
+				return err
+			}
+			p.Settings = append(p.Settings, configSetting{Key: key, Value: value, Source: source})
+		}
+		list.Profiles = append(list.Profiles, p)
+	}
+
+	names := profiles.Names()
+	return render(cmd, result{
+		value: list,
+		table: func(w io.Writer) {
+			for _, p := range list.Profiles {
+				marker := " "
+				if p.Current {
+					marker = "*"
+				}
+				fmt.Fprintf(w, "%s %s\n", marker, p.Name)
+				for _, s := range p.Settings {
+					if s.Source != config.SourceUnset {
+						fmt.Fprintf(w, "    %s: %s (%s)\n", s.Key, s.Value, s.Source)
+					}
+				}
+			}
+		},
+		raw: lines(names...),
+	})
+}
+
+func runConfigGet(cmd *cobra.Command, args []string) error {
+	profiles, err := config.LoadProfiles()
+	if err != nil {
+		return err
+	}
+	selected, err := profiles.Selected(profile)
+	if err != nil {
+		return err
+	}
+
+	value, source, err := profiles.Resolve(selected, args[0])
+	if err != nil {
+		return err
+	}
+	return render(cmd, result{
+		value: configSetting{Profile: selected, Key: args[0], Value: value, Source: source},
+		table: func(w io.Writer) {
+			if source == config.SourceUnset {
+				fmt.Fprintf(w, "%s is not set\n", args[0])
+				return
+			}
+			fmt.Fprintf(w, "%s (%s)\n", value, source)
+		},
+		raw: lines(value),
+	})
+}
+
+func runConfigSet(cmd *cobra.Command, args []string) error {
+	key, value := args[0], args[1]
+
+	profiles, err := config.LoadProfiles()
+	if err != nil {
+		return err
+	}
+	// Unlike the other commands, --profile may name a profile to create
+	selected := profile
+	if selected == "" {
+		if selected, err = profiles.Selected(""); err != nil {
+			return err
+		}
+	}
+
+	if err := profiles.Set(selected, key, value); err != nil {
+		return err
+	}
+	if err := profiles.Save(); err != nil {
+		return err
+	}
+	stored, _ := profiles.Get(selected, key)
+
+	return render(cmd, result{
+		value: configSetting{Profile: selected, Key: key, Value: stored},
+		table: func(w io.Writer) {
+			if stored == "" {
+				fmt.Fprintf(w, "✅ Removed %s from profile %s\n", key, selected)
+				return
+			}
+			fmt.Fprintf(w, "✅ Set %s to %s in profile %s\n", key, stored, selected)
+		},
+	})
+}
+
+func runConfigUse(cmd *cobra.Command, args []string) error {
+	profiles, err := config.LoadProfiles()
+	if err != nil {
+		return err
+	}
+	if err := profiles.Use(args[0]); err != nil {
+		return err
+	}
+	if err := profiles.Save(); err != nil {
+		return err
+	}
+
+	return render(cmd, result{
+		value: configUse{CurrentProfile: args[0]},
+		table: func(w io.Writer) {
+			fmt.Fprintf(w, "✅ Now using profile %s\n", args[0])
+		},
+		raw: lines(args[0]),
+	})
+}
@@ -0,0 +1,58 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/owenHochwald/egg-carton/cli/api"
+)
+
+// Exit codes, so scripts can tell failures apart. egg hatch exits with the
+// command's own status instead.
+const (
+	ExitError          = 1 // Anything not listed below
+	ExitInvalidRequest = 2 // The API rejected the request as malformed
+	ExitNotFound       = 3 // The secret, version or team doesn't exist or has expired
+	ExitUnauthorized   = 4 // Not logged in, or the session can't be refreshed
+	ExitForbidden      = 5 // Logged in, but without access to the vault or team
+	ExitConflict       = 6 // Someone else changed the secret or team first, or it is in the trash
+	ExitThrottled      = 7 // Too many requests; retry after a pause
+	ExitIncompatible   = 8 // The deployment and the CLI share no API version
+)
+
+// ExitCode returns the exit code for an error returned by a command.
+func ExitCode(err error) int {
+	switch {
+	case errors.Is(err, api.ErrInvalidRequest):
+		return ExitInvalidRequest
+	case errors.Is(err, api.ErrNotFound), errors.Is(err, api.ErrExpired):
+		return ExitNotFound
+	case errors.Is(err, api.ErrUnauthorized):
+		return ExitUnauthorized
+	case errors.Is(err, api.ErrForbidden):
+		return ExitForbidden
+	case errors.Is(err, api.ErrConflict), errors.Is(err, api.ErrTrashed):
+		return ExitConflict
+	case errors.Is(err, api.ErrThrottled):
+		return ExitThrottled
+	case errors.Is(err, api.ErrIncompatible):
+		return ExitIncompatible
+	default:
+		return ExitError
+	}
+}
+
+// rewordedError replaces an error's message, keeping it for errors.Is so the
+// exit code still reflects it.
+type rewordedError struct {
+	message string
+	err     error
+}
+
+func (e *rewordedError) Error() string { return e.message }
+func (e *rewordedError) Unwrap() error { return e.err }
+
+// reword returns err with a friendlier message.
+func reword(err error, format string, args ...any) error {
+	return &rewordedError{message: fmt.Sprintf(format, args...), err: err}
+}
@@ -0,0 +1,69 @@
+package commands
+
+import (
+	"fmt"
+	"time"
+)
+
+// expiresSoon is how close to its expiry a secret gets a warning in listings
+const expiresSoon = 24 * time.Hour
+
+// parseExpiry turns --ttl or --expires into an expiry time. --expires takes
+// an RFC 3339 time or a date, which means midnight local time at the start
+// of that day. Neither flag means the secret never expires (the zero time).
+func parseExpiry(ttl time.Duration, expires string) (time.Time, error) {
+	switch {
+	case ttl != 0 && expires != "":
+		return time.Time{}, fmt.Errorf("use either --ttl or --expires, not both")
+	case ttl < 0:
+		return time.Time{}, fmt.Errorf("--ttl must be positive")
+	case ttl > 0:
+		return time.Now().Add(ttl), nil
+	case expires == "":
+		return time.Time{}, nil
+	}
+
+	expiry, err := time.Parse(time.RFC3339, expires)
+	if err != nil {
+		expiry, err = time.ParseInLocation(time.DateOnly, expires, time.Local)
+	}
+	if err != nil {
+		return time.Time{}, fmt.Errorf("--expires must be a date (2026-12-31) or RFC 3339 time")
+	}
+	if !expiry.After(time.Now()) {
+		return time.Time{}, fmt.Errorf("--expires must be in the future")
+	}
+	return expiry, nil
+}
+
+// describeExpiry formats an RFC 3339 expiry with the time remaining, e.g.
+// "2026-12-31T00:00:00Z (in 3h20m)". soon reports whether that is less than
+// expiresSoon away.
+func describeExpiry(expiresAt string) (description string, soon bool) {
+	expiry, err := time.Parse(time.RFC3339, expiresAt)
+	if err != nil {
+		return expiresAt, false
+	}
+	remaining := time.Until(expiry)
+	if remaining <= 0 {
+		return expiresAt + " (expired)", true
+	}
+	return fmt.Sprintf("%s (in %s)", expiresAt, formatRemaining(remaining)), remaining < expiresSoon
+}
+
+// formatRemaining rounds a duration to the two most significant units
+func formatRemaining(d time.Duration) string {
+	days := int(d.Hours()) / 24
+	hours := int(d.Hours()) % 24
+	minutes := int(d.Minutes()) % 60
+	switch {
+	case days > 0:
+		return fmt.Sprintf("%dd%dh", days, hours)
+	case hours > 0:
+		return fmt.Sprintf("%dh%dm", hours, minutes)
+	case minutes > 0:
+		return fmt.Sprintf("%dm", minutes)
+	default:
+		return "less than a minute"
+	}
+}
@@ -0,0 +1,175 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/owenHochwald/egg-carton/cli/config"
+	"github.com/owenHochwald/egg-carton/cli/envfile"
+	"github.com/spf13/cobra"
+)
+
+// ExportCmd represents the export command
+var ExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Write secrets out for other tools",
+	Long: `Decrypt the secrets of a vault and write them in a format other tools read.
+
+Formats:
+  dotenv      KEY=value lines, quoted and escaped where needed (the default)
+  json        a flat JSON object
+  yaml        a flat YAML mapping
+  shell       export KEY='value' lines, for eval or source
+  docker-env  KEY=value lines for docker run --env-file and compose env_file;
+              values are taken literally, so multi-line values are refused
+  k8s-secret  a Kubernetes Secret manifest for kubectl apply, named with
+              --name (default from the project and env)
+
+--include and --exclude pick secrets by glob, e.g. 'STRIPE_*'. Keys can be
+renamed with --rename OLD=NEW, or by --strip-prefix and --prefix, in that
+order. Secrets go to standard output unless --file is given; the file is
+replaced as a whole and readable only by you (mode 0600).
+
+Example:
+  egg export --project api --env dev --file .env
+  egg export --format docker-env --include 'DB_*' | docker run --env-file /dev/stdin app
+  egg export --format k8s-secret --project api --env prod | kubectl apply -f -
+  eval "$(egg export --format shell --strip-prefix LEGACY_)"`,
+	Args: cobra.NoArgs,
+	RunE: runExport,
+}
+
+func init() {
+	ExportCmd.Flags().String("format", string(envfile.Dotenv), "dotenv, json, yaml, shell, docker-env or k8s-secret")
+	ExportCmd.Flags().String("file", "", "write to this file, with mode 0600, instead of standard output")
+	ExportCmd.Flags().StringArray("include", nil, "only export keys matching this glob (repeatable)")
+	ExportCmd.Flags().StringArray("exclude", nil, "don't export keys matching this glob (repeatable)")
+	ExportCmd.Flags().StringArray("rename", nil, "export a key under another name, as OLD=NEW (repeatable)")
+	ExportCmd.Flags().String("strip-prefix", "", "remove this prefix from keys that have it")
+	ExportCmd.Flags().String("prefix", "", "put this in front of every key")
+	ExportCmd.Flags().String("name", "", "name of the Kubernetes Secret (default from the project and env)")
+	ExportCmd.Flags().String("k8s-namespace", "", "Kubernetes namespace of the Secret")
+	addNamespaceFlags(ExportCmd)
+}
+
+func runExport(cmd *cobra.Command, args []string) error {
+	formatName, _ := cmd.Flags().GetString("format")
+	file, _ := cmd.Flags().GetString("file")
+	include, _ := cmd.Flags().GetStringArray("include")
+	exclude, _ := cmd.Flags().GetStringArray("exclude")
+	renames, _ := cmd.Flags().GetStringArray("rename")
+	stripPrefix, _ := cmd.Flags().GetString("strip-prefix")
+	prefix, _ := cmd.Flags().GetString("prefix")
+	name, _ := cmd.Flags().GetString("name")
+	k8sNamespace, _ := cmd.Flags().GetString("k8s-namespace")
+
+	format, err := envfile.ParseExportFormat(formatName)
+	if err != nil {
+		return err
+	}
+	// The secrets themselves are the output, in --format
+	switch output, _ := outputFormat(cmd); output {
+	case outputJSON, outputYAML:
+		return fmt.Errorf("egg export doesn't take --output; use --format %s", output)
+	case outputRaw:
+		return fmt.Errorf("egg export doesn't take --output; use --format")
+	}
+	for _, pattern := range slices.Concat(include, exclude) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+	}
+	renamed := make(map[string]string, len(renames))
+	for _, rename := range renames {
+		from, to, ok := strings.Cut(rename, "=")
+		if !ok || from == "" || to == "" {
+			return fmt.Errorf("invalid --rename %q: use OLD=NEW", rename)
+		}
+		renamed[from] = to
+	}
+
+	client, owner, err := newAuthenticatedClient()
+	if err != nil {
+		return err
+	}
+
+	vault, err := scopeFromFlags(cmd, owner)
+	if err != nil {
+		return err
+	}
+
+	var entries []envfile.Entry
+	exportedAs := make(map[string]string) // New key to the secret it came from
+	for egg, err := range client.Eggs(vault.owner, vault.namespace) {
+		if err != nil {
+			return fmt.Errorf("failed to get eggs: %w", err)
+		}
+		// Without a namespace only export secrets that don't belong to one
+		if vault.namespace.IsZero() && (egg.Project != "" || egg.Env != "") {
+			continue
+		}
+		if !selected(egg.SecretID, include, exclude) {
+			continue
+		}
+
+		key, ok := renamed[egg.SecretID]
+		if !ok {
+			key = prefix + strings.TrimPrefix(egg.SecretID, stripPrefix)
+		}
+		if other, ok := exportedAs[key]; ok {
+			return fmt.Errorf("%s and %s would both be exported as %s", other, egg.SecretID, key)
+		}
+		exportedAs[key] = egg.SecretID
+		entries = append(entries, envfile.Entry{Key: key, Value: egg.Plaintext})
+	}
+	slices.SortFunc(entries, func(a, b envfile.Entry) int { return strings.Compare(a.Key, b.Key) })
+
+	opts := envfile.WriteOptions{Name: name, Namespace: k8sNamespace}
+	if format == envfile.K8sSecret && opts.Name == "" {
+		opts.Name = secretName(vault)
+	}
+
+	if file == "" {
+		return envfile.Write(os.Stdout, entries, format, opts)
+	}
+	err = config.WritePrivateFile(file, func(w io.Writer) error {
+		return envfile.Write(w, entries, format, opts)
+	})
+	if err != nil {
+		return err
+	}
+	status("🥚 Exported %d secret(s) to %s\n", len(entries), file)
+	return nil
+}
+
+// selected reports whether a key matches any include pattern (or there are
+// none) and no exclude pattern. The patterns were checked beforehand.
+func selected(key string, include, exclude []string) bool {
+	matches := func(pattern string) bool {
+		ok, _ := path.Match(pattern, key)
+		return ok
+	}
+	if len(include) > 0 && !slices.ContainsFunc(include, matches) {
+		return false
+	}
+	return !slices.ContainsFunc(exclude, matches)
+}
+
+var nonNameChars = regexp.MustCompile(`[^a-z0-9.-]+`)
+
+// secretName derives a Kubernetes Secret name from the namespace, e.g.
+// "api-prod", falling back to "eggcarton".
+func secretName(vault scope) string {
+	parts := []string{vault.namespace.Project, vault.namespace.Env}
+	name := nonNameChars.ReplaceAllString(strings.ToLower(strings.Join(parts, "-")), "-")
+	name = strings.Trim(name, "-.")
+	if name == "" {
+		return "eggcarton"
+	}
+	return name
+}
@@ -0,0 +1,45 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/owenHochwald/egg-carton/cli/config"
+	"github.com/spf13/cobra"
+)
+
+// profile is the --profile flag, empty unless given
+var profile string
+
+// AddGlobalFlags registers the flags every command takes on the root
+// command: --output, the format results are printed in on stdout (progress
+// and status messages go to stderr), and --profile, the deployment to use.
+func AddGlobalFlags(root *cobra.Command) {
+	root.PersistentFlags().StringP("output", "o", outputTable, "output format: table, json, yaml or raw")
+	root.PersistentFlags().StringVar(&profile, "profile", "", "configuration profile to use (default $EGG_PROFILE or the current profile)")
+	root.PersistentPreRunE = func(cmd *cobra.Command, args []string) error {
+		if _, err := outputFormat(cmd); err != nil {
+			return err
+		}
+		// The command line made sense, so any error from here on isn't
+		// helped by the usage, which would bury it on stderr
+		cmd.SilenceUsage = true
+		return nil
+	}
+}
+
+// loadConfig loads the configuration of the selected profile, moving any
+// tokens still in a plaintext credentials file into its token store
+func loadConfig() (*config.Config, error) {
+	cfg, err := config.LoadConfig(profile)
+	if err != nil {
+		return nil, err
+	}
+	migrated, err := cfg.MigrateTokens()
+	if err != nil {
+		return nil, fmt.Errorf("failed to move your tokens out of %s: %w", cfg.TokenPath, err)
+	}
+	if migrated {
+		status("🔒 Moved your tokens from %s into %s\n", cfg.TokenPath, cfg.TokenStore.Name())
+	}
+	return cfg, nil
+}
@@ -0,0 +1,86 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/owenHochwald/egg-carton/cli/api"
+	"github.com/spf13/cobra"
+)
+
+// HistoryCmd represents the history command
+var HistoryCmd = &cobra.Command{
+	Use:   "history [key]",
+	Short: "Show the version history of a secret",
+	Long: `List every stored version of a secret, oldest first.
+
+Use --version to decrypt and print the value of one specific version.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runHistory,
+}
+
+func init() {
+	HistoryCmd.Flags().Int("version", 0, "print the value of this version")
+	addNamespaceFlags(HistoryCmd)
+}
+
+func runHistory(cmd *cobra.Command, args []string) error {
+	key := args[0]
+	version, _ := cmd.Flags().GetInt("version")
+
+	client, owner, err := newAuthenticatedClient()
+	if err != nil {
+		return err
+	}
+
+	vault, err := scopeFromFlags(cmd, owner)
+	if err != nil {
+		return err
+	}
+
+	// Print a single version's value
+	if version > 0 {
+		egg, err := client.GetEggVersion(vault.owner, vault.namespace, key, version)
+		if err != nil {
+			return fmt.Errorf("failed to get version %d of %s: %w", version, key, err)
+		}
+		return render(cmd, result{
+			value: egg,
+			table: func(w io.Writer) {
+				fmt.Fprintf(w, "🥚 Secret: %s (version %d)\n", vault.describe(key), egg.Version)
+				fmt.Fprintf(w, "Value: %s\n", egg.Plaintext)
+				fmt.Fprintf(w, "Created: %s\n", egg.CreatedAt)
+			},
+			// As egg get, just the value without a newline
+			raw: func(w io.Writer) { io.WriteString(w, egg.Plaintext) },
+		})
+	}
+
+	versions, err := client.ListEggVersions(vault.owner, vault.namespace, key)
+	if err != nil {
+		return fmt.Errorf("failed to get history of %s: %w", key, err)
+	}
+
+	numbers := make([]string, len(versions))
+	for i, v := range versions {
+		numbers[i] = strconv.Itoa(v.Version)
+	}
+	return render(cmd, result{
+		value: api.ListEggVersionsResponse{Versions: nonNil(versions)},
+		table: func(w io.Writer) {
+			fmt.Fprintf(w, "📜 %d version(s) of %s:\n\n", len(versions), vault.describe(key))
+			for _, v := range versions {
+				fmt.Fprintf(w, "Version: %d\n", v.Version)
+				fmt.Fprintf(w, "Created: %s\n", v.CreatedAt)
+				fmt.Fprintf(w, "Created By: %s\n", v.CreatedBy)
+				if v.ExpiresAt != "" {
+					expiry, _ := describeExpiry(v.ExpiresAt)
+					fmt.Fprintf(w, "Expires: %s\n", expiry)
+				}
+				fmt.Fprintln(w, "---")
+			}
+		},
+		raw: lines(numbers...),
+	})
+}
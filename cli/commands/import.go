@@ -0,0 +1,365 @@
+package commands
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+
+	"github.com/owenHochwald/egg-carton/cli/api"
+	"github.com/owenHochwald/egg-carton/cli/envfile"
+	"github.com/spf13/cobra"
+)
+
+// Conflict policies for egg import, for keys that already exist in the vault
+const (
+	conflictSkip      = "skip"
+	conflictOverwrite = "overwrite"
+	conflictFail      = "fail"
+)
+
+// ImportCmd represents the import command
+var ImportCmd = &cobra.Command{
+	Use:   "import [file]",
+	Short: "Store every secret in a .env, JSON or YAML file",
+	Long: `Read secrets from a file and lay them all in your vault.
+
+The format is taken from the file name (.json, .yaml or .yml, anything else
+is read as a dotenv file) unless --format is given; use "-" to read standard
+input. Dotenv files may quote values, span them over several lines inside
+quotes and put "export" in front of keys. JSON and YAML files must hold a
+flat object of keys to strings, numbers or booleans.
+
+--on-conflict decides what happens to keys that are already in the vault:
+  fail       import nothing if any of them exist (the default)
+  skip       leave them alone and import the rest
+  overwrite  lay a new version over them
+
+--dry-run shows what would be created, overwritten or skipped without
+writing anything. --shred overwrites the file with random data and deletes
+it, once every entry in it has been laid; a file with skipped entries is
+kept. On SSDs and copy-on-write file systems the old blocks may survive, so
+treat it as a convenience rather than a guarantee.
+
+Example:
+  egg import .env
+  egg import --project api --env dev --prefix STRIPE_ --dry-run stripe.json
+  egg import --on-conflict overwrite --shred .env.production`,
+	Args: cobra.ExactArgs(1),
+	RunE: runImport,
+}
+
+func init() {
+	ImportCmd.Flags().String("format", "", "file format: dotenv, json or yaml (default from the file name)")
+	ImportCmd.Flags().String("prefix", "", "put this in front of every key, e.g. LEGACY_")
+	ImportCmd.Flags().String("on-conflict", conflictFail, "what to do with keys that already exist: fail, skip or overwrite")
+	ImportCmd.Flags().Bool("dry-run", false, "show what would be imported without writing anything")
+	ImportCmd.Flags().Bool("shred", false, "overwrite and delete the file after a successful import")
+	addNamespaceFlags(ImportCmd)
+}
+
+func runImport(cmd *cobra.Command, args []string) error {
+	path := args[0]
+	formatName, _ := cmd.Flags().GetString("format")
+	prefix, _ := cmd.Flags().GetString("prefix")
+	policy, _ := cmd.Flags().GetString("on-conflict")
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+	shred, _ := cmd.Flags().GetBool("shred")
+
+	if !slices.Contains([]string{conflictFail, conflictSkip, conflictOverwrite}, policy) {
+		return fmt.Errorf("invalid --on-conflict %q: use fail, skip or overwrite", policy)
+	}
+	if shred && path == "-" {
+		return fmt.Errorf("--shred needs a file, not standard input")
+	}
+
+	format := envfile.FormatOf(path)
+	if formatName != "" {
+		var err error
+		if format, err = envfile.ParseFormat(formatName); err != nil {
+			return err
+		}
+	}
+
+	entries, err := readEntries(path, format)
+	if err != nil {
+		return err
+	}
+
+	client, owner, err := newAuthenticatedClient()
+	if err != nil {
+		return err
+	}
+
+	vault, err := scopeFromFlags(cmd, owner)
+	if err != nil {
+		return err
+	}
+
+	// Find out which keys are taken, to plan the import
+	existing := make(map[string]bool)
+	for egg, err := range client.EggsMetadata(vault.owner, vault.namespace) {
+		if err != nil {
+			return fmt.Errorf("failed to list eggs: %w", err)
+		}
+		// Without a namespace only secrets that don't belong to one clash
+		if vault.namespace.IsZero() && (egg.Project != "" || egg.Env != "") {
+			continue
+		}
+		existing[egg.SecretID] = true
+	}
+
+	// Plan what to do with each key; items are the secrets to lay, and
+	// planned[i] is the result of items[i]
+	var results []importResult
+	var items []api.BatchPutItem
+	var planned []int
+	conflicts := 0
+	for _, entry := range entries {
+		key := prefix + entry.Key
+		switch {
+		case entry.Value == "":
+			results = append(results, importResult{SecretID: key, Action: actionSkip, Reason: "empty"})
+		case existing[key] && policy == conflictSkip:
+			results = append(results, importResult{SecretID: key, Action: actionSkip, Reason: "exists"})
+		case existing[key] && policy == conflictFail:
+			results = append(results, importResult{SecretID: key, Action: actionConflict, Reason: "exists"})
+			conflicts++
+		default:
+			action := actionCreate
+			if existing[key] {
+				action = actionOverwrite
+			}
+			results = append(results, importResult{SecretID: key, Action: action})
+			planned = append(planned, len(results)-1)
+			// Only overwrite what was planned; anything laid meanwhile is a conflict
+			items = append(items, api.BatchPutItem{
+				Key:        key,
+				Value:      entry.Value,
+				PutOptions: api.PutOptions{IfAbsent: !existing[key]},
+			})
+		}
+	}
+
+	output := importOutput{
+		Owner:   vault.owner,
+		Project: vault.namespace.Project,
+		Env:     vault.namespace.Env,
+		DryRun:  dryRun,
+		Results: nonNil(results),
+	}
+	var summary string
+	var importErr error
+	switch {
+	case conflicts > 0:
+		summary = fmt.Sprintf("%d secret(s) already exist; nothing was imported (use --on-conflict skip or overwrite)", conflicts)
+		if !dryRun {
+			importErr = reword(api.ErrConflict, "%s", summary)
+			summary = ""
+		} else {
+			summary = "Dry run: " + summary
+		}
+	case dryRun:
+		summary = fmt.Sprintf("Dry run: %d secret(s) would be imported into %s", len(items), vaultName(vault))
+	case len(items) == 0:
+		summary = "Nothing to import."
+	default:
+		source := path
+		if path == "-" {
+			source = "standard input"
+		}
+		status("📦 Importing %d secret(s) from %s\n", len(items), source)
+		laid, err := client.PutEggs(vault.owner, vault.namespace, items)
+		if err != nil {
+			return fmt.Errorf("failed to import eggs: %w", err)
+		}
+
+		var firstErr error
+		failed := 0
+		for i, r := range laid {
+			result := &output.Results[planned[i]]
+			err := r.Err()
+			if err == nil {
+				result.Version = r.Version
+				continue
+			}
+			if errors.Is(err, api.ErrConflict) && items[i].IfAbsent {
+				err = reword(err, "laid by someone else during the import")
+			}
+			var apiErr *api.APIError
+			if errors.As(err, &apiErr) {
+				result.Code = apiErr.Code
+			}
+			result.Error = err.Error()
+			if firstErr == nil {
+				firstErr = err
+			}
+			failed++
+		}
+		if failed > 0 {
+			importErr = reword(firstErr, "%d of %d secrets couldn't be imported", failed, len(laid))
+		} else {
+			summary = fmt.Sprintf("🥚 Imported %d secret(s) into %s", len(laid), vaultName(vault))
+		}
+	}
+
+	err = render(cmd, result{
+		value: output,
+		table: func(w io.Writer) {
+			for _, r := range output.Results {
+				fmt.Fprintln(w, r.describe(vault, dryRun))
+			}
+			if summary != "" {
+				fmt.Fprintln(w, summary)
+			}
+		},
+		raw: lines(output.imported()...),
+	})
+	if err != nil {
+		return err
+	}
+	if importErr != nil {
+		if shred {
+			status("Kept %s since not every secret was imported\n", path)
+		}
+		return importErr
+	}
+	if dryRun {
+		return nil
+	}
+	return maybeShred(path, shred, output)
+}
+
+// What egg import does, or in a dry run would do, with a key
+const (
+	actionCreate    = "create"
+	actionOverwrite = "overwrite"
+	actionSkip      = "skip"     // Left alone; see Reason
+	actionConflict  = "conflict" // Already exists, failing the import
+)
+
+// importOutput is the result of egg import
+type importOutput struct {
+	Owner   string         `json:"owner"`
+	Project string         `json:"project,omitempty"`
+	Env     string         `json:"env,omitempty"`
+	DryRun  bool           `json:"dry_run"`
+	Results []importResult `json:"results"`
+}
+
+// importResult is the outcome for one key. Error is set if laying the
+// secret failed, and Version once it succeeded.
+type importResult struct {
+	SecretID string `json:"secret_id"`
+	Action   string `json:"action"`
+	Reason   string `json:"reason,omitempty"` // Why a key was skipped: empty or exists
+	Version  int    `json:"version,omitempty"`
+	Code     string `json:"code,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// imported lists the keys that were laid
+func (o importOutput) imported() []string {
+	var keys []string
+	for _, r := range o.Results {
+		if r.Version > 0 {
+			keys = append(keys, r.SecretID)
+		}
+	}
+	return keys
+}
+
+// describe is the table line for a key
+func (r importResult) describe(vault scope, dryRun bool) string {
+	key := vault.describe(r.SecretID)
+	switch {
+	case r.Reason == "empty":
+		return fmt.Sprintf("⚠️  %s: empty value, not imported", key)
+	case r.Action == actionSkip:
+		return fmt.Sprintf("⏭️  %s: already exists, skipped", key)
+	case r.Action == actionConflict:
+		return fmt.Sprintf("❌ %s: already exists", key)
+	case dryRun && r.Action == actionCreate:
+		return fmt.Sprintf("+ %s (create)", key)
+	case dryRun:
+		return fmt.Sprintf("~ %s (overwrite)", key)
+	case r.Error != "":
+		return fmt.Sprintf("❌ %s: %s", key, r.Error)
+	case r.Action == actionCreate:
+		return fmt.Sprintf("✅ %s created (version %d)", key, r.Version)
+	default:
+		return fmt.Sprintf("✅ %s overwritten (version %d)", key, r.Version)
+	}
+}
+
+// readEntries parses a secrets file, or standard input for "-"
+func readEntries(path string, format envfile.Format) ([]envfile.Entry, error) {
+	var in io.Reader = os.Stdin
+	if path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open %s: %w", path, err)
+		}
+		defer file.Close()
+		in = file
+	}
+
+	entries, err := envfile.Parse(in, format)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s as %s: %w", path, format, err)
+	}
+	return entries, nil
+}
+
+// vaultName names the vault and namespace for a summary line
+func vaultName(vault scope) string {
+	if label := vault.label(); label != "" {
+		return label
+	}
+	return "your vault"
+}
+
+// maybeShred shreds the imported file if asked to, but only once every
+// entry in it is in the vault: skipped entries may exist nowhere else.
+func maybeShred(path string, shred bool, output importOutput) error {
+	if !shred {
+		return nil
+	}
+	if kept := len(output.Results) - len(output.imported()); kept > 0 {
+		status("Kept %s: %d entries were skipped\n", path, kept)
+		return nil
+	}
+	if err := shredFile(path); err != nil {
+		return fmt.Errorf("secrets were imported, but %s couldn't be shredded: %w", path, err)
+	}
+	status("🔥 Shredded %s\n", path)
+	return nil
+}
+
+// shredFile overwrites a file with random data, flushes it to disk and
+// deletes it.
+func shredFile(path string) error {
+	file, err := os.OpenFile(path, os.O_WRONLY, 0)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	if _, err := io.CopyN(file, rand.Reader, info.Size()); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return os.Remove(path)
+}
@@ -0,0 +1,47 @@
+package commands
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMaybeShred(t *testing.T) {
+	laid := importResult{SecretID: "DB_URL", Action: actionCreate, Version: 1}
+	tests := []struct {
+		name    string
+		shred   bool
+		results []importResult
+		kept    bool
+	}{
+		{"everything laid", true, []importResult{laid}, false},
+		{"skipped existing key", true, []importResult{laid, {SecretID: "API_KEY", Action: actionSkip, Reason: "exists"}}, true},
+		{"skipped empty value", true, []importResult{laid, {SecretID: "EMPTY", Action: actionSkip, Reason: "empty"}}, true},
+		{"nothing to import", true, []importResult{{SecretID: "API_KEY", Action: actionSkip, Reason: "exists"}}, true},
+		{"failed to lay", true, []importResult{laid, {SecretID: "TOKEN", Action: actionCreate, Error: "conflict"}}, true},
+		{"no --shred", false, []importResult{laid}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), ".env")
+			if err := os.WriteFile(path, []byte("DB_URL=postgres://\nAPI_KEY=abc\n"), 0600); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := maybeShred(path, tt.shred, importOutput{Results: tt.results}); err != nil {
+				t.Fatalf("maybeShred: %v", err)
+			}
+			data, err := os.ReadFile(path)
+			switch {
+			case tt.kept && err != nil:
+				t.Errorf("file removed, want it kept: %v", err)
+			case tt.kept && string(data) != "DB_URL=postgres://\nAPI_KEY=abc\n":
+				t.Errorf("file changed to %q, want it untouched", data)
+			case !tt.kept && !errors.Is(err, fs.ErrNotExist):
+				t.Errorf("file still there (%v), want it shredded", err)
+			}
+		})
+	}
+}
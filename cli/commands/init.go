@@ -0,0 +1,115 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/owenHochwald/egg-carton/cli/api"
+	"github.com/owenHochwald/egg-carton/cli/config"
+	"github.com/spf13/cobra"
+)
+
+// InitCmd represents the init command
+var InitCmd = &cobra.Command{
+	Use:   "init",
+	Short: "Configure the CLI for a deployment",
+	Long: `Point the CLI at a deployment, e.g. one you just created with terraform apply.
+
+Fetches the deployment's discovery document from ` + api.DiscoveryPath + `,
+checks that it speaks an API version this CLI understands, and writes its
+endpoint and Cognito settings to a profile in ~/.eggcarton/` + config.ProfilesFile + `,
+which becomes the current profile. The profile is --profile, or the default
+one; its project and env defaults are kept.
+
+Example:
+  egg init --endpoint https://abc123.execute-api.us-west-1.amazonaws.com/dev
+  egg init --endpoint http://localhost:8787 --profile local`,
+	Args: cobra.NoArgs,
+	RunE: runInit,
+}
+
+func init() {
+	InitCmd.Flags().String("endpoint", "", "base URL of the deployment's API (the terraform api_endpoint output)")
+	InitCmd.Flags().Bool("force", false, "replace a profile that points at another deployment")
+	InitCmd.MarkFlagRequired("endpoint")
+}
+
+// initOutput is the result of egg init
+type initOutput struct {
+	Profile     string   `json:"profile"`
+	APIEndpoint string   `json:"api_endpoint"`
+	APIVersion  int      `json:"api_version"` // The version the CLI and the deployment both speak
+	Cognito     bool     `json:"cognito"`     // Whether the deployment logs users in with Cognito
+	Features    []string `json:"features"`
+	Path        string   `json:"path"`
+}
+
+func runInit(cmd *cobra.Command, args []string) error {
+	endpoint, _ := cmd.Flags().GetString("endpoint")
+	force, _ := cmd.Flags().GetBool("force")
+
+	name := profile
+	if name == "" {
+		name = config.DefaultProfile
+	}
+	profiles, err := config.LoadProfiles()
+	if err != nil {
+		return err
+	}
+
+	status("🔎 Fetching %s...\n", api.DiscoveryURL(endpoint))
+	discovery, err := api.Discover(endpoint)
+	if err != nil {
+		return err
+	}
+
+	if current, _ := profiles.Get(name, "api_endpoint"); current != "" && current != discovery.APIEndpoint && !force {
+		return fmt.Errorf("profile %s already points at %s; pass --force to replace it, or --profile to write another one", name, current)
+	}
+
+	// Settings the document doesn't give are removed, so they don't linger
+	// from another deployment
+	var cognito api.DiscoveryCognito
+	if discovery.Cognito != nil {
+		cognito = *discovery.Cognito
+	}
+	for key, value := range map[string]string{
+		"api_endpoint":         discovery.APIEndpoint,
+		"cognito.user_pool_id": cognito.UserPoolID,
+		"cognito.client_id":    cognito.ClientID,
+		"cognito.domain":       cognito.Domain,
+		"cognito.region":       cognito.Region,
+	} {
+		if err := profiles.Set(name, key, value); err != nil {
+			return err
+		}
+	}
+	if err := profiles.Use(name); err != nil {
+		return err
+	}
+	if err := profiles.Save(); err != nil {
+		return err
+	}
+
+	out := initOutput{
+		Profile:     name,
+		APIEndpoint: discovery.APIEndpoint,
+		APIVersion:  discovery.Version(),
+		Cognito:     discovery.Cognito != nil,
+		Features:    nonNil(discovery.Features),
+		Path:        profiles.Path,
+	}
+	return render(cmd, result{
+		value: out,
+		table: func(w io.Writer) {
+			fmt.Fprintf(w, "🥚 Found egg-carton API version %d at %s\n", out.APIVersion, out.APIEndpoint)
+			fmt.Fprintf(w, "✅ Wrote profile %s to %s and made it current\n", out.Profile, out.Path)
+			if out.Cognito {
+				fmt.Fprintln(w, "Next, log in with: egg login")
+			} else {
+				fmt.Fprintln(w, "The deployment has no Cognito; log in with: egg login --token TOKEN")
+			}
+		},
+		raw: lines(name),
+	})
+}
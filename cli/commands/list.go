@@ -0,0 +1,97 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/owenHochwald/egg-carton/cli/api"
+	"github.com/spf13/cobra"
+)
+
+// ListCmd represents the list command
+var ListCmd = &cobra.Command{
+	Use:     "list",
+	Aliases: []string{"ls"},
+	Short:   "List the secrets in your vault",
+	Long: `List the keys in your EggCarton vault without decrypting them.
+
+Only metadata (key, version, size, creation time and expiry) is fetched;
+secret values never leave the vault. Secrets expiring within a day are
+flagged. With --project/--env (or a project config
+file) only that namespace is listed.`,
+	Args: cobra.NoArgs,
+	RunE: runList,
+}
+
+func init() {
+	addNamespaceFlags(ListCmd)
+}
+
+func runList(cmd *cobra.Command, args []string) error {
+	client, owner, err := newAuthenticatedClient()
+	if err != nil {
+		return err
+	}
+
+	vault, err := scopeFromFlags(cmd, owner)
+	if err != nil {
+		return err
+	}
+
+	format, err := outputFormat(cmd)
+	if err != nil {
+		return err
+	}
+
+	if format != outputTable {
+		eggs, err := client.ListEggs(vault.owner, vault.namespace)
+		if err != nil {
+			return fmt.Errorf("failed to list eggs: %w", err)
+		}
+		keys := make([]string, len(eggs))
+		for i, egg := range eggs {
+			keys[i] = egg.SecretID
+		}
+		return render(cmd, result{
+			value: api.ListEggsResponse{Eggs: nonNil(eggs)},
+			raw:   lines(keys...),
+		})
+	}
+
+	// Print each page as it arrives, so large vaults start listing at once
+	found, expiringSoon := 0, 0
+	for egg, err := range client.EggsMetadata(vault.owner, vault.namespace) {
+		if err != nil {
+			return fmt.Errorf("failed to list eggs: %w", err)
+		}
+		found++
+		fmt.Printf("Key: %s\n", egg.SecretID)
+		if egg.Project != "" || egg.Env != "" {
+			fmt.Printf("Project: %s\n", egg.Project)
+			fmt.Printf("Env: %s\n", egg.Env)
+		}
+		fmt.Printf("Version: %d\n", egg.Version)
+		fmt.Printf("Size: %d bytes\n", egg.Size)
+		fmt.Printf("Created: %s\n", egg.CreatedAt)
+		if egg.ExpiresAt != "" {
+			expiry, soon := describeExpiry(egg.ExpiresAt)
+			if soon {
+				expiringSoon++
+				fmt.Printf("Expires: %s ⚠️\n", expiry)
+			} else {
+				fmt.Printf("Expires: %s\n", expiry)
+			}
+		}
+		fmt.Println("---")
+	}
+
+	if found == 0 {
+		fmt.Println("No secrets found in your vault.")
+		return nil
+	}
+	fmt.Printf("\n🥚 Found %d secret(s)\n", found)
+	if expiringSoon > 0 {
+		status("\n⚠️  %d secret(s) expire within a day\n", expiringSoon)
+	}
+
+	return nil
+}
@@ -3,6 +3,7 @@ package commands
 import (
 	"context"
 	"fmt"
+	"io"
 	"time"
 
 	"github.com/owenHochwald/egg-carton/cli/auth"
@@ -18,26 +19,47 @@ var LoginCmd = &cobra.Command{
 	Long: `Opens your browser to authenticate with AWS Cognito.
 	
 Uses PKCE flow for secure authentication without client secrets.
-Tokens are stored locally in ~/.eggcarton/credentials.json`,
+Tokens are kept in the OS keyring (the macOS Keychain, or the Secret
+Service through secret-tool) if there is one, and otherwise in
+~/.eggcarton/credentials.enc (credentials-<profile>.enc for other
+profiles), encrypted under a passphrase you choose. Set token_store with
+'egg config set' to pick one; "file" keeps the old plaintext file.
+
+With --token, stores the given access token instead, e.g. one printed by
+the local server's -mint-token flag.`,
 	RunE: runLogin,
 }
 
+func init() {
+	LoginCmd.Flags().String("token", "", "store this access token instead of logging in through the browser")
+}
+
 func runLogin(cmd *cobra.Command, args []string) error {
-	fmt.Println("🔐 Starting authentication flow...")
+	status("🔐 Starting authentication flow...\n")
 
-	cfg, err := config.LoadConfig()
+	cfg, err := loadConfig()
 	if err != nil {
 		return fmt.Errorf("failed to load config: %w", err)
 	}
 
+	if token, _ := cmd.Flags().GetString("token"); token != "" {
+		tokens, err := config.TokensFromAccessToken(token)
+		if err != nil {
+			return fmt.Errorf("invalid token: %w", err)
+		}
+		if err := cfg.SaveTokens(tokens); err != nil {
+			return fmt.Errorf("failed to save tokens: %w", err)
+		}
+		status("🔒 Token stored in %s\n", cfg.TokenStore.Name())
+		return loggedIn(cmd, tokens, "🎉 Token saved!")
+	}
+
 	existingTokens, _ := cfg.LoadTokens()
 	if existingTokens != nil && existingTokens.IsTokenValid() {
-		fmt.Println("You are already logged in!")
-		fmt.Println("Your session is still valid. Use --force to re-authenticate.")
-		return nil
+		return loggedIn(cmd, existingTokens, "You are already logged in!\nYour session is still valid. Use --force to re-authenticate.")
 	}
 
-	fmt.Println("Generating PKCE challenge...")
+	status("Generating PKCE challenge...\n")
 	pkce, err := auth.GeneratePKCEChallenge()
 	if err != nil {
 		return fmt.Errorf("failed to generate PKCE: %w", err)
@@ -50,7 +72,7 @@ func runLogin(cmd *cobra.Command, args []string) error {
 		pkce.Challenge,
 	)
 
-	fmt.Printf("If browser doesn't open, visit:\n   %s\n\n", authURL)
+	status("If browser doesn't open, visit:\n   %s\n\n", authURL)
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
 	defer cancel()
@@ -70,17 +92,17 @@ func runLogin(cmd *cobra.Command, args []string) error {
 
 	time.Sleep(500 * time.Millisecond) // Give server time to start
 	if err := browser.OpenURL(authURL); err != nil {
-		fmt.Printf("Failed to open browser automatically: %v\n", err)
-		fmt.Printf("Please open this URL manually:\n%s\n", authURL)
+		status("Failed to open browser automatically: %v\n", err)
+		status("Please open this URL manually:\n%s\n", authURL)
 	}
 
 	if err := <-serverErrChan; err != nil {
 		return fmt.Errorf("authentication failed: %w", err)
 	}
 
-	fmt.Println("Authorization code received!")
+	status("Authorization code received!\n")
 
-	fmt.Println("Exchanging code for tokens...")
+	status("Exchanging code for tokens...\n")
 	tokens, err := auth.ExchangeCodeForTokens(
 		cfg.GetTokenURL(),
 		cfg.CognitoConfig.ClientID,
@@ -95,8 +117,25 @@ func runLogin(cmd *cobra.Command, args []string) error {
 	if err := cfg.SaveTokens(tokens); err != nil {
 		return fmt.Errorf("failed to save tokens: %w", err)
 	}
+	status("🔒 Tokens stored in %s\n", cfg.TokenStore.Name())
+
+	return loggedIn(cmd, tokens, "\n🎉 Login successful!")
+}
 
-	fmt.Println("\n🎉 Login successful!")
+// loginOutput is the result of egg login
+type loginOutput struct {
+	UserID string `json:"user_id"`
+}
 
-	return nil
+// loggedIn reports who is now logged in
+func loggedIn(cmd *cobra.Command, tokens *config.TokenData, message string) error {
+	owner, err := tokens.Owner()
+	if err != nil {
+		return fmt.Errorf("failed to extract owner from token: %w", err)
+	}
+	return render(cmd, result{
+		value: loginOutput{UserID: owner},
+		table: func(w io.Writer) { fmt.Fprintln(w, message) },
+		raw:   lines(owner),
+	})
 }
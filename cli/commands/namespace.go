@@ -0,0 +1,95 @@
+package commands
+
+import (
+	"strings"
+
+	"github.com/owenHochwald/egg-carton/cli/api"
+	"github.com/owenHochwald/egg-carton/cli/config"
+	"github.com/spf13/cobra"
+)
+
+// addNamespaceFlags registers --project, --env and --team on a command that
+// works with secrets.
+func addNamespaceFlags(cmd *cobra.Command) {
+	cmd.Flags().String("project", "", "project the secrets belong to (default from "+config.ProjectConfigFile+")")
+	cmd.Flags().String("env", "", "environment within the project, e.g. dev or prod (default from "+config.ProjectConfigFile+")")
+	cmd.Flags().String("team", "", "use this team's shared vault instead of your own (default from "+config.ProjectConfigFile+")")
+}
+
+// scope is where a command's secrets live: whose vault, and which namespace
+// within it.
+type scope struct {
+	self      string // The logged-in user
+	owner     string
+	namespace api.Namespace
+}
+
+// scopeFromFlags resolves --project, --env and --team, falling back to the
+// nearest project config file, and then the profile, for whichever is unset.
+// self is the logged-in user, whose vault is used unless a team is selected.
+func scopeFromFlags(cmd *cobra.Command, self string) (scope, error) {
+	project, _ := cmd.Flags().GetString("project")
+	env, _ := cmd.Flags().GetString("env")
+	team, _ := cmd.Flags().GetString("team")
+	return resolveScope(project, env, team, self)
+}
+
+func resolveScope(project, env, team, self string) (scope, error) {
+	if project == "" || env == "" || team == "" {
+		defaults, err := config.LoadProjectConfig()
+		if err != nil {
+			return scope{}, err
+		}
+		if project == "" {
+			project = defaults.Project
+		}
+		if env == "" {
+			env = defaults.Env
+		}
+		if team == "" {
+			team = defaults.Team
+		}
+	}
+
+	// Then the profile's defaults, or EGG_PROJECT and EGG_ENV
+	if project == "" || env == "" {
+		cfg, err := loadConfig()
+		if err != nil {
+			return scope{}, err
+		}
+		if project == "" {
+			project = cfg.Project
+		}
+		if env == "" {
+			env = cfg.Env
+		}
+	}
+
+	s := scope{self: self, owner: self, namespace: api.Namespace{Project: project, Env: env}}
+	if team != "" {
+		s.owner = api.TeamOwner(team)
+	}
+	return s, nil
+}
+
+// label names the vault and namespace, e.g. "team:backend api/prod", or is
+// empty for the unscoped part of your own vault.
+func (s scope) label() string {
+	var parts []string
+	if s.owner != s.self {
+		parts = append(parts, s.owner)
+	}
+	if !s.namespace.IsZero() {
+		parts = append(parts, s.namespace.String())
+	}
+	return strings.Join(parts, " ")
+}
+
+// describe names a secret together with its vault and namespace, e.g.
+// "team:backend api/prod:DATABASE_URL".
+func (s scope) describe(key string) string {
+	if label := s.label(); label != "" {
+		return label + ":" + key
+	}
+	return key
+}
@@ -0,0 +1,129 @@
+package commands
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// Output formats for --output
+const (
+	outputTable = "table" // Human-readable, the default
+	outputJSON  = "json"
+	outputYAML  = "yaml"
+	outputRaw   = "raw" // Bare values, e.g. just the secret for egg get
+)
+
+// outputFormat returns the --output format, checking it's one we know
+func outputFormat(cmd *cobra.Command) (string, error) {
+	format, err := cmd.Flags().GetString("output")
+	if err != nil {
+		// egg hatch parses its own flags, so has no --output
+		return outputTable, nil
+	}
+	if !slices.Contains([]string{outputTable, outputJSON, outputYAML, outputRaw}, format) {
+		return "", fmt.Errorf("invalid --output %q: use table, json, yaml or raw", format)
+	}
+	return format, nil
+}
+
+// result is what a command prints when it's done. value is its stable
+// schema, printed as is for json and yaml; table prints it for people and
+// raw prints its bare values, if it has any, for scripts.
+type result struct {
+	value any
+	table func(w io.Writer)
+	raw   func(w io.Writer)
+}
+
+// render prints a command's result on stdout in the --output format
+func render(cmd *cobra.Command, r result) error {
+	format, err := outputFormat(cmd)
+	if err != nil {
+		return err
+	}
+	return r.write(os.Stdout, format)
+}
+
+// write prints the result to w in format
+func (r result) write(w io.Writer, format string) error {
+	switch format {
+	case outputJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		encoder.SetEscapeHTML(false)
+		return encoder.Encode(r.value)
+	case outputYAML:
+		return writeYAML(w, r.value)
+	case outputRaw:
+		if r.raw != nil {
+			r.raw(w)
+		}
+	default:
+		r.table(w)
+	}
+	return nil
+}
+
+// writeYAML prints v with the same field names as its JSON, by way of JSON
+func writeYAML(w io.Writer, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	var node yaml.Node
+	if err := yaml.Unmarshal(data, &node); err != nil {
+		return err
+	}
+	blockStyle(&node)
+
+	var b bytes.Buffer
+	encoder := yaml.NewEncoder(&b)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&node); err != nil {
+		return err
+	}
+	if err := encoder.Close(); err != nil {
+		return err
+	}
+	_, err = w.Write(b.Bytes())
+	return err
+}
+
+// blockStyle drops the flow style and quotes that JSON parses into, so
+// the YAML comes out in its usual block form.
+func blockStyle(node *yaml.Node) {
+	node.Style = 0
+	for _, child := range node.Content {
+		blockStyle(child)
+	}
+}
+
+// status prints a progress or status message on stderr, leaving stdout to
+// the result.
+func status(format string, args ...any) {
+	fmt.Fprintf(os.Stderr, format, args...)
+}
+
+// lines prints each value on a line of its own, for raw output
+func lines(values ...string) func(w io.Writer) {
+	return func(w io.Writer) {
+		for _, value := range values {
+			fmt.Fprintln(w, value)
+		}
+	}
+}
+
+// nonNil turns a nil slice into an empty one, so it is [] in JSON, not null
+func nonNil[T any](s []T) []T {
+	if s == nil {
+		return []T{}
+	}
+	return s
+}
@@ -0,0 +1,129 @@
+package commands
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+type testOutput struct {
+	Owner string   `json:"owner"`
+	Eggs  []string `json:"eggs"`
+	Note  string   `json:"note,omitempty"`
+}
+
+func TestResultWrite(t *testing.T) {
+	listing := result{
+		value: testOutput{Owner: "alice", Eggs: nonNil([]string(nil))},
+		table: func(w io.Writer) { fmt.Fprintln(w, "🥚 No eggs") },
+		raw:   lines(),
+	}
+	secret := result{
+		value: testOutput{Owner: "alice", Eggs: []string{"DB_URL"}},
+		table: func(w io.Writer) { fmt.Fprintln(w, "DB_URL = postgres://db") },
+		raw:   func(w io.Writer) { io.WriteString(w, "postgres://db") },
+	}
+
+	tests := []struct {
+		name   string
+		r      result
+		format string
+		want   string
+	}{
+		{"table", secret, outputTable, "DB_URL = postgres://db\n"},
+		{"json", secret, outputJSON, "{\n  \"owner\": \"alice\",\n  \"eggs\": [\n    \"DB_URL\"\n  ]\n}\n"},
+		{"json empty list", listing, outputJSON, "{\n  \"owner\": \"alice\",\n  \"eggs\": []\n}\n"},
+		{"yaml", secret, outputYAML, "owner: alice\neggs:\n  - DB_URL\n"},
+		{"yaml empty list", listing, outputYAML, "owner: alice\neggs: []\n"},
+		{"raw without trailing newline", secret, outputRaw, "postgres://db"},
+		{"raw lines", result{raw: lines("A", "B")}, outputRaw, "A\nB\n"},
+		{"raw nothing", listing, outputRaw, ""},
+		{"raw unsupported", result{value: testOutput{}}, outputRaw, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var b bytes.Buffer
+			if err := tt.r.write(&b, tt.format); err != nil {
+				t.Fatalf("write: %v", err)
+			}
+			if b.String() != tt.want {
+				t.Errorf("got %q, want %q", b.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestNonNil(t *testing.T) {
+	var b bytes.Buffer
+	if err := (result{value: nonNil([]int(nil))}).write(&b, outputJSON); err != nil || b.String() != "[]\n" {
+		t.Errorf("got %q, %v, want []", b.String(), err)
+	}
+	if got := nonNil([]int{1}); len(got) != 1 {
+		t.Errorf("nonNil changed a non-empty slice: %v", got)
+	}
+}
+
+// newTestRoot returns a root command with the global flags and sub added
+func newTestRoot(sub *cobra.Command) *cobra.Command {
+	root := &cobra.Command{Use: "egg", SilenceErrors: true, SilenceUsage: true}
+	AddGlobalFlags(root)
+	root.SetOut(io.Discard)
+	root.SetErr(io.Discard)
+	root.AddCommand(sub)
+	return root
+}
+
+func TestOutputFormat(t *testing.T) {
+	tests := []struct {
+		args    []string
+		want    string
+		wantErr bool
+	}{
+		{nil, outputTable, false},
+		{[]string{"-o", "json"}, outputJSON, false},
+		{[]string{"--output", "yaml"}, outputYAML, false},
+		{[]string{"--output=raw"}, outputRaw, false},
+		{[]string{"-o", "xml"}, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(strings.Join(tt.args, " "), func(t *testing.T) {
+			var got string
+			sub := &cobra.Command{Use: "show", RunE: func(cmd *cobra.Command, args []string) error {
+				var err error
+				got, err = outputFormat(cmd)
+				return err
+			}}
+			root := newTestRoot(sub)
+			root.SetArgs(append([]string{"show"}, tt.args...))
+			err := root.Execute()
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Errorf("got %q, %v, want %q (error %v)", got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestExportRefusesOutput(t *testing.T) {
+	root := newTestRoot(ExportCmd)
+	t.Cleanup(func() { root.RemoveCommand(ExportCmd) })
+	tests := []struct {
+		output string
+		want   string
+	}{
+		{"json", "egg export doesn't take --output; use --format json"},
+		{"yaml", "egg export doesn't take --output; use --format yaml"},
+		{"raw", "egg export doesn't take --output; use --format"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.output, func(t *testing.T) {
+			root.SetArgs([]string{"export", "-o", tt.output})
+			if err := root.Execute(); err == nil || err.Error() != tt.want {
+				t.Errorf("got %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,50 @@
+package commands
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// errNotConfirmed is returned when a destructive command is declined at its
+// prompt, so scripts see it didn't happen.
+var errNotConfirmed = errors.New("cancelled: not confirmed")
+
+// errNoTerminal refuses a destructive command that can't be confirmed.
+var errNoTerminal = errors.New("standard input isn't a terminal, so there's nothing to confirm on: use --yes")
+
+// canConfirm returns errNoTerminal unless yes is set or there is a terminal
+// on stdin to ask on. Commands check it before doing any work.
+func canConfirm(yes bool) error {
+	if yes || term.IsTerminal(int(os.Stdin.Fd())) {
+		return nil
+	}
+	return errNoTerminal
+}
+
+// confirm asks a yes/no question on stdin, prompting on stderr, unless yes is
+// set. Anything but y or yes, including no input at all, is errNotConfirmed.
+func confirm(question string, yes bool) error {
+	if yes {
+		return nil
+	}
+	if err := canConfirm(yes); err != nil {
+		return err
+	}
+	return ask(os.Stdin, question)
+}
+
+func ask(in io.Reader, question string) error {
+	fmt.Fprintf(os.Stderr, "%s [y/N] ", question)
+	answer, _ := bufio.NewReader(in).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	if answer != "y" && answer != "yes" {
+		return errNotConfirmed
+	}
+	return nil
+}
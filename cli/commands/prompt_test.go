@@ -0,0 +1,38 @@
+package commands
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestAsk(t *testing.T) {
+	tests := []struct {
+		answer string
+		want   error
+	}{
+		{"y\n", nil},
+		{"YES\n", nil},
+		{"n\n", errNotConfirmed},
+		{"\n", errNotConfirmed},
+		{"", errNotConfirmed}, // stdin closed without an answer
+	}
+	for _, tt := range tests {
+		if err := ask(strings.NewReader(tt.answer), "Break?"); !errors.Is(err, tt.want) {
+			t.Errorf("ask(%q) = %v, want %v", tt.answer, err, tt.want)
+		}
+	}
+}
+
+func TestConfirmWithoutTerminal(t *testing.T) {
+	// go test runs with stdin away from any terminal
+	if err := confirm("Break?", false); !errors.Is(err, errNoTerminal) {
+		t.Errorf("confirm without a terminal = %v, want errNoTerminal", err)
+	}
+	if err := confirm("Break?", true); err != nil {
+		t.Errorf("confirm with --yes = %v, want nil", err)
+	}
+	if ExitCode(errNoTerminal) == 0 || ExitCode(errNotConfirmed) == 0 {
+		t.Error("refusing to break must exit non-zero")
+	}
+}
@@ -0,0 +1,60 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/spf13/cobra"
+)
+
+// RollbackCmd represents the rollback command
+var RollbackCmd = &cobra.Command{
+	Use:   "rollback [key] --version N",
+	Short: "Restore an older version of a secret",
+	Long: `Make an older version of a secret current again.
+
+The restored value is stored as a new version, so the value being replaced
+stays in the history and can itself be rolled back to.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runRollback,
+}
+
+func init() {
+	RollbackCmd.Flags().Int("version", 0, "version to restore (see 'egg history')")
+	RollbackCmd.MarkFlagRequired("version")
+	addNamespaceFlags(RollbackCmd)
+}
+
+func runRollback(cmd *cobra.Command, args []string) error {
+	key := args[0]
+	version, _ := cmd.Flags().GetInt("version")
+	if version < 1 {
+		return fmt.Errorf("--version must be a positive version number")
+	}
+
+	client, owner, err := newAuthenticatedClient()
+	if err != nil {
+		return err
+	}
+
+	vault, err := scopeFromFlags(cmd, owner)
+	if err != nil {
+		return err
+	}
+
+	status("⏪ Rolling back %s to version %d\n", vault.describe(key), version)
+
+	restored, err := client.RestoreEggVersion(vault.owner, vault.namespace, key, version)
+	if err != nil {
+		return fmt.Errorf("failed to roll back egg: %w", err)
+	}
+
+	return render(cmd, result{
+		value: restored,
+		table: func(w io.Writer) {
+			fmt.Fprintf(w, "✅ Restored version %d of %s as version %d\n", restored.RestoredVersion, key, restored.Version)
+		},
+		raw: lines(strconv.Itoa(restored.Version)),
+	})
+}
@@ -6,8 +6,6 @@ import (
 	"os/exec"
 	"strings"
 
-	"github.com/owenHochwald/egg-carton/cli/api"
-	"github.com/owenHochwald/egg-carton/cli/auth"
 	"github.com/owenHochwald/egg-carton/cli/config"
 	"github.com/spf13/cobra"
 )
@@ -18,77 +16,73 @@ var RunCmd = &cobra.Command{
 	Aliases: []string{"run"},
 	Short:   "Inject secrets and run a command (hatch your eggs)",
 	Long: `Fetch all secrets, set them as environment variables, and execute a command.
-	
+
+Use --project and --env (before "--") to hatch one namespace, and --team to
+hatch from a team vault; they default to the nearest ` + config.ProjectConfigFile + ` file.
+
 Example:
   egg hatch -- go run main.go
-  egg hatch -- npm start
-  egg hatch -- ./my-script.sh`,
+  egg hatch --env staging -- npm start
+  egg hatch --project api --env prod -- ./my-script.sh`,
 	RunE: runRun,
 	// DisableFlagParsing allows passing flags to the subprocess
 	DisableFlagParsing: true,
 }
 
 func runRun(cmd *cobra.Command, args []string) error {
-	// 1. Load config
-	cfg, err := config.LoadConfig()
-	if err != nil {
-		return fmt.Errorf("failed to load config: %w", err)
+	// Find the "--" separator in args
+	dashIndex := -1
+	for i, arg := range args {
+		if arg == "--" {
+			dashIndex = i
+			break
+		}
 	}
 
-	// 2. Load tokens (check if logged in)
-	tokens, err := cfg.LoadTokens()
+	if dashIndex == -1 || dashIndex == len(args)-1 {
+		return fmt.Errorf("usage: egg hatch [--project P] [--env E] [--team T] [--profile NAME] -- <command> [args...]")
+	}
+
+	// Flag parsing is disabled for the subprocess, so pick out our own flags
+	// from the arguments before "--"
+	flags, err := parseScopeArgs(args[:dashIndex])
 	if err != nil {
-		return fmt.Errorf("you are not logged in. Please run 'egg login' first: %w", err)
+		return err
+	}
+	if flags["profile"] != "" {
+		profile = flags["profile"]
 	}
 
-	// 3. Check if token is valid (refresh if needed)
-	if !tokens.IsTokenValid() {
-		fmt.Println("⏰ Token expired, refreshing...")
-		newTokens, err := auth.RefreshAccessToken(cfg.GetTokenURL(), cfg.CognitoConfig.ClientID, tokens.RefreshToken)
-		if err != nil {
-			return fmt.Errorf("failed to refresh token: %w", err)
-		}
-		if err := cfg.SaveTokens(newTokens); err != nil {
-			return fmt.Errorf("failed to save refreshed tokens: %w", err)
-		}
-		tokens = newTokens
+	client, owner, err := newAuthenticatedClient()
+	if err != nil {
+		return err
 	}
 
-	// 4. Extract owner from token
-	owner, err := cfg.GetOwner()
+	vault, err := resolveScope(flags["project"], flags["env"], flags["team"], owner)
 	if err != nil {
-		return fmt.Errorf("failed to extract owner from token: %w", err)
+		return err
 	}
+	namespace := vault.namespace
 
-	// 5. Create API client and fetch ALL secrets
-	client := api.NewClient(cfg.GetAPIBaseURL(), tokens.AccessToken)
-	eggs, err := client.GetEgg(owner)
+	// Fetch every secret in the namespace
+	eggs, err := client.GetEgg(vault.owner, namespace)
 	if err != nil {
 		return fmt.Errorf("failed to get eggs: %w", err)
 	}
 
-	// 6. Parse secrets into environment variables
+	// Parse secrets into environment variables
 	secretEnvVars := make(map[string]string)
 	for _, egg := range eggs {
+		// Without a namespace only hatch secrets that don't belong to one
+		if namespace.IsZero() && (egg.Project != "" || egg.Env != "") {
+			continue
+		}
 		// Convert secret_id to uppercase env var format (e.g., api_key -> API_KEY)
 		envVarName := strings.ToUpper(egg.SecretID)
 		secretEnvVars[envVarName] = egg.Plaintext
 	}
 
-	// 7. Find the "--" separator in args
-	dashIndex := -1
-	for i, arg := range args {
-		if arg == "--" {
-			dashIndex = i
-			break
-		}
-	}
-
-	if dashIndex == -1 || dashIndex == len(args)-1 {
-		return fmt.Errorf("usage: egg hatch -- <command> [args...]")
-	}
-
-	// 8. Extract command and arguments after "--"
+	// Extract command and arguments after "--"
 	commandArgs := args[dashIndex+1:]
 	if len(commandArgs) == 0 {
 		return fmt.Errorf("no command specified after '--'")
@@ -97,32 +91,37 @@ func runRun(cmd *cobra.Command, args []string) error {
 	commandName := commandArgs[0]
 	commandArguments := commandArgs[1:]
 
-	// 9. Get current environment variables
+	// Get current environment variables
 	currentEnv := os.Environ()
 
-	// 10. Merge secrets into environment
+	// Merge secrets into environment
 	mergedEnv := append([]string{}, currentEnv...)
 	for key, value := range secretEnvVars {
 		mergedEnv = append(mergedEnv, fmt.Sprintf("%s=%s", key, value))
 	}
 
-	fmt.Printf("� Hatching %d egg(s) into your environment...\n", len(secretEnvVars))
+	// Keep stdout for the command's own output
+	if label := vault.label(); label == "" {
+		status("🐣 Hatching %d egg(s) into your environment...\n", len(secretEnvVars))
+	} else {
+		status("🐣 Hatching %d egg(s) from %s into your environment...\n", len(secretEnvVars), label)
+	}
 	for key := range secretEnvVars {
-		fmt.Printf("   ✓ %s\n", key)
+		status("   ✓ %s\n", key)
 	}
-	fmt.Println()
+	status("\n")
 
-	// 11. Create exec.Command with custom environment
+	// Create exec.Command with custom environment
 	command := exec.Command(commandName, commandArguments...)
 	command.Env = mergedEnv
 	command.Stdin = os.Stdin
 	command.Stdout = os.Stdout
 	command.Stderr = os.Stderr
 
-	// 12. Run command and wait
+	// Run command and wait
 	if err := command.Run(); err != nil {
 		if exitErr, ok := err.(*exec.ExitError); ok {
-			// 13. Exit with same code as subprocess
+			// Exit with same code as subprocess
 			os.Exit(exitErr.ExitCode())
 		}
 		return fmt.Errorf("failed to run command: %w", err)
@@ -130,3 +129,25 @@ func runRun(cmd *cobra.Command, args []string) error {
 
 	return nil
 }
+
+// parseScopeArgs reads --project, --env, --team and --profile (as
+// "--flag value" or "--flag=value") from args, keyed by flag name.
+func parseScopeArgs(args []string) (map[string]string, error) {
+	flags := make(map[string]string)
+	for i := 0; i < len(args); i++ {
+		name, value, hasValue := strings.Cut(args[i], "=")
+		flag, ok := strings.CutPrefix(name, "--")
+		if !ok || (flag != "project" && flag != "env" && flag != "team" && flag != "profile") {
+			return nil, fmt.Errorf("unknown flag %q before '--'", args[i])
+		}
+		if !hasValue {
+			if i+1 >= len(args) {
+				return nil, fmt.Errorf("flag %s needs a value", name)
+			}
+			i++
+			value = args[i]
+		}
+		flags[flag] = value
+	}
+	return flags, nil
+}
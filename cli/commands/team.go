@@ -0,0 +1,207 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/owenHochwald/egg-carton/cli/api"
+	"github.com/spf13/cobra"
+)
+
+// TeamCmd groups the team vault management commands
+var TeamCmd = &cobra.Command{
+	Use:   "team",
+	Short: "Manage shared team vaults",
+	Long: `Create teams and manage who can use their shared vault.
+
+Members have one of three roles:
+  reader  read and hatch the team's secrets
+  writer  also lay, break and roll back secrets
+  admin   also add and remove members
+
+Use --team NAME with any secret command to work in a team vault.`,
+}
+
+var teamCreateCmd = &cobra.Command{
+	Use:   "create [team]",
+	Short: "Create a team with you as its admin",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runTeamCreate,
+}
+
+var teamListCmd = &cobra.Command{
+	Use:     "list",
+	Aliases: []string{"ls"},
+	Short:   "List the teams you belong to",
+	Args:    cobra.NoArgs,
+	RunE:    runTeamList,
+}
+
+var teamMembersCmd = &cobra.Command{
+	Use:   "members [team]",
+	Short: "List the members of a team",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runTeamMembers,
+}
+
+var teamAddCmd = &cobra.Command{
+	Use:   "add [team] [user-id]",
+	Short: "Add a member to a team, or change their role",
+	Long: `Add a member to a team, or change the role of an existing member.
+
+Members are identified by user ID; 'egg team list' shows yours.`,
+	Args: cobra.ExactArgs(2),
+	RunE: runTeamAdd,
+}
+
+var teamRemoveCmd = &cobra.Command{
+	Use:   "remove [team] [user-id]",
+	Short: "Remove a member from a team",
+	Args:  cobra.ExactArgs(2),
+	RunE:  runTeamRemove,
+}
+
+func init() {
+	teamAddCmd.Flags().String("role", "reader", "role to grant: reader, writer or admin")
+	TeamCmd.AddCommand(teamCreateCmd, teamListCmd, teamMembersCmd, teamAddCmd, teamRemoveCmd)
+}
+
+// teamsOutput is the result of egg team list
+type teamsOutput struct {
+	UserID string     `json:"user_id"`
+	Teams  []api.Team `json:"teams"`
+}
+
+// teamRemoval is the result of egg team remove
+type teamRemoval struct {
+	Team   string `json:"team"`
+	Member string `json:"member"`
+}
+
+func runTeamCreate(cmd *cobra.Command, args []string) error {
+	client, _, err := newAuthenticatedClient()
+	if err != nil {
+		return err
+	}
+
+	team, err := client.CreateTeam(args[0])
+	if err != nil {
+		return err
+	}
+
+	return render(cmd, result{
+		value: team,
+		table: func(w io.Writer) {
+			fmt.Fprintf(w, "✅ Created team %s; you are its admin\n", team.Team)
+			fmt.Fprintf(w, "Use --team %s to lay and hatch its secrets.\n", team.Team)
+		},
+		raw: lines(team.Team),
+	})
+}
+
+func runTeamList(cmd *cobra.Command, args []string) error {
+	client, owner, err := newAuthenticatedClient()
+	if err != nil {
+		return err
+	}
+
+	teams, err := client.ListTeams()
+	if err != nil {
+		return err
+	}
+
+	names := make([]string, len(teams))
+	for i, team := range teams {
+		names[i] = team.Team
+	}
+	return render(cmd, result{
+		value: teamsOutput{UserID: owner, Teams: nonNil(teams)},
+		table: func(w io.Writer) {
+			fmt.Fprintf(w, "Your user ID: %s\n\n", owner)
+			if len(teams) == 0 {
+				fmt.Fprintln(w, "You don't belong to any teams.")
+				return
+			}
+			for _, team := range teams {
+				fmt.Fprintf(w, "Team: %s\n", team.Team)
+				fmt.Fprintf(w, "Role: %s\n", team.Role)
+				fmt.Fprintln(w, "---")
+			}
+		},
+		raw: lines(names...),
+	})
+}
+
+func runTeamMembers(cmd *cobra.Command, args []string) error {
+	client, _, err := newAuthenticatedClient()
+	if err != nil {
+		return err
+	}
+
+	members, err := client.ListTeamMembers(args[0])
+	if err != nil {
+		return err
+	}
+
+	ids := make([]string, len(members))
+	for i, m := range members {
+		ids[i] = m.Member
+	}
+	return render(cmd, result{
+		value: api.ListTeamMembersResponse{Members: nonNil(members)},
+		table: func(w io.Writer) {
+			fmt.Fprintf(w, "👥 %d member(s) of %s:\n\n", len(members), args[0])
+			for _, m := range members {
+				fmt.Fprintf(w, "User: %s\n", m.Member)
+				fmt.Fprintf(w, "Role: %s\n", m.Role)
+				fmt.Fprintf(w, "Added: %s\n", m.AddedAt)
+				fmt.Fprintln(w, "---")
+			}
+		},
+		raw: lines(ids...),
+	})
+}
+
+func runTeamAdd(cmd *cobra.Command, args []string) error {
+	team, member := args[0], args[1]
+	role, _ := cmd.Flags().GetString("role")
+	if role != "reader" && role != "writer" && role != "admin" {
+		return fmt.Errorf("--role must be reader, writer or admin")
+	}
+
+	client, _, err := newAuthenticatedClient()
+	if err != nil {
+		return err
+	}
+
+	if err := client.SetTeamMember(team, member, role); err != nil {
+		return err
+	}
+
+	return render(cmd, result{
+		value: api.TeamMember{Team: team, Member: member, Role: role},
+		table: func(w io.Writer) {
+			fmt.Fprintf(w, "✅ %s is now a %s of %s\n", member, role, team)
+		},
+	})
+}
+
+func runTeamRemove(cmd *cobra.Command, args []string) error {
+	team, member := args[0], args[1]
+
+	client, _, err := newAuthenticatedClient()
+	if err != nil {
+		return err
+	}
+
+	if err := client.RemoveTeamMember(team, member); err != nil {
+		return err
+	}
+
+	return render(cmd, result{
+		value: teamRemoval{Team: team, Member: member},
+		table: func(w io.Writer) {
+			fmt.Fprintf(w, "✅ Removed %s from %s\n", member, team)
+		},
+	})
+}
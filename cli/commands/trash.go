@@ -0,0 +1,124 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/owenHochwald/egg-carton/cli/api"
+	"github.com/spf13/cobra"
+)
+
+// TrashCmd groups the commands for broken secrets awaiting purge
+var TrashCmd = &cobra.Command{
+	Use:   "trash",
+	Short: "Manage secrets in the trash",
+	Long: `Secrets removed with 'egg break' go to the trash, where they can be
+restored until they are purged.`,
+}
+
+var trashListCmd = &cobra.Command{
+	Use:     "list",
+	Aliases: []string{"ls"},
+	Short:   "List the secrets in the trash",
+	Args:    cobra.NoArgs,
+	RunE:    runTrashList,
+}
+
+// RestoreCmd represents the restore command
+var RestoreCmd = &cobra.Command{
+	Use:   "restore [key]",
+	Short: "Restore a secret from the trash",
+	Long:  `Bring a broken secret back out of the trash, with its version history.`,
+	Args:  cobra.ExactArgs(1),
+	RunE:  runRestore,
+}
+
+func init() {
+	addNamespaceFlags(trashListCmd)
+	addNamespaceFlags(RestoreCmd)
+	TrashCmd.AddCommand(trashListCmd)
+}
+
+func runTrashList(cmd *cobra.Command, args []string) error {
+	client, owner, err := newAuthenticatedClient()
+	if err != nil {
+		return err
+	}
+
+	vault, err := scopeFromFlags(cmd, owner)
+	if err != nil {
+		return err
+	}
+
+	eggs, err := client.ListTrash(vault.owner, vault.namespace)
+	if err != nil {
+		return fmt.Errorf("failed to list trash: %w", err)
+	}
+
+	keys := make([]string, len(eggs))
+	for i, egg := range eggs {
+		keys[i] = egg.SecretID
+	}
+	return render(cmd, result{
+		value: api.ListTrashResponse{Eggs: nonNil(eggs)},
+		table: func(w io.Writer) {
+			if len(eggs) == 0 {
+				fmt.Fprintln(w, "The trash is empty.")
+				return
+			}
+			fmt.Fprintf(w, "🗑️  %d secret(s) in the trash:\n\n", len(eggs))
+			for _, egg := range eggs {
+				fmt.Fprintf(w, "Key: %s\n", egg.SecretID)
+				if egg.Project != "" || egg.Env != "" {
+					fmt.Fprintf(w, "Project: %s\n", egg.Project)
+					fmt.Fprintf(w, "Env: %s\n", egg.Env)
+				}
+				fmt.Fprintf(w, "Version: %d\n", egg.Version)
+				fmt.Fprintf(w, "Deleted: %s by %s\n", egg.DeletedAt, egg.DeletedBy)
+				purge, _ := describeExpiry(egg.PurgeAt)
+				fmt.Fprintf(w, "Purged: %s\n", purge)
+				fmt.Fprintln(w, "---")
+			}
+		},
+		raw: lines(keys...),
+	})
+}
+
+// restoredEgg is the result of egg restore
+type restoredEgg struct {
+	Owner    string `json:"owner"`
+	SecretID string `json:"secret_id"`
+	Project  string `json:"project,omitempty"`
+	Env      string `json:"env,omitempty"`
+}
+
+func runRestore(cmd *cobra.Command, args []string) error {
+	key := args[0]
+
+	client, owner, err := newAuthenticatedClient()
+	if err != nil {
+		return err
+	}
+
+	vault, err := scopeFromFlags(cmd, owner)
+	if err != nil {
+		return err
+	}
+
+	err = client.RestoreTrashedEgg(vault.owner, vault.namespace, key)
+	if errors.Is(err, api.ErrNotFound) {
+		return reword(err, "secret '%s' is not in the trash", vault.describe(key))
+	}
+	if err != nil {
+		return fmt.Errorf("failed to restore egg: %w", err)
+	}
+
+	return render(cmd, result{
+		value: restoredEgg{Owner: vault.owner, SecretID: key, Project: vault.namespace.Project, Env: vault.namespace.Env},
+		table: func(w io.Writer) {
+			fmt.Fprintf(w, "✅ Restored %s from the trash\n", vault.describe(key))
+		},
+		raw: lines(key),
+	})
+}
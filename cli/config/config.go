@@ -3,6 +3,7 @@ package config
 import (
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -10,19 +11,28 @@ import (
 	"time"
 )
 
-// Config holds the CLI configuration
+// Config holds the CLI configuration of the selected profile, with any
+// environment variable overrides applied
 type Config struct {
+	Profile       string        `json:"profile"`
 	APIEndpoint   string        `json:"api_endpoint"`
 	CognitoConfig CognitoConfig `json:"cognito"`
-	TokenPath     string        `json:"-"` // Not serialized
+	Project       string        `json:"project,omitempty"` // Default namespace, after any project config file
+	Env           string        `json:"env,omitempty"`
+	TokenStore    TokenStore    `json:"-"` // Where the profile's tokens are kept
+	TokenPath     string        `json:"-"` // Plaintext credentials file, migrated to TokenStore by MigrateTokens
+
+	// tokens caches what was last loaded or saved, since every load may run
+	// Argon2id or a keyring helper
+	tokens *TokenData
 }
 
 // CognitoConfig holds Cognito-specific configuration
 type CognitoConfig struct {
-	UserPoolID string `json:"user_pool_id"`
-	ClientID   string `json:"client_id"`
-	Domain     string `json:"domain"`
-	Region     string `json:"region"`
+	UserPoolID string `json:"user_pool_id" yaml:"user_pool_id,omitempty"`
+	ClientID   string `json:"client_id" yaml:"client_id,omitempty"`
+	Domain     string `json:"domain" yaml:"domain,omitempty"`
+	Region     string `json:"region" yaml:"region,omitempty"`
 }
 
 // TokenData holds the OAuth tokens
@@ -35,68 +45,90 @@ type TokenData struct {
 	IssuedAt     int64  `json:"issued_at"` // Unix timestamp when token was received
 }
 
-// TODO: Implement LoadConfig
-// Should load from environment variables or a default config
-// For now, you can hardcode the values from terraform output
-func LoadConfig() (*Config, error) {
-	// TODO: Get these from environment variables or config file
-	// For now, hardcode from your terraform output:
-	config := &Config{
-		APIEndpoint: "https://z7ha1j4xr9.execute-api.us-west-1.amazonaws.com/dev",
-		CognitoConfig: CognitoConfig{
-			UserPoolID: "us-west-1_2fzGdmIah",
-			ClientID:   "1vccvf2hh5amna78lurbn9bjhi",
-			Domain:     "eggcarton-auth-uqhqvdut.auth.us-west-1.amazoncognito.com",
-			Region:     "us-west-1",
-		},
-	}
-
-	// Set token path
-	home, err := os.UserHomeDir()
+// LoadConfig loads the settings of a profile from ProfilesFile: the one
+// named, or if that's empty, EGG_PROFILE or the file's current profile.
+// Each setting can be overridden by an EGG_* environment variable, e.g.
+// EGG_API_ENDPOINT to use the local server in cmd/server, and anything
+// still unset comes from the built-in deployment.
+func LoadConfig(profile string) (*Config, error) {
+	profiles, err := LoadProfiles()
 	if err != nil {
-		return nil, fmt.Errorf("failed to get home directory: %w", err)
+		return nil, err
+	}
+	if profile, err = profiles.Selected(profile); err != nil {
+		return nil, err
+	}
+
+	config := &Config{Profile: profile}
+	resolved := Profile{}
+	for _, s := range settings {
+		value, _, _ := profiles.Resolve(profile, s.key)
+		*s.field(&resolved) = value
+	}
+	config.APIEndpoint = strings.TrimSuffix(resolved.APIEndpoint, "/")
+	config.CognitoConfig = resolved.Cognito
+	config.Project = resolved.Project
+	config.Env = resolved.Env
+
+	// Each profile has its own tokens
+	dir := filepath.Dir(profiles.Path)
+	config.TokenPath = filepath.Join(dir, credentialsFile(profile))
+	if config.TokenStore, err = NewTokenStore(resolved.TokenStore, dir); err != nil {
+		return nil, err
 	}
-	config.TokenPath = filepath.Join(home, ".eggcarton", "credentials.json")
 
 	return config, nil
 }
 
-// Should save tokens to ~/.eggcarton/credentials.json with 0600 permissions
+// SaveTokens stores the profile's tokens in its token store
 func (c *Config) SaveTokens(tokens *TokenData) error {
-	// Create directory if it doesn't exist
-	dir := filepath.Dir(c.TokenPath)
-	if err := os.MkdirAll(dir, 0700); err != nil {
-		return fmt.Errorf("failed to create directory: %w", err)
-	}
-
-	// Marshal tokens to JSON
-	b, err := json.MarshalIndent(tokens, "", "  ")
-	if err != nil {
-		return fmt.Errorf("failed to marshal tokens: %w", err)
-	}
-
-	// Write to file with secure permissions
-	if err := os.WriteFile(c.TokenPath, b, 0600); err != nil {
-		return fmt.Errorf("failed to write tokens to file: %w", err)
+	if err := c.TokenStore.Save(c.Profile, tokens); err != nil {
+		return err
 	}
-
+	c.tokens = tokens
 	return nil
 }
 
-// Should load tokens from ~/.eggcarton/credentials.json
+// LoadTokens reads the profile's tokens from its token store, returning
+// ErrNoTokens if it isn't logged in. The store is only read once.
 func (c *Config) LoadTokens() (*TokenData, error) {
-	data, err := os.ReadFile(c.TokenPath)
+	if c.tokens != nil {
+		return c.tokens, nil
+	}
+	tokens, err := c.TokenStore.Load(c.Profile)
 	if err != nil {
 		return nil, err
 	}
+	c.tokens = tokens
+	return tokens, nil
+}
 
-	var tokens TokenData
-	err = json.Unmarshal(data, &tokens)
-	if err != nil {
-		return nil, fmt.Errorf("failed to unmarshal tokens: %w", err)
+// MigrateTokens moves tokens saved in plaintext at TokenPath, as the CLI
+// used to, into the token store, unless the store already has some. It
+// reports whether it moved any.
+func (c *Config) MigrateTokens() (bool, error) {
+	if _, ok := c.TokenStore.(*fileStore); ok {
+		return false, nil
+	}
+	if _, err := os.Stat(c.TokenPath); err != nil {
+		return false, nil
 	}
 
-	return &tokens, nil
+	if _, err := c.LoadTokens(); !errors.Is(err, ErrNoTokens) {
+		// Already stored, or unreadable: either way, don't overwrite them
+		if err != nil {
+			return false, err
+		}
+		return false, removePlaintext(c.TokenPath)
+	}
+	tokens, err := readPlaintextTokens(c.TokenPath)
+	if err != nil {
+		return false, err
+	}
+	if err := c.SaveTokens(tokens); err != nil {
+		return false, err
+	}
+	return true, removePlaintext(c.TokenPath)
 }
 
 // Should check if access token is still valid (not expired)
@@ -135,9 +167,14 @@ func (c *Config) GetOwner() (string, error) {
 	if err != nil {
 		return "", fmt.Errorf("failed to load tokens: %w", err)
 	}
+	return tokens.Owner()
+}
 
+// Owner extracts the owner (user ID) from the access token, for callers
+// that already hold the tokens
+func (t *TokenData) Owner() (string, error) {
 	// Extract owner (sub claim) from the access token JWT
-	return extractOwnerFromToken(tokens.AccessToken)
+	return extractOwnerFromToken(t.AccessToken)
 }
 
 // extractOwnerFromToken decodes JWT and extracts the 'sub' claim (user ID)
@@ -167,3 +204,28 @@ func extractOwnerFromToken(accessToken string) (string, error) {
 
 	return claims.Sub, nil
 }
+
+// TokensFromAccessToken wraps a bearer token obtained outside the OAuth flow,
+// such as one minted by the local server, so it can be saved like a login.
+// It stays valid until its exp claim and can't be refreshed.
+func TokensFromAccessToken(accessToken string) (*TokenData, error) {
+	if _, err := extractOwnerFromToken(accessToken); err != nil {
+		return nil, err
+	}
+
+	payload, _ := base64.RawURLEncoding.DecodeString(strings.Split(accessToken, ".")[1])
+	var claims struct {
+		Exp int64 `json:"exp"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil || claims.Exp == 0 {
+		return nil, fmt.Errorf("exp claim not found in token")
+	}
+
+	now := time.Now().Unix()
+	return &TokenData{
+		AccessToken: accessToken,
+		ExpiresIn:   int(claims.Exp - now),
+		TokenType:   "Bearer",
+		IssuedAt:    now,
+	}, nil
+}
@@ -0,0 +1,240 @@
+package config
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/term"
+)
+
+// PassphraseEnv holds the encrypted token file's passphrase, for scripts
+// that can't answer a prompt
+const PassphraseEnv = "EGG_TOKEN_PASSPHRASE"
+
+const minPassphraseLength = 8
+
+// Argon2id parameters for new files; each file records its own
+var defaultKDFParams = kdfParams{Time: 3, Memory: 64 * 1024, Threads: 4}
+
+// Limits on the parameters read from a file, which are used before the
+// passphrase can be checked, so a damaged or edited file can't make egg
+// spend minutes or gigabytes deriving a key
+const (
+	maxKDFTime   = 16
+	maxKDFMemory = 256 * 1024 // KiB
+)
+
+const (
+	saltSize  = 16
+	nonceSize = 12 // AES-GCM's standard nonce, as newAEAD uses
+)
+
+type kdfParams struct {
+	Time    uint32 `json:"time"`
+	Memory  uint32 `json:"memory"` // KiB
+	Threads uint8  `json:"threads"`
+}
+
+// encryptedTokens is the contents of an encrypted token file. The tokens
+// are sealed with AES-256-GCM under a key derived from the passphrase with
+// Argon2id; the header is authenticated along with them.
+type encryptedTokens struct {
+	Version    int       `json:"version"`
+	KDF        string    `json:"kdf"`
+	Params     kdfParams `json:"params"`
+	Salt       []byte    `json:"salt"`
+	Nonce      []byte    `json:"nonce"`
+	Ciphertext []byte    `json:"ciphertext"`
+}
+
+// additionalData is the header, bound to the ciphertext so it can't be
+// swapped for weaker parameters
+func (e *encryptedTokens) additionalData(profile string) []byte {
+	return fmt.Appendf(nil, "eggcarton-tokens/v%d/%s/%d/%d/%d/%s", e.Version, e.KDF, e.Params.Time, e.Params.Memory, e.Params.Threads, profile)
+}
+
+// valid reports whether argon2 can use the parameters, within our limits
+func (p kdfParams) valid() bool {
+	return p.Time >= 1 && p.Time <= maxKDFTime &&
+		p.Memory >= 8*uint32(p.Threads) && p.Memory <= maxKDFMemory &&
+		p.Threads >= 1
+}
+
+func (p kdfParams) key(passphrase string, salt []byte) []byte {
+	return argon2.IDKey([]byte(passphrase), salt, p.Time, p.Memory, p.Threads, 32)
+}
+
+// encryptedFileStore keeps each profile's tokens in credentials.enc (or
+// credentials-<profile>.enc), encrypted under a passphrase
+type encryptedFileStore struct {
+	dir string
+	// passphrase asks for the passphrase; confirm is set when choosing a
+	// new one
+	passphrase func(confirm bool) (string, error)
+	known      string // The passphrase, once it has opened or sealed a file
+}
+
+func newEncryptedFileStore(dir string) *encryptedFileStore {
+	return &encryptedFileStore{dir: dir, passphrase: promptPassphrase}
+}
+
+func (s *encryptedFileStore) Name() string {
+	return "an encrypted file"
+}
+
+func (s *encryptedFileStore) path(profile string) string {
+	return filepath.Join(s.dir, strings.TrimSuffix(credentialsFile(profile), ".json")+".enc")
+}
+
+func (s *encryptedFileStore) Load(profile string) (*TokenData, error) {
+	path := s.path(profile)
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNoTokens
+	}
+	if err != nil {
+		return nil, err
+	}
+	var sealed encryptedTokens
+	if err := json.Unmarshal(data, &sealed); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	if sealed.Version != 1 || sealed.KDF != "argon2id" {
+		return nil, fmt.Errorf("%s was written by a newer egg (version %d, %s)", path, sealed.Version, sealed.KDF)
+	}
+	if !sealed.Params.valid() || len(sealed.Salt) != saltSize || len(sealed.Nonce) != nonceSize {
+		return nil, fmt.Errorf("%s is damaged: run egg login to replace it", path)
+	}
+
+	passphrase := s.known
+	if passphrase == "" {
+		if passphrase, err = s.passphrase(false); err != nil {
+			return nil, err
+		}
+	}
+	aead, err := newAEAD(sealed.Params.key(passphrase, sealed.Salt))
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := aead.Open(nil, sealed.Nonce, sealed.Ciphertext, sealed.additionalData(profile))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt %s: wrong passphrase, or the file is damaged", path)
+	}
+	s.known = passphrase
+
+	var tokens TokenData
+	if err := json.Unmarshal(plaintext, &tokens); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal tokens: %w", err)
+	}
+	return &tokens, nil
+}
+
+// Save seals the tokens under the passphrase that opened them, or a new
+// one if they weren't loaded first, e.g. on a fresh login
+func (s *encryptedFileStore) Save(profile string, tokens *TokenData) error {
+	plaintext, err := json.Marshal(tokens)
+	if err != nil {
+		return fmt.Errorf("failed to marshal tokens: %w", err)
+	}
+	passphrase := s.known
+	if passphrase == "" {
+		if passphrase, err = s.passphrase(true); err != nil {
+			return err
+		}
+	}
+
+	sealed := encryptedTokens{
+		Version: 1,
+		KDF:     "argon2id",
+		Params:  defaultKDFParams,
+		Salt:    make([]byte, saltSize),
+	}
+	if _, err := rand.Read(sealed.Salt); err != nil {
+		return fmt.Errorf("failed to generate salt: %w", err)
+	}
+	aead, err := newAEAD(sealed.Params.key(passphrase, sealed.Salt))
+	if err != nil {
+		return err
+	}
+	sealed.Nonce = make([]byte, aead.NonceSize())
+	if _, err := rand.Read(sealed.Nonce); err != nil {
+		return fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	sealed.Ciphertext = aead.Seal(nil, sealed.Nonce, plaintext, sealed.additionalData(profile))
+
+	data, err := json.MarshalIndent(sealed, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal tokens: %w", err)
+	}
+	if err := writePrivateFile(s.path(profile), data); err != nil {
+		return err
+	}
+	s.known = passphrase
+	return nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
+
+// promptPassphrase reads the passphrase from PassphraseEnv, or asks for it
+// on the terminal without echoing it. A new passphrase is asked for twice.
+func promptPassphrase(confirm bool) (string, error) {
+	if passphrase := os.Getenv(PassphraseEnv); passphrase != "" {
+		return passphrase, nil
+	}
+
+	// Standard input may be a pipe, as with egg import -, so use the
+	// terminal itself
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		if !term.IsTerminal(int(os.Stdin.Fd())) {
+			return "", fmt.Errorf("no terminal to ask for the token passphrase on; set %s, or token_store to keyring or file", PassphraseEnv)
+		}
+		tty = os.Stdin
+	} else {
+		defer tty.Close()
+	}
+
+	read := func(prompt string) (string, error) {
+		fmt.Fprint(os.Stderr, prompt)
+		b, err := term.ReadPassword(int(tty.Fd()))
+		fmt.Fprintln(os.Stderr)
+		if err != nil {
+			return "", fmt.Errorf("failed to read passphrase: %w", err)
+		}
+		return string(b), nil
+	}
+
+	if !confirm {
+		return read("🔑 Passphrase for your egg tokens: ")
+	}
+	passphrase, err := read("🔑 Choose a passphrase to encrypt your egg tokens with: ")
+	if err != nil {
+		return "", err
+	}
+	if len(passphrase) < minPassphraseLength {
+		return "", fmt.Errorf("the passphrase must be at least %d characters", minPassphraseLength)
+	}
+	again, err := read("🔑 Enter it again: ")
+	if err != nil {
+		return "", err
+	}
+	if again != passphrase {
+		return "", fmt.Errorf("the passphrases don't match")
+	}
+	return passphrase, nil
+}
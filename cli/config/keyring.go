@@ -0,0 +1,118 @@
+package config
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// keyringService names the CLI's entries in the keyring; each profile is an
+// account under it
+const keyringService = "eggcarton"
+
+// keyringStore keeps tokens in the OS keyring through its command line
+// tool: security on macOS, secret-tool for the Secret Service (GNOME
+// Keyring, KWallet) elsewhere. Tokens are passed on stdin, never as
+// arguments other processes could see.
+type keyringStore struct {
+	tool string
+	// run executes the tool with stdin, returning its stdout and exit code
+	run func(stdin string, args ...string) (string, int, error)
+}
+
+// newKeyringStore returns the keyring of this system, if it has one
+func newKeyringStore() (*keyringStore, bool) {
+	tool := "secret-tool"
+	if runtime.GOOS == "darwin" {
+		tool = "security"
+	} else if runtime.GOOS == "windows" || os.Getenv("DBUS_SESSION_BUS_ADDRESS") == "" {
+		return nil, false
+	}
+	path, err := exec.LookPath(tool)
+	if err != nil {
+		return nil, false
+	}
+	return &keyringStore{tool: tool, run: commandRunner(path)}, true
+}
+
+func commandRunner(path string) func(string, ...string) (string, int, error) {
+	return func(stdin string, args ...string) (string, int, error) {
+		var stdout, stderr bytes.Buffer
+		cmd := exec.Command(path, args...)
+		cmd.Stdin = strings.NewReader(stdin)
+		cmd.Stdout = &stdout
+		cmd.Stderr = &stderr
+		err := cmd.Run()
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return stdout.String(), exitErr.ExitCode(), fmt.Errorf("%s failed: %s", path, strings.TrimSpace(stderr.String()))
+		}
+		return stdout.String(), 0, err
+	}
+}
+
+func (s *keyringStore) Name() string {
+	return "the keyring"
+}
+
+func (s *keyringStore) Load(profile string) (*TokenData, error) {
+	var out string
+	var code int
+	var err error
+	if s.tool == "security" {
+		out, code, err = s.run("", "find-generic-password", "-s", keyringService, "-a", profile, "-w")
+		if code == 44 { // errSecItemNotFound
+			return nil, ErrNoTokens
+		}
+	} else {
+		out, code, err = s.run("", "lookup", "service", keyringService, "account", profile)
+		if code == 1 && out == "" {
+			return nil, ErrNoTokens
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tokens from the keyring: %w", err)
+	}
+
+	data := strings.TrimSpace(out)
+	// security prints values it doesn't consider text as hex
+	if decoded, err := hex.DecodeString(data); err == nil {
+		data = string(decoded)
+	}
+	var tokens TokenData
+	if err := json.Unmarshal([]byte(data), &tokens); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal tokens from the keyring: %w", err)
+	}
+	return &tokens, nil
+}
+
+func (s *keyringStore) Save(profile string, tokens *TokenData) error {
+	data, err := json.Marshal(tokens)
+	if err != nil {
+		return fmt.Errorf("failed to marshal tokens: %w", err)
+	}
+
+	if s.tool == "security" {
+		// security -i reads commands from stdin; -X takes the value in hex,
+		// so it needs no quoting, and -U replaces an existing entry
+		command := fmt.Sprintf("add-generic-password -U -s %s -a %s -l %q -X %s\n",
+			keyringService, profile, keyringLabel(profile), hex.EncodeToString(data))
+		_, _, err = s.run(command, "-i")
+	} else {
+		_, _, err = s.run(string(data), "store", "--label", keyringLabel(profile), "service", keyringService, "account", profile)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to save tokens to the keyring: %w", err)
+	}
+	return nil
+}
+
+func keyringLabel(profile string) string {
+	return "EggCarton tokens (" + profile + ")"
+}
@@ -0,0 +1,272 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultProfile is used when no profile is selected. Its credentials live
+// where they always have, in ~/.eggcarton/credentials.json.
+const DefaultProfile = "default"
+
+// ProfilesFile holds the named profiles, under the config directory
+const ProfilesFile = "config.yaml"
+
+// Profile holds the settings of one deployment, e.g.
+//
+//	api_endpoint: https://abc123.execute-api.us-west-1.amazonaws.com/dev
+//	cognito:
+//	  client_id: 1vccvf2hh5amna78lurbn9bjhi
+//	  domain: eggcarton-auth.auth.us-west-1.amazoncognito.com
+//	project: api
+//	env: dev
+//	token_store: keyring
+//
+// Settings left out fall back to the built-in deployment.
+type Profile struct {
+	APIEndpoint string        `yaml:"api_endpoint,omitempty"`
+	Cognito     CognitoConfig `yaml:"cognito,omitempty"`
+	Project     string        `yaml:"project,omitempty"` // Default --project, after any project config file
+	Env         string        `yaml:"env,omitempty"`     // Default --env, after any project config file
+	TokenStore  string        `yaml:"token_store,omitempty"`
+}
+
+// Profiles is the contents of ProfilesFile
+type Profiles struct {
+	CurrentProfile string              `yaml:"current_profile,omitempty"`
+	Profiles       map[string]*Profile `yaml:"profiles,omitempty"`
+	Path           string              `yaml:"-"` // File it was loaded from
+}
+
+// builtinProfile is the deployment the CLI talks to out of the box
+var builtinProfile = Profile{
+	APIEndpoint: "https://z7ha1j4xr9.execute-api.us-west-1.amazonaws.com/dev",
+	Cognito: CognitoConfig{
+		UserPoolID: "us-west-1_2fzGdmIah",
+		ClientID:   "1vccvf2hh5amna78lurbn9bjhi",
+		Domain:     "eggcarton-auth-uqhqvdut.auth.us-west-1.amazoncognito.com",
+		Region:     "us-west-1",
+	},
+	TokenStore: TokenStoreAuto,
+}
+
+// setting is one key of a profile, with the environment variable that
+// overrides it
+type setting struct {
+	key   string
+	env   string
+	field func(*Profile) *string
+}
+
+var settings = []setting{
+	{"api_endpoint", "EGG_API_ENDPOINT", func(p *Profile) *string { return &p.APIEndpoint }},
+	{"cognito.user_pool_id", "EGG_COGNITO_USER_POOL_ID", func(p *Profile) *string { return &p.Cognito.UserPoolID }},
+	{"cognito.client_id", "EGG_COGNITO_CLIENT_ID", func(p *Profile) *string { return &p.Cognito.ClientID }},
+	{"cognito.domain", "EGG_COGNITO_DOMAIN", func(p *Profile) *string { return &p.Cognito.Domain }},
+	{"cognito.region", "EGG_COGNITO_REGION", func(p *Profile) *string { return &p.Cognito.Region }},
+	{"project", "EGG_PROJECT", func(p *Profile) *string { return &p.Project }},
+	{"env", "EGG_ENV", func(p *Profile) *string { return &p.Env }},
+	{"token_store", "EGG_TOKEN_STORE", func(p *Profile) *string { return &p.TokenStore }},
+}
+
+// Settings lists the keys a profile can set
+func Settings() []string {
+	keys := make([]string, len(settings))
+	for i, s := range settings {
+		keys[i] = s.key
+	}
+	return keys
+}
+
+func findSetting(key string) (setting, error) {
+	i := slices.IndexFunc(settings, func(s setting) bool { return s.key == key })
+	if i < 0 {
+		return setting{}, fmt.Errorf("unknown setting %q: use one of %s", key, strings.Join(Settings(), ", "))
+	}
+	return settings[i], nil
+}
+
+var profileNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_-]{0,63}$`)
+
+// Where a resolved setting came from
+const (
+	SourceEnv     = "env"
+	SourceProfile = "profile"
+	SourceBuiltin = "builtin"
+	SourceUnset   = "unset"
+)
+
+// configDir is ~/.eggcarton, holding the profiles and credentials
+func configDir() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(home, ".eggcarton"), nil
+}
+
+// LoadProfiles reads ProfilesFile, returning no profiles if there isn't one
+func LoadProfiles() (*Profiles, error) {
+	dir, err := configDir()
+	if err != nil {
+		return nil, err
+	}
+	profiles := &Profiles{Path: filepath.Join(dir, ProfilesFile)}
+
+	data, err := os.ReadFile(profiles.Path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return profiles, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", profiles.Path, err)
+	}
+	if err := yaml.Unmarshal(data, profiles); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", profiles.Path, err)
+	}
+	return profiles, nil
+}
+
+// Save writes the profiles back, readable only by the user
+func (p *Profiles) Save() error {
+	if err := os.MkdirAll(filepath.Dir(p.Path), 0700); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+	var data bytes.Buffer
+	encoder := yaml.NewEncoder(&data)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(p); err != nil {
+		return fmt.Errorf("failed to marshal profiles: %w", err)
+	}
+	if err := os.WriteFile(p.Path, data.Bytes(), 0600); err != nil {
+		return fmt.Errorf("failed to write %s: %w", p.Path, err)
+	}
+	return nil
+}
+
+// Selected picks the profile to use: name if given, else EGG_PROFILE, else
+// the current profile, else DefaultProfile. It must exist unless it is
+// DefaultProfile.
+func (p *Profiles) Selected(name string) (string, error) {
+	switch {
+	case name != "":
+	case os.Getenv("EGG_PROFILE") != "":
+		name = os.Getenv("EGG_PROFILE")
+	case p.CurrentProfile != "":
+		name = p.CurrentProfile
+	default:
+		name = DefaultProfile
+	}
+	if _, ok := p.Profiles[name]; !ok && name != DefaultProfile {
+		return "", fmt.Errorf("profile %q not found in %s", name, p.Path)
+	}
+	return name, nil
+}
+
+// Names lists the profiles, always including DefaultProfile
+func (p *Profiles) Names() []string {
+	names := []string{DefaultProfile}
+	for name := range p.Profiles {
+		if name != DefaultProfile {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names[1:])
+	return names
+}
+
+// Get returns a setting of a profile as stored, or empty if it isn't set
+func (p *Profiles) Get(profile, key string) (string, error) {
+	s, err := findSetting(key)
+	if err != nil {
+		return "", err
+	}
+	if p.Profiles[profile] == nil {
+		return "", nil
+	}
+	return *s.field(p.Profiles[profile]), nil
+}
+
+// Resolve returns the value a setting takes for a profile, and where it
+// came from: its environment variable, the profile, or the built-in
+// deployment.
+func (p *Profiles) Resolve(profile, key string) (value, source string, err error) {
+	s, err := findSetting(key)
+	if err != nil {
+		return "", "", err
+	}
+	if value := os.Getenv(s.env); value != "" {
+		return value, SourceEnv, nil
+	}
+	if stored := p.Profiles[profile]; stored != nil && *s.field(stored) != "" {
+		return *s.field(stored), SourceProfile, nil
+	}
+	builtin := builtinProfile
+	if value := *s.field(&builtin); value != "" {
+		return value, SourceBuiltin, nil
+	}
+	return "", SourceUnset, nil
+}
+
+// Set stores a setting in a profile, creating the profile if need be. An
+// empty value removes the setting.
+func (p *Profiles) Set(profile, key, value string) error {
+	s, err := findSetting(key)
+	if err != nil {
+		return err
+	}
+	if !profileNamePattern.MatchString(profile) {
+		return fmt.Errorf("invalid profile name %q: use letters, digits, '-' and '_'", profile)
+	}
+	if key == "api_endpoint" && value != "" {
+		if value, err = checkEndpoint(value); err != nil {
+			return err
+		}
+	}
+	if key == "token_store" && value != "" && !slices.Contains(TokenStores, value) {
+		return fmt.Errorf("unknown token store %q: use one of %s", value, strings.Join(TokenStores, ", "))
+	}
+
+	if p.Profiles == nil {
+		p.Profiles = make(map[string]*Profile)
+	}
+	if p.Profiles[profile] == nil {
+		p.Profiles[profile] = &Profile{}
+	}
+	*s.field(p.Profiles[profile]) = value
+	return nil
+}
+
+// Use makes a profile the current one
+func (p *Profiles) Use(profile string) error {
+	if _, ok := p.Profiles[profile]; !ok && profile != DefaultProfile {
+		return fmt.Errorf("profile %q not found; create it with 'egg config set --profile %s api_endpoint URL'", profile, profile)
+	}
+	p.CurrentProfile = profile
+	return nil
+}
+
+func checkEndpoint(endpoint string) (string, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return "", fmt.Errorf("api_endpoint must be an http or https URL")
+	}
+	return strings.TrimSuffix(endpoint, "/"), nil
+}
+
+// credentialsFile is where a profile's tokens are stored
+func credentialsFile(profile string) string {
+	if profile == DefaultProfile {
+		return "credentials.json"
+	}
+	return "credentials-" + profile + ".json"
+}
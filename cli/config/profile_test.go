@@ -0,0 +1,79 @@
+package config
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadConfigProfiles(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("EGG_PROFILE", "")
+	t.Setenv("EGG_API_ENDPOINT", "")
+	t.Setenv("EGG_ENV", "")
+
+	profiles, err := LoadProfiles()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, set := range [][3]string{
+		{"staging", "api_endpoint", "https://staging.example.com/dev/"},
+		{"staging", "env", "staging"},
+		{"local", "api_endpoint", "http://localhost:8787"},
+	} {
+		if err := profiles.Set(set[0], set[1], set[2]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := profiles.Use("staging"); err != nil {
+		t.Fatal(err)
+	}
+	if err := profiles.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The current profile, with the rest from the built-in deployment
+	cfg, err := LoadConfig("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Profile != "staging" || cfg.APIEndpoint != "https://staging.example.com/dev" || cfg.Env != "staging" {
+		t.Errorf("unexpected staging config %+v", cfg)
+	}
+	if cfg.CognitoConfig != builtinProfile.Cognito {
+		t.Errorf("expected the built-in Cognito settings, got %+v", cfg.CognitoConfig)
+	}
+	if cfg.TokenPath != filepath.Join(home, ".eggcarton", "credentials-staging.json") {
+		t.Errorf("unexpected token path %s", cfg.TokenPath)
+	}
+
+	// EGG_PROFILE beats the current profile, and a named profile beats both
+	t.Setenv("EGG_PROFILE", "local")
+	if cfg, _ := LoadConfig(""); cfg.APIEndpoint != "http://localhost:8787" || cfg.Env != "" {
+		t.Errorf("EGG_PROFILE not used: %+v", cfg)
+	}
+	cfg, err = LoadConfig(DefaultProfile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.APIEndpoint != builtinProfile.APIEndpoint || cfg.TokenPath != filepath.Join(home, ".eggcarton", "credentials.json") {
+		t.Errorf("unexpected default config %+v", cfg)
+	}
+
+	// Environment variables override the profile
+	t.Setenv("EGG_API_ENDPOINT", "http://override:1")
+	t.Setenv("EGG_ENV", "prod")
+	if cfg, _ := LoadConfig("staging"); cfg.APIEndpoint != "http://override:1" || cfg.Env != "prod" {
+		t.Errorf("environment not applied: %+v", cfg)
+	}
+
+	if _, err := LoadConfig("missing"); err == nil {
+		t.Error("expected an error for a missing profile")
+	}
+	if err := profiles.Set("bad name", "env", "x"); err == nil {
+		t.Error("expected an error for an invalid profile name")
+	}
+	if err := profiles.Set("local", "api_endpoint", "localhost"); err == nil {
+		t.Error("expected an error for an endpoint without a scheme")
+	}
+}
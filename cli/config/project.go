@@ -0,0 +1,56 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// ProjectConfigFile is the per-directory config file holding the default
+// project and environment for commands run in that directory or below it.
+const ProjectConfigFile = ".eggcarton.json"
+
+// ProjectConfig holds the default namespace (and optionally team vault) for a
+// directory, e.g.
+//
+//	{"project": "api", "env": "dev", "team": "backend"}
+type ProjectConfig struct {
+	Project string `json:"project"`
+	Env     string `json:"env"`
+	Team    string `json:"team,omitempty"`
+	Path    string `json:"-"` // File it was loaded from
+}
+
+// LoadProjectConfig looks for ProjectConfigFile in the current directory and
+// then each parent in turn. It returns an empty config if none is found.
+func LoadProjectConfig() (*ProjectConfig, error) {
+	dir, err := os.Getwd()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get working directory: %w", err)
+	}
+
+	for {
+		path := filepath.Join(dir, ProjectConfigFile)
+		data, err := os.ReadFile(path)
+		if err == nil {
+			var project ProjectConfig
+			if err := json.Unmarshal(data, &project); err != nil {
+				return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+			}
+			project.Path = path
+			return &project, nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return &ProjectConfig{}, nil
+		}
+		dir = parent
+	}
+}
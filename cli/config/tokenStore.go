@@ -0,0 +1,193 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// TokenStore keeps the OAuth tokens of each profile
+type TokenStore interface {
+	// Name says where the tokens are kept, e.g. "the keyring"
+	Name() string
+	// Load returns the profile's tokens, or ErrNoTokens if there are none
+	Load(profile string) (*TokenData, error)
+	// Save replaces the profile's tokens
+	Save(profile string, tokens *TokenData) error
+}
+
+// ErrNoTokens means a profile has no stored tokens, i.e. isn't logged in
+var ErrNoTokens = errors.New("no tokens stored")
+
+// Kinds of token store, for the token_store setting
+const (
+	TokenStoreAuto          = "auto"           // The keyring if there is one, else an encrypted file
+	TokenStoreKeyring       = "keyring"        // macOS Keychain or the Secret Service
+	TokenStoreEncryptedFile = "encrypted-file" // A file encrypted under a passphrase
+	TokenStoreFile          = "file"           // A plaintext file, readable only by the user
+)
+
+// TokenStores lists the kinds of token store
+var TokenStores = []string{TokenStoreAuto, TokenStoreKeyring, TokenStoreEncryptedFile, TokenStoreFile}
+
+// NewTokenStore returns a token store of the given kind keeping files in dir
+func NewTokenStore(kind, dir string) (TokenStore, error) {
+	switch kind {
+	case TokenStoreAuto, "":
+		file := newEncryptedFileStore(dir)
+		if keyring, ok := newKeyringStore(); ok {
+			return &autoStore{keyring: keyring, file: file}, nil
+		}
+		return file, nil
+	case TokenStoreKeyring:
+		keyring, ok := newKeyringStore()
+		if !ok {
+			return nil, fmt.Errorf("no keyring found: egg uses the macOS Keychain, or the Secret Service through secret-tool (libsecret-tools) with a D-Bus session")
+		}
+		return keyring, nil
+	case TokenStoreEncryptedFile:
+		return newEncryptedFileStore(dir), nil
+	case TokenStoreFile:
+		return &fileStore{dir: dir}, nil
+	}
+	return nil, fmt.Errorf("unknown token store %q: use one of %v", kind, TokenStores)
+}
+
+// autoStore uses the keyring, falling back to an encrypted file if the
+// keyring fails, as it does when the Secret Service is locked or absent
+type autoStore struct {
+	keyring TokenStore
+	file    TokenStore
+	last    TokenStore // The store the tokens were last loaded from or saved to
+}
+
+func (s *autoStore) Name() string {
+	if s.last != nil {
+		return s.last.Name()
+	}
+	return s.keyring.Name()
+}
+
+func (s *autoStore) Load(profile string) (*TokenData, error) {
+	tokens, err := s.keyring.Load(profile)
+	if err == nil {
+		s.last = s.keyring
+		return tokens, nil
+	}
+	if tokens, fileErr := s.file.Load(profile); !errors.Is(fileErr, ErrNoTokens) {
+		s.last = s.file
+		return tokens, fileErr
+	}
+	return nil, err
+}
+
+func (s *autoStore) Save(profile string, tokens *TokenData) error {
+	// Keep refreshed tokens where the old ones were
+	if s.last != s.file {
+		if err := s.keyring.Save(profile, tokens); err == nil {
+			s.last = s.keyring
+			return nil
+		}
+	}
+	s.last = s.file
+	return s.file.Save(profile, tokens)
+}
+
+// fileStore keeps tokens as plaintext JSON, as the CLI always used to
+type fileStore struct {
+	dir string
+}
+
+func (s *fileStore) Name() string {
+	return "a plaintext file"
+}
+
+// Should load tokens from the profile's credentials file
+func (s *fileStore) Load(profile string) (*TokenData, error) {
+	return readPlaintextTokens(filepath.Join(s.dir, credentialsFile(profile)))
+}
+
+// Should save tokens to the profile's credentials file (~/.eggcarton/credentials.json
+// for the default profile) with 0600 permissions
+func (s *fileStore) Save(profile string, tokens *TokenData) error {
+	b, err := json.MarshalIndent(tokens, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal tokens: %w", err)
+	}
+	return writePrivateFile(filepath.Join(s.dir, credentialsFile(profile)), b)
+}
+
+func readPlaintextTokens(path string) (*TokenData, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNoTokens
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var tokens TokenData
+	if err := json.Unmarshal(data, &tokens); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal tokens: %w", err)
+	}
+	return &tokens, nil
+}
+
+// writePrivateFile replaces a file with data readable only by the user,
+// creating its directory if need be
+func writePrivateFile(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+	return WritePrivateFile(path, func(w io.Writer) error {
+		_, err := w.Write(data)
+		return err
+	})
+}
+
+// WritePrivateFile replaces a file with whatever write puts in it. The data
+// goes to a temporary file, which is created with mode 0600, flushed to disk
+// and moved into place, so it is never readable by others, even if the file
+// existed with a wider mode, and a failed write leaves the old file alone.
+func WritePrivateFile(path string, write func(w io.Writer) error) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", path, err)
+	}
+	defer os.Remove(tmp.Name()) // Fails harmlessly once renamed
+
+	if err := write(tmp); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
+
+// removePlaintext overwrites a file with zeros before removing it, so the
+// tokens it held don't linger in the freed blocks
+func removePlaintext(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if f, err := os.OpenFile(path, os.O_WRONLY, 0); err == nil {
+		f.Write(make([]byte, info.Size()))
+		f.Sync()
+		f.Close()
+	}
+	return os.Remove(path)
+}
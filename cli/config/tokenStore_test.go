@@ -0,0 +1,236 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func testTokens(access string) *TokenData {
+	return &TokenData{AccessToken: access, RefreshToken: "refresh-" + access, ExpiresIn: 3600, IssuedAt: 1700000000}
+}
+
+func TestEncryptedFileStore(t *testing.T) {
+	defaultKDFParams = kdfParams{Time: 1, Memory: 1024, Threads: 1} // Fast, for tests only
+	dir := t.TempDir()
+
+	var prompts []bool
+	newStore := func(passphrase string) *encryptedFileStore {
+		store := newEncryptedFileStore(dir)
+		store.passphrase = func(confirm bool) (string, error) {
+			prompts = append(prompts, confirm)
+			return passphrase, nil
+		}
+		return store
+	}
+
+	store := newStore("correct horse")
+	if _, err := store.Load("staging"); !errors.Is(err, ErrNoTokens) {
+		t.Fatalf("expected ErrNoTokens, got %v", err)
+	}
+	if err := store.Save("staging", testTokens("a")); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(filepath.Join(dir, "credentials-staging.enc"))
+	if strings.Contains(string(data), "refresh-a") {
+		t.Error("tokens stored in plaintext")
+	}
+	if info, _ := os.Stat(filepath.Join(dir, "credentials-staging.enc")); info.Mode().Perm() != 0600 {
+		t.Errorf("file mode %v, want 0600", info.Mode().Perm())
+	}
+
+	// A new process asks once, and refreshed tokens keep the passphrase
+	store = newStore("correct horse")
+	prompts = nil
+	tokens, err := store.Load("staging")
+	if err != nil || tokens.RefreshToken != "refresh-a" {
+		t.Fatalf("unexpected tokens %+v, %v", tokens, err)
+	}
+	if err := store.Save("staging", testTokens("b")); err != nil {
+		t.Fatal(err)
+	}
+	if len(prompts) != 1 || prompts[0] {
+		t.Errorf("unexpected prompts %v", prompts)
+	}
+
+	if _, err := newStore("wrong").Load("staging"); err == nil || errors.Is(err, ErrNoTokens) {
+		t.Errorf("expected a decryption error, got %v", err)
+	}
+	// Tokens are bound to their profile
+	os.Rename(filepath.Join(dir, "credentials-staging.enc"), filepath.Join(dir, "credentials.enc"))
+	if _, err := newStore("correct horse").Load(DefaultProfile); err == nil {
+		t.Error("tokens of another profile accepted")
+	}
+}
+
+func TestEncryptedFileStoreDamaged(t *testing.T) {
+	defaultKDFParams = kdfParams{Time: 1, Memory: 1024, Threads: 1}
+	dir := t.TempDir()
+	store := newEncryptedFileStore(dir)
+	store.passphrase = func(bool) (string, error) { return "correct horse", nil }
+	if err := store.Save(DefaultProfile, testTokens("a")); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "credentials.enc")
+	data, _ := os.ReadFile(path)
+	var good encryptedTokens
+	if err := json.Unmarshal(data, &good); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		damage func(e *encryptedTokens)
+	}{
+		{"short nonce", func(e *encryptedTokens) { e.Nonce = e.Nonce[:4] }},
+		{"no nonce", func(e *encryptedTokens) { e.Nonce = nil }},
+		{"short salt", func(e *encryptedTokens) { e.Salt = e.Salt[:8] }},
+		{"no threads", func(e *encryptedTokens) { e.Params.Threads = 0 }},
+		{"no time", func(e *encryptedTokens) { e.Params.Time = 0 }},
+		{"huge memory", func(e *encryptedTokens) { e.Params.Memory = 1 << 31 }},
+		{"huge time", func(e *encryptedTokens) { e.Params.Time = 1 << 20 }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			damaged := good
+			damaged.Salt = slices.Clone(good.Salt)
+			damaged.Nonce = slices.Clone(good.Nonce)
+			tt.damage(&damaged)
+			data, _ := json.Marshal(damaged)
+			if err := os.WriteFile(path, data, 0600); err != nil {
+				t.Fatal(err)
+			}
+
+			fresh := newEncryptedFileStore(dir)
+			fresh.passphrase = func(bool) (string, error) {
+				t.Error("asked for the passphrase of a damaged file")
+				return "correct horse", nil
+			}
+			if _, err := fresh.Load(DefaultProfile); err == nil || !strings.Contains(err.Error(), "damaged") {
+				t.Errorf("expected a damaged file error, got %v", err)
+			}
+		})
+	}
+}
+
+// fakeSecretTool stands in for secret-tool, keeping entries in a map
+func fakeSecretTool(entries map[string]string, fail bool) *keyringStore {
+	return &keyringStore{tool: "secret-tool", run: func(stdin string, args ...string) (string, int, error) {
+		if fail {
+			return "", 1, errors.New("secret-tool failed: Cannot autolaunch D-Bus")
+		}
+		account := args[len(args)-1]
+		switch args[0] {
+		case "store":
+			entries[account] = stdin
+			return "", 0, nil
+		case "lookup":
+			if value, ok := entries[account]; ok {
+				return value, 0, nil
+			}
+			return "", 1, errors.New("secret-tool failed: ")
+		}
+		return "", 2, errors.New("unexpected command")
+	}}
+}
+
+func TestKeyringStore(t *testing.T) {
+	entries := map[string]string{}
+	keyring := fakeSecretTool(entries, false)
+	if _, err := keyring.Load("default"); !errors.Is(err, ErrNoTokens) {
+		t.Fatalf("expected ErrNoTokens, got %v", err)
+	}
+	if err := keyring.Save("default", testTokens("a")); err != nil {
+		t.Fatal(err)
+	}
+	if tokens, err := keyring.Load("default"); err != nil || tokens.AccessToken != "a" {
+		t.Errorf("unexpected tokens %+v, %v", tokens, err)
+	}
+
+	// Without a working keyring, auto falls back to the encrypted file
+	defaultKDFParams = kdfParams{Time: 1, Memory: 1024, Threads: 1}
+	file := newEncryptedFileStore(t.TempDir())
+	file.passphrase = func(bool) (string, error) { return "correct horse", nil }
+	auto := &autoStore{keyring: fakeSecretTool(entries, true), file: file}
+	if err := auto.Save("default", testTokens("b")); err != nil {
+		t.Fatal(err)
+	}
+	if tokens, err := auto.Load("default"); err != nil || tokens.AccessToken != "b" || auto.Name() != file.Name() {
+		t.Errorf("unexpected tokens %+v from %s, %v", tokens, auto.Name(), err)
+	}
+}
+
+func TestMigrateTokens(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("EGG_PROFILE", "")
+	t.Setenv("EGG_TOKEN_STORE", "")
+
+	cfg, err := LoadConfig("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries := map[string]string{}
+	cfg.TokenStore = fakeSecretTool(entries, false)
+
+	if migrated, err := cfg.MigrateTokens(); migrated || err != nil {
+		t.Fatalf("nothing to migrate, got %v, %v", migrated, err)
+	}
+	if err := (&fileStore{dir: filepath.Dir(cfg.TokenPath)}).Save(DefaultProfile, testTokens("old")); err != nil {
+		t.Fatal(err)
+	}
+	if migrated, err := cfg.MigrateTokens(); !migrated || err != nil {
+		t.Fatalf("expected a migration, got %v, %v", migrated, err)
+	}
+	if _, err := os.Stat(cfg.TokenPath); !os.IsNotExist(err) {
+		t.Error("plaintext credentials left behind")
+	}
+	if tokens, err := cfg.LoadTokens(); err != nil || tokens.AccessToken != "old" {
+		t.Errorf("unexpected tokens %+v, %v", tokens, err)
+	}
+
+	// token_store file keeps them where they are
+	t.Setenv("EGG_TOKEN_STORE", TokenStoreFile)
+	cfg, _ = LoadConfig("")
+	cfg.SaveTokens(testTokens("plain"))
+	if migrated, _ := cfg.MigrateTokens(); migrated {
+		t.Error("plaintext store migrated")
+	}
+	if tokens, err := cfg.LoadTokens(); err != nil || tokens.AccessToken != "plain" {
+		t.Errorf("unexpected tokens %+v, %v", tokens, err)
+	}
+}
+
+// countingStore counts the loads that reach the wrapped store
+type countingStore struct {
+	TokenStore
+	loads int
+}
+
+func (s *countingStore) Load(profile string) (*TokenData, error) {
+	s.loads++
+	return s.TokenStore.Load(profile)
+}
+
+func TestLoadTokensOnce(t *testing.T) {
+	store := &countingStore{TokenStore: fakeSecretTool(map[string]string{}, false)}
+	cfg := &Config{Profile: DefaultProfile, TokenStore: store}
+	if _, err := cfg.LoadTokens(); !errors.Is(err, ErrNoTokens) {
+		t.Fatalf("expected ErrNoTokens, got %v", err)
+	}
+	if err := cfg.SaveTokens(testTokens("a")); err != nil {
+		t.Fatal(err)
+	}
+	for range 3 {
+		if tokens, err := cfg.LoadTokens(); err != nil || tokens.AccessToken != "a" {
+			t.Fatalf("unexpected tokens %+v, %v", tokens, err)
+		}
+	}
+	if store.loads != 1 {
+		t.Errorf("store loaded %d times, want 1", store.loads)
+	}
+}
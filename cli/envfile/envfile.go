@@ -0,0 +1,280 @@
+// Package envfile reads secrets from the files projects usually keep them in,
+// dotenv files, flat JSON objects and flat YAML mappings, and writes them in
+// those and the formats deployment tools take.
+package envfile
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Format is a kind of secrets file
+type Format string
+
+const (
+	Dotenv Format = "dotenv"
+	JSON   Format = "json"
+	YAML   Format = "yaml"
+)
+
+// Formats lists every format Parse reads
+var Formats = []Format{Dotenv, JSON, YAML}
+
+// ParseFormat checks a format name given by the user
+func ParseFormat(name string) (Format, error) {
+	format := Format(strings.ToLower(name))
+	if format == "yml" {
+		format = YAML
+	}
+	if !slices.Contains(Formats, format) {
+		return "", fmt.Errorf("unknown format %q: use dotenv, json or yaml", name)
+	}
+	return format, nil
+}
+
+// FormatOf guesses a file's format from its name: .json and .yaml/.yml
+// files are JSON and YAML, anything else (.env, .env.local, ...) is dotenv.
+func FormatOf(path string) Format {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return JSON
+	case ".yaml", ".yml":
+		return YAML
+	}
+	return Dotenv
+}
+
+// Entry is one secret read from a file
+type Entry struct {
+	Key   string
+	Value string
+	Line  int // Where the entry starts, or 0 if not known
+}
+
+// Parse reads every entry of a file in the given format, in file order
+// (sorted by key for JSON). A key set twice is an error rather than letting
+// one value silently win.
+func Parse(r io.Reader, format Format) ([]Entry, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []Entry
+	switch format {
+	case Dotenv:
+		entries, err = parseDotenv(string(data))
+	case JSON:
+		entries, err = parseJSON(data)
+	case YAML:
+		entries, err = parseYAML(data)
+	default:
+		return nil, fmt.Errorf("unknown format %q", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]Entry, len(entries))
+	for _, entry := range entries {
+		if entry.Key == "" {
+			return nil, fmt.Errorf("%sempty key", at(entry.Line))
+		}
+		if first, ok := seen[entry.Key]; ok {
+			return nil, fmt.Errorf("%s%s is already set%s", at(entry.Line), entry.Key, onLine(first.Line))
+		}
+		seen[entry.Key] = entry
+	}
+	return entries, nil
+}
+
+func at(line int) string {
+	if line == 0 {
+		return ""
+	}
+	return fmt.Sprintf("line %d: ", line)
+}
+
+func onLine(line int) string {
+	if line == 0 {
+		return ""
+	}
+	return fmt.Sprintf(" on line %d", line)
+}
+
+// parseDotenv reads KEY=value lines. Blank lines and # comments are skipped,
+// and an "export " prefix is dropped. Unquoted values are trimmed and end at
+// a " #" comment; single-quoted values are taken literally and double-quoted
+// ones understand \n, \t, \" and friends. Either kind of quote can span lines.
+func parseDotenv(data string) ([]Entry, error) {
+	data = strings.ReplaceAll(data, "\r\n", "\n")
+	lines := strings.Split(data, "\n")
+
+	var entries []Entry
+	for i := 0; i < len(lines); i++ {
+		start := i + 1
+		line := strings.TrimSpace(lines[i])
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if rest, ok := strings.CutPrefix(line, "export"); ok && rest != "" && (rest[0] == ' ' || rest[0] == '\t') {
+			line = strings.TrimSpace(rest)
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected KEY=value", start)
+		}
+		key = strings.TrimSpace(key)
+		if strings.ContainsAny(key, " \t'\"") {
+			return nil, fmt.Errorf("line %d: invalid key %q", start, key)
+		}
+		value = strings.TrimLeft(value, " \t")
+
+		if value == "" || (value[0] != '"' && value[0] != '\'') {
+			entries = append(entries, Entry{Key: key, Value: unquoted(value), Line: start})
+			continue
+		}
+
+		// A quoted value runs until its closing quote, possibly lines later
+		quote := value[0]
+		raw := value[1:]
+		end := closingQuote(raw, quote)
+		for end < 0 {
+			i++
+			if i == len(lines) {
+				return nil, fmt.Errorf("line %d: %s has no closing %c", start, key, quote)
+			}
+			raw += "\n" + lines[i]
+			end = closingQuote(raw, quote)
+		}
+		if rest := strings.TrimSpace(raw[end+1:]); rest != "" && !strings.HasPrefix(rest, "#") {
+			return nil, fmt.Errorf("line %d: unexpected %q after the value of %s", i+1, rest, key)
+		}
+		raw = raw[:end]
+		if quote == '"' {
+			raw = unescape(raw)
+		}
+		entries = append(entries, Entry{Key: key, Value: raw, Line: start})
+	}
+	return entries, nil
+}
+
+// unquoted trims an unquoted value and drops any trailing comment
+func unquoted(value string) string {
+	for i := 1; i < len(value); i++ {
+		if value[i] == '#' && (value[i-1] == ' ' || value[i-1] == '\t') {
+			value = value[:i]
+			break
+		}
+	}
+	return strings.TrimSpace(value)
+}
+
+// closingQuote finds the quote ending a value, skipping escaped double
+// quotes, or returns -1 if there isn't one.
+func closingQuote(s string, quote byte) int {
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\\' && quote == '"':
+			i++
+		case s[i] == quote:
+			return i
+		}
+	}
+	return -1
+}
+
+func unescape(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i == len(s)-1 {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch s[i] {
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case 't':
+			b.WriteByte('\t')
+		case '"', '\\', '$':
+			b.WriteByte(s[i])
+		default:
+			b.WriteByte('\\')
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String()
+}
+
+// parseJSON reads a flat object. Strings are taken as they are, numbers and
+// booleans as written; nested objects, arrays and nulls are rejected.
+func parseJSON(data []byte) ([]Entry, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var object map[string]any
+	if err := decoder.Decode(&object); err != nil {
+		return nil, fmt.Errorf("expected a JSON object of secrets: %w", err)
+	}
+
+	keys := make([]string, 0, len(object))
+	for key := range object {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	entries := make([]Entry, 0, len(keys))
+	for _, key := range keys {
+		var value string
+		switch v := object[key].(type) {
+		case string:
+			value = v
+		case json.Number:
+			value = v.String()
+		case bool:
+			value = fmt.Sprint(v)
+		default:
+			return nil, fmt.Errorf("%s: only strings, numbers and booleans can be imported", key)
+		}
+		entries = append(entries, Entry{Key: key, Value: value})
+	}
+	return entries, nil
+}
+
+// parseYAML reads a flat mapping of scalars, keeping the file's order
+func parseYAML(data []byte) ([]Entry, error) {
+	var document yaml.Node
+	if err := yaml.Unmarshal(data, &document); err != nil {
+		return nil, fmt.Errorf("invalid YAML: %w", err)
+	}
+	if len(document.Content) == 0 {
+		return nil, nil
+	}
+	mapping := document.Content[0]
+	if mapping.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("line %d: expected a YAML mapping of secrets", mapping.Line)
+	}
+
+	entries := make([]Entry, 0, len(mapping.Content)/2)
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		key, value := mapping.Content[i], mapping.Content[i+1]
+		if key.Kind != yaml.ScalarNode {
+			return nil, fmt.Errorf("line %d: keys must be plain strings", key.Line)
+		}
+		if value.Kind != yaml.ScalarNode || value.Tag == "!!null" {
+			return nil, fmt.Errorf("line %d: %s: only strings, numbers and booleans can be imported", value.Line, key.Value)
+		}
+		entries = append(entries, Entry{Key: key.Value, Value: value.Value, Line: key.Line})
+	}
+	return entries, nil
+}
@@ -0,0 +1,157 @@
+package envfile
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name   string
+		format Format
+		input  string
+		want   []Entry
+	}{
+		{"dotenv", Dotenv, `
+# Database
+DB_HOST=localhost
+export DB_USER = admin   # inline comment
+DB_PASS='p@ss # not a comment'
+GREETING="hello\n\"world\""
+EMPTY=
+URL=http://example.com/#anchor
+`, []Entry{
+			{"DB_HOST", "localhost", 3},
+			{"DB_USER", "admin", 4},
+			{"DB_PASS", "p@ss # not a comment", 5},
+			{"GREETING", "hello\n\"world\"", 6},
+			{"EMPTY", "", 7},
+			{"URL", "http://example.com/#anchor", 8},
+		}},
+		{"dotenv multiline", Dotenv, "KEY=\"-----BEGIN KEY-----\nabc\n-----END KEY-----\"\r\nexporter=1\n", []Entry{
+			{"KEY", "-----BEGIN KEY-----\nabc\n-----END KEY-----", 1},
+			{"exporter", "1", 4},
+		}},
+		{"json", JSON, `{"B": "two", "A": 1.50, "C": true}`, []Entry{
+			{"A", "1.50", 0},
+			{"B", "two", 0},
+			{"C", "true", 0},
+		}},
+		{"yaml", YAML, "B: two\nA: 1.50\nC: |\n  line one\n  line two\n", []Entry{
+			{"B", "two", 1},
+			{"A", "1.50", 2},
+			{"C", "line one\nline two\n", 3},
+		}},
+	}
+	for _, tt := range tests {
+		got, err := Parse(strings.NewReader(tt.input), tt.format)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		format Format
+		input  string
+		want   string
+	}{
+		{Dotenv, "A=1\nNOT A PAIR\n", "line 2: expected KEY=value"},
+		{Dotenv, "A=1\nA=2\n", "line 2: A is already set on line 1"},
+		{Dotenv, "A=\"open\n", "line 1: A has no closing \""},
+		{Dotenv, "A='x' y\n", `line 1: unexpected "y" after the value of A`},
+		{JSON, `{"A": {"nested": true}}`, "A: only strings, numbers and booleans can be imported"},
+		{JSON, `["A"]`, "expected a JSON object of secrets"},
+		{YAML, "A: [1, 2]\n", "line 1: A: only strings, numbers and booleans can be imported"},
+		{YAML, "A: ~\n", "line 1: A: only strings, numbers and booleans can be imported"},
+		{YAML, "- A\n", "line 1: expected a YAML mapping of secrets"},
+	}
+	for _, tt := range tests {
+		_, err := Parse(strings.NewReader(tt.input), tt.format)
+		if err == nil || !strings.HasPrefix(err.Error(), tt.want) {
+			t.Errorf("%s %q: got error %v, want %q", tt.format, tt.input, err, tt.want)
+		}
+	}
+}
+
+func TestFormatOf(t *testing.T) {
+	for path, want := range map[string]Format{
+		".env":             Dotenv,
+		"config/.env.prod": Dotenv,
+		"secrets.json":     JSON,
+		"secrets.YAML":     YAML,
+		"secrets.yml":      YAML,
+	} {
+		if got := FormatOf(path); got != want {
+			t.Errorf("FormatOf(%q) = %s, want %s", path, got, want)
+		}
+	}
+}
+
+func TestWriteRoundTrip(t *testing.T) {
+	entries := []Entry{
+		{Key: "BOOL", Value: "true"},
+		{Key: "MULTI", Value: "line one\nline \"two\"\tit's $HOME \\n"},
+		{Key: "URL", Value: "postgres://u:p@host/db?a=1&b=<2> # not a comment"},
+	}
+	for _, format := range Formats {
+		var b strings.Builder
+		if err := Write(&b, entries, format, WriteOptions{}); err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+		got, err := Parse(strings.NewReader(b.String()), format)
+		if err != nil {
+			t.Fatalf("%s: %v\n%s", format, err, b.String())
+		}
+		for i := range got {
+			got[i].Line = 0
+		}
+		if !slices.Equal(got, entries) {
+			t.Errorf("%s: got %q back from\n%s", format, got, b.String())
+		}
+	}
+}
+
+func TestWrite(t *testing.T) {
+	entries := []Entry{{Key: "A", Value: "it's"}, {Key: "B", Value: "x=1"}}
+	tests := []struct {
+		format Format
+		want   string
+	}{
+		{Shell, "export A='it'\\''s'\nexport B='x=1'\n"},
+		{DockerEnv, "A=it's\nB=x=1\n"},
+		{K8sSecret, "apiVersion: v1\nkind: Secret\nmetadata:\n  name: api-prod\n  namespace: web\ntype: Opaque\ndata:\n  A: aXQncw==\n  B: eD0x\n"},
+	}
+	for _, tt := range tests {
+		var b strings.Builder
+		if err := Write(&b, entries, tt.format, WriteOptions{Name: "api-prod", Namespace: "web"}); err != nil {
+			t.Fatalf("%s: %v", tt.format, err)
+		}
+		if b.String() != tt.want {
+			t.Errorf("%s: got\n%s\nwant\n%s", tt.format, b.String(), tt.want)
+		}
+	}
+
+	errors := []struct {
+		format  Format
+		entries []Entry
+		opts    WriteOptions
+	}{
+		{DockerEnv, []Entry{{Key: "A", Value: "two\nlines"}}, WriteOptions{}},
+		{Shell, []Entry{{Key: "my-key", Value: "x"}}, WriteOptions{}},
+		{K8sSecret, []Entry{{Key: "A", Value: "x"}}, WriteOptions{Name: "Not_Valid"}},
+		{K8sSecret, []Entry{{Key: "A:B", Value: "x"}}, WriteOptions{Name: "ok"}},
+	}
+	for _, tt := range errors {
+		var b strings.Builder
+		if err := Write(&b, tt.entries, tt.format, tt.opts); err == nil || b.Len() > 0 {
+			t.Errorf("%s %q: expected an error and no output, got %v and %q", tt.format, tt.entries, err, b.String())
+		}
+	}
+}
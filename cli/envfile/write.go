@@ -0,0 +1,222 @@
+package envfile
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Formats that can only be written
+const (
+	Shell     Format = "shell"      // export KEY='value' lines for eval or source
+	DockerEnv Format = "docker-env" // docker run --env-file and compose env_file
+	K8sSecret Format = "k8s-secret" // A Kubernetes Secret manifest for kubectl apply
+)
+
+// ExportFormats lists every format Write supports
+var ExportFormats = []Format{Dotenv, JSON, YAML, Shell, DockerEnv, K8sSecret}
+
+// ParseExportFormat checks a format name given by the user for Write
+func ParseExportFormat(name string) (Format, error) {
+	format := Format(strings.ToLower(name))
+	if format == "yml" {
+		format = YAML
+	}
+	if !slices.Contains(ExportFormats, format) {
+		return "", fmt.Errorf("unknown format %q: use dotenv, json, yaml, shell, docker-env or k8s-secret", name)
+	}
+	return format, nil
+}
+
+// WriteOptions holds what K8sSecret needs beyond the entries
+type WriteOptions struct {
+	Name      string // metadata.name of the Secret
+	Namespace string // metadata.namespace, left out if empty
+}
+
+var (
+	shellKeyPattern   = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+	k8sKeyPattern     = regexp.MustCompile(`^[-._a-zA-Z0-9]+$`)
+	k8sNamePattern    = regexp.MustCompile(`^[a-z0-9]([-a-z0-9.]{0,251}[a-z0-9])?$`)
+	plainValuePattern = regexp.MustCompile(`^[A-Za-z0-9_./:@%+,=-]*$`)
+)
+
+// Write writes entries in the given format, escaped so the tool reading the
+// format gets the values back exactly. Keys or values a format can't hold,
+// such as a multi-line value in a Docker env file, are an error and nothing
+// is written.
+func Write(w io.Writer, entries []Entry, format Format, opts WriteOptions) error {
+	var out []byte
+	var err error
+	switch format {
+	case Dotenv:
+		out, err = lines(entries, dotenvLine)
+	case Shell:
+		out, err = lines(entries, shellLine)
+	case DockerEnv:
+		out, err = lines(entries, dockerEnvLine)
+	case JSON:
+		out, err = writeJSON(entries)
+	case YAML:
+		out, err = writeYAML(entries)
+	case K8sSecret:
+		out, err = writeK8sSecret(entries, opts)
+	default:
+		return fmt.Errorf("unknown format %q", format)
+	}
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(out)
+	return err
+}
+
+func lines(entries []Entry, line func(Entry) (string, error)) ([]byte, error) {
+	var b strings.Builder
+	for _, entry := range entries {
+		text, err := line(entry)
+		if err != nil {
+			return nil, err
+		}
+		b.WriteString(text)
+		b.WriteByte('\n')
+	}
+	return []byte(b.String()), nil
+}
+
+// dotenvLine writes KEY=value, double quoting any value that isn't plain,
+// as Parse and most dotenv loaders read it.
+func dotenvLine(entry Entry) (string, error) {
+	if entry.Key == "" || strings.ContainsAny(entry.Key, "= \t\r\n'\"#") {
+		return "", fmt.Errorf("%q can't be a dotenv key", entry.Key)
+	}
+	if plainValuePattern.MatchString(entry.Value) {
+		return entry.Key + "=" + entry.Value, nil
+	}
+	escaper := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`, "\t", `\t`, "$", `\$`)
+	return entry.Key + `="` + escaper.Replace(entry.Value) + `"`, nil
+}
+
+// shellLine writes export KEY='value'. Nothing is special inside single
+// quotes, so a quote in the value only has to close them, add an escaped
+// quote and open them again.
+func shellLine(entry Entry) (string, error) {
+	if !shellKeyPattern.MatchString(entry.Key) {
+		return "", fmt.Errorf("%q can't be a shell variable name", entry.Key)
+	}
+	return "export " + entry.Key + "='" + strings.ReplaceAll(entry.Value, "'", `'\''`) + "'", nil
+}
+
+// dockerEnvLine writes KEY=value. Docker takes the rest of the line as it
+// is, quotes included, so there is no escaping and no way to hold a newline.
+func dockerEnvLine(entry Entry) (string, error) {
+	if entry.Key == "" || strings.ContainsAny(entry.Key, "= \t\r\n") || strings.HasPrefix(entry.Key, "#") {
+		return "", fmt.Errorf("%q can't be a Docker env-file key", entry.Key)
+	}
+	if strings.ContainsAny(entry.Value, "\r\n") {
+		return "", fmt.Errorf("%s: Docker env files can't hold multi-line values; use another format", entry.Key)
+	}
+	return entry.Key + "=" + entry.Value, nil
+}
+
+// writeJSON writes a flat object, keeping the entries' order
+func writeJSON(entries []Entry) ([]byte, error) {
+	var b strings.Builder
+	b.WriteString("{")
+	for i, entry := range entries {
+		if i > 0 {
+			b.WriteString(",")
+		}
+		key, err := marshalJSON(entry.Key)
+		if err != nil {
+			return nil, err
+		}
+		value, err := marshalJSON(entry.Value)
+		if err != nil {
+			return nil, err
+		}
+		b.WriteString("\n  " + key + ": " + value)
+	}
+	if len(entries) > 0 {
+		b.WriteString("\n")
+	}
+	b.WriteString("}\n")
+	return []byte(b.String()), nil
+}
+
+// marshalJSON encodes a string without escaping <, > and &, which are
+// common in connection strings.
+func marshalJSON(s string) (string, error) {
+	var b strings.Builder
+	encoder := json.NewEncoder(&b)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(s); err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(b.String(), "\n"), nil
+}
+
+// writeYAML writes a flat mapping. Every value is tagged as a string, so
+// values like "true" or "012" are quoted rather than read back as other types.
+func writeYAML(entries []Entry) ([]byte, error) {
+	mapping := &yaml.Node{Kind: yaml.MappingNode}
+	for _, entry := range entries {
+		mapping.Content = append(mapping.Content, stringNode(entry.Key), stringNode(entry.Value))
+	}
+	return marshalYAML(mapping)
+}
+
+// writeK8sSecret writes an Opaque Secret with the values base64 encoded
+// under data, as kubectl create secret generic would.
+func writeK8sSecret(entries []Entry, opts WriteOptions) ([]byte, error) {
+	if !k8sNamePattern.MatchString(opts.Name) {
+		return nil, fmt.Errorf("%q isn't a valid Kubernetes Secret name: use lower case letters, digits, '-' and '.'", opts.Name)
+	}
+	metadata := &yaml.Node{Kind: yaml.MappingNode}
+	metadata.Content = append(metadata.Content, stringNode("name"), stringNode(opts.Name))
+	if opts.Namespace != "" {
+		metadata.Content = append(metadata.Content, stringNode("namespace"), stringNode(opts.Namespace))
+	}
+
+	data := &yaml.Node{Kind: yaml.MappingNode}
+	for _, entry := range entries {
+		if !k8sKeyPattern.MatchString(entry.Key) {
+			return nil, fmt.Errorf("%q can't be a Kubernetes Secret key: use letters, digits, '-', '_' and '.'", entry.Key)
+		}
+		encoded := base64.StdEncoding.EncodeToString([]byte(entry.Value))
+		data.Content = append(data.Content, stringNode(entry.Key), stringNode(encoded))
+	}
+
+	secret := &yaml.Node{Kind: yaml.MappingNode}
+	secret.Content = append(secret.Content,
+		stringNode("apiVersion"), stringNode("v1"),
+		stringNode("kind"), stringNode("Secret"),
+		stringNode("metadata"), metadata,
+		stringNode("type"), stringNode("Opaque"),
+		stringNode("data"), data,
+	)
+	return marshalYAML(secret)
+}
+
+func stringNode(s string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: s}
+}
+
+func marshalYAML(node *yaml.Node) ([]byte, error) {
+	var b strings.Builder
+	encoder := yaml.NewEncoder(&b)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(node); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return []byte(b.String()), nil
+}
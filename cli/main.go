@@ -14,11 +14,26 @@ var rootCmd = &cobra.Command{
 	Long: `EggCarton is a secure CLI tool for managing secrets with an egg theme!
 
 Commands:
+  🪺 init            - Configure the CLI for a deployment
   🔐 login           - Authenticate with OAuth
   🐔 lay (add)       - Store a secret (lay an egg)
   🥚 get             - Retrieve secrets from your vault
+  📋 list            - List secret keys without decrypting them
   🐣 hatch (run)     - Inject secrets and run a command (hatch your eggs)
-  💥 break           - Delete a secret from your vault
+  💥 break           - Move secrets to the trash (--purge deletes them for good)
+  🗑️  trash list      - List secrets in the trash
+  ♻️  restore         - Restore a secret from the trash
+  📜 history         - Show the version history of a secret
+  ⏪ rollback        - Restore an older version of a secret
+  👥 team            - Manage shared team vaults
+  🔍 audit           - Show who accessed a vault
+  📦 import          - Store every secret in a .env, JSON or YAML file
+  📤 export          - Write secrets out as dotenv, JSON, YAML, shell, Docker or Kubernetes
+  ⚙️  config          - Manage configuration profiles
+
+Every command takes --output table|json|yaml|raw: results go to stdout in
+that format, and progress and status messages to stderr. --profile picks
+the deployment from ~/.eggcarton/config.yaml (see 'egg config').
 
 It uses AWS Lambda, DynamoDB, and KMS for encryption,
 with Cognito authentication via OAuth PKCE flow.`,
@@ -26,15 +41,29 @@ with Cognito authentication via OAuth PKCE flow.`,
 
 func main() {
 	// Add all subcommands
+	rootCmd.AddCommand(commands.InitCmd)
 	rootCmd.AddCommand(commands.LoginCmd)
 	rootCmd.AddCommand(commands.AddCmd)
 	rootCmd.AddCommand(commands.GetCmd)
+	rootCmd.AddCommand(commands.ListCmd)
 	rootCmd.AddCommand(commands.BreakCmd)
+	rootCmd.AddCommand(commands.TrashCmd)
+	rootCmd.AddCommand(commands.RestoreCmd)
 	rootCmd.AddCommand(commands.RunCmd)
+	rootCmd.AddCommand(commands.HistoryCmd)
+	rootCmd.AddCommand(commands.RollbackCmd)
+	rootCmd.AddCommand(commands.TeamCmd)
+	rootCmd.AddCommand(commands.AuditCmd)
+	rootCmd.AddCommand(commands.ImportCmd)
+	rootCmd.AddCommand(commands.ExportCmd)
+	rootCmd.AddCommand(commands.ConfigCmd)
+
+	commands.AddGlobalFlags(rootCmd)
+	rootCmd.SilenceErrors = true // Printed below
 
 	// Execute the root command
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
+		os.Exit(commands.ExitCode(err))
 	}
 }
@@ -0,0 +1,228 @@
+package actions
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/owenHochwald/egg-carton/pkg/crypto"
+)
+
+var ErrAuditConflict = errors.New("audit sequence number already taken")
+
+// Audit actions
+const (
+	AuditPut     = "put"
+	AuditGet     = "get"
+	AuditList    = "list"
+	AuditBreak   = "break"
+	AuditHistory = "history"
+	AuditRestore = "restore"
+	// Trash
+	AuditPurge    = "purge"
+	AuditUndelete = "undelete"
+)
+
+// Audit outcomes
+const (
+	OutcomeSuccess  = "success"
+	OutcomeDenied   = "denied"
+	OutcomeNotFound = "not_found"
+	OutcomeError    = "error"
+)
+
+// AuditEvent records one access to a vault. Each vault has its own chain of
+// events: Seq counts up from 1 and every event's Hash covers its own fields
+// and the Hash of the event before it, so editing, removing or reordering
+// any stored event breaks every hash after it.
+//
+// A hash alone can be recomputed by anyone able to write the table, so MAC
+// authenticates Hash under an audit key the writer can use but not read (a
+// KMS HMAC key in production). Since each Hash covers everything before it,
+// a valid MAC on the newest event vouches for the whole chain (see
+// VerifyAuditChain). Events written before the chain was keyed have no MAC.
+type AuditEvent struct {
+	Vault     string `dynamodbav:"Vault" json:"vault"` // Owner of the vault accessed
+	Seq       int    `dynamodbav:"Seq" json:"seq"`
+	Time      string `dynamodbav:"Time" json:"time"` // RFC 3339, UTC
+	Actor     string `dynamodbav:"Actor" json:"actor"`
+	Action    string `dynamodbav:"Action" json:"action"`
+	SecretID  string `dynamodbav:"SecretID,omitempty" json:"secret_id,omitempty"`
+	Version   int    `dynamodbav:"Version,omitempty" json:"version,omitempty"`
+	SourceIP  string `dynamodbav:"SourceIP,omitempty" json:"source_ip,omitempty"`
+	UserAgent string `dynamodbav:"UserAgent,omitempty" json:"user_agent,omitempty"`
+	RequestID string `dynamodbav:"RequestID,omitempty" json:"request_id,omitempty"`
+	Outcome   string `dynamodbav:"Outcome" json:"outcome"`
+	PrevHash  string `dynamodbav:"PrevHash" json:"prev_hash"`
+	Hash      string `dynamodbav:"Hash" json:"hash"`
+	MAC       string `dynamodbav:"MAC,omitempty" json:"mac,omitempty"`
+}
+
+// AuditActions is the append-only audit store. Every storage backend
+// implements it alongside EggActions.
+type AuditActions interface {
+	// PutAuditEvent stores event at its Seq. It returns ErrAuditConflict if
+	// that sequence number is already taken; stored events are never
+	// overwritten.
+	PutAuditEvent(ctx context.Context, event AuditEvent) error
+	// LastAuditEvent returns the newest event of vault, or the zero event if
+	// there is none.
+	LastAuditEvent(ctx context.Context, vault string) (AuditEvent, error)
+	// ListAuditEvents returns the whole chain of vault, oldest first.
+	ListAuditEvents(ctx context.Context, vault string) ([]AuditEvent, error)
+}
+
+// computeHash hashes the event's fields, excluding Hash and MAC, chained to
+// PrevHash.
+func (e AuditEvent) computeHash() string {
+	e.Hash, e.MAC = "", ""
+	fields, err := json.Marshal(e)
+	if err != nil {
+		panic(err)
+	}
+	sum := sha256.Sum256(append([]byte(e.PrevHash+"\n"), fields...))
+	return hex.EncodeToString(sum[:])
+}
+
+// AppendAuditEvent links event onto the end of its vault's chain, signs it
+// with key and stores it, retrying if another writer appends at the same
+// time.
+func AppendAuditEvent(ctx context.Context, store AuditActions, key crypto.MACKey, event AuditEvent) (AuditEvent, error) {
+	if key == nil {
+		return event, errors.New("no audit key to sign the event with")
+	}
+	for attempt := 0; attempt < 5; attempt++ {
+		last, err := store.LastAuditEvent(ctx, event.Vault)
+		if err != nil {
+			return event, err
+		}
+		event.Seq = last.Seq + 1
+		event.PrevHash = last.Hash
+		event.Hash = event.computeHash()
+		mac, err := key.GenerateMAC(ctx, []byte(event.Hash))
+		if err != nil {
+			return event, fmt.Errorf("failed to sign audit event: %w", err)
+		}
+		event.MAC = hex.EncodeToString(mac)
+
+		err = store.PutAuditEvent(ctx, event)
+		if !errors.Is(err, ErrAuditConflict) {
+			return event, err
+		}
+	}
+	return event, ErrAuditConflict
+}
+
+// VerifyAuditChain checks that events form an unbroken chain from Seq 1,
+// that no event has been altered, and that the chain was written by a holder
+// of key. It reports the first broken link.
+//
+// Only the newest event's MAC is checked, as its Hash covers every event
+// before it. Once one event is signed every later one must be too, or the
+// end of the chain was rewritten without the key. Events removed from the
+// end can't be detected from the chain itself.
+func VerifyAuditChain(ctx context.Context, key crypto.MACKey, events []AuditEvent) error {
+	prevHash := ""
+	signed := false
+	for i, event := range events {
+		if event.Seq != i+1 {
+			return fmt.Errorf("audit event %d is missing or out of order (found %d)", i+1, event.Seq)
+		}
+		if event.PrevHash != prevHash {
+			return fmt.Errorf("audit event %d does not link to the event before it", event.Seq)
+		}
+		if event.computeHash() != event.Hash {
+			return fmt.Errorf("audit event %d has been modified", event.Seq)
+		}
+		if signed && event.MAC == "" {
+			return fmt.Errorf("audit event %d is not signed but follows signed events", event.Seq)
+		}
+		signed = event.MAC != ""
+		prevHash = event.Hash
+	}
+	if len(events) == 0 {
+		return nil
+	}
+
+	head := events[len(events)-1]
+	if head.MAC == "" {
+		return fmt.Errorf("audit event %d is not signed, so the chain can't be authenticated", head.Seq)
+	}
+	mac, err := hex.DecodeString(head.MAC)
+	if err == nil {
+		err = key.VerifyMAC(ctx, []byte(head.Hash), mac)
+	} else {
+		err = crypto.ErrInvalidMAC
+	}
+	if errors.Is(err, crypto.ErrInvalidMAC) {
+		return fmt.Errorf("audit event %d has been modified: its MAC does not match", head.Seq)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to verify audit event %d: %w", head.Seq, err)
+	}
+	return nil
+}
+
+// AuditFilter selects audit events. Zero fields match everything.
+type AuditFilter struct {
+	SecretID string
+	Since    time.Time
+	Until    time.Time
+}
+
+// FilterAuditEvents returns the events that match f.
+func FilterAuditEvents(events []AuditEvent, f AuditFilter) []AuditEvent {
+	matched := []AuditEvent{}
+	for _, event := range events {
+		if f.SecretID != "" && event.SecretID != f.SecretID {
+			continue
+		}
+		at, err := time.Parse(time.RFC3339Nano, event.Time)
+		if err == nil && (!f.Since.IsZero() && at.Before(f.Since) || !f.Until.IsZero() && at.After(f.Until)) {
+			continue
+		}
+		matched = append(matched, event)
+	}
+	return matched
+}
+
+// NewAuditEvent starts an audit event for an API request. The handler fills
+// in Vault, SecretID and Version as it learns them, then passes the event to
+// RecordAudit.
+func NewAuditEvent(request events.APIGatewayV2HTTPRequest, action string) AuditEvent {
+	return AuditEvent{
+		Time:      time.Now().UTC().Format(time.RFC3339Nano),
+		Actor:     request.RequestContext.Authorizer.JWT.Claims["sub"],
+		Action:    action,
+		SourceIP:  request.RequestContext.HTTP.SourceIP,
+		UserAgent: request.RequestContext.HTTP.UserAgent,
+		RequestID: request.RequestContext.RequestID,
+	}
+}
+
+// RecordAudit appends event with the outcome implied by the response status.
+// Requests that never identified a vault or caller (malformed or
+// unauthenticated) aren't recorded. The caller decides what a failure means
+// for the request.
+func RecordAudit(ctx context.Context, store AuditActions, key crypto.MACKey, event AuditEvent, statusCode int) error {
+	if event.Vault == "" || event.Actor == "" {
+		return nil
+	}
+	switch {
+	case statusCode < 300:
+		event.Outcome = OutcomeSuccess
+	case statusCode == 401 || statusCode == 403:
+		event.Outcome = OutcomeDenied
+	case statusCode == 404 || statusCode == 410:
+		event.Outcome = OutcomeNotFound
+	default:
+		event.Outcome = OutcomeError
+	}
+	_, err := AppendAuditEvent(ctx, store, key, event)
+	return err
+}
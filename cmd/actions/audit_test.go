@@ -0,0 +1,139 @@
+package actions
+
+import (
+	"context"
+	"encoding/hex"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/owenHochwald/egg-carton/pkg/crypto"
+)
+
+func newAuditKey(t *testing.T, name string) *crypto.LocalMACKey {
+	t.Helper()
+	key, err := crypto.NewLocalMACKey(filepath.Join(t.TempDir(), name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+// relink recomputes every hash of events from i on, as anyone able to write
+// the table could, signing them with key or, if it is nil, not at all.
+func relink(t *testing.T, events []AuditEvent, i int, key crypto.MACKey) []AuditEvent {
+	t.Helper()
+	events = append([]AuditEvent(nil), events...)
+	for ; i < len(events); i++ {
+		if i > 0 {
+			events[i].PrevHash = events[i-1].Hash
+		}
+		events[i].Hash = events[i].computeHash()
+		events[i].MAC = ""
+		if key != nil {
+			mac, _ := key.GenerateMAC(context.Background(), []byte(events[i].Hash))
+			events[i].MAC = hex.EncodeToString(mac)
+		}
+	}
+	return events
+}
+
+func TestAuditChain(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMemoryEggRepository()
+	key := newAuditKey(t, "audit.key")
+	for i, action := range []string{AuditPut, AuditGet, AuditBreak} {
+		event, err := AppendAuditEvent(ctx, repo, key, AuditEvent{
+			Vault:    "alice",
+			Time:     time.Date(2026, 1, i+1, 0, 0, 0, 0, time.UTC).Format(time.RFC3339Nano),
+			Actor:    "alice",
+			Action:   action,
+			SecretID: "KEY",
+			Outcome:  OutcomeSuccess,
+		})
+		if err != nil {
+			t.Fatalf("AppendAuditEvent: %v", err)
+		}
+		if event.Seq != i+1 {
+			t.Errorf("AppendAuditEvent: got seq %d, want %d", event.Seq, i+1)
+		}
+	}
+
+	events, _ := repo.ListAuditEvents(ctx, "alice")
+	if err := VerifyAuditChain(ctx, key, events); err != nil {
+		t.Fatalf("VerifyAuditChain on an untouched chain: %v", err)
+	}
+
+	edited := append([]AuditEvent(nil), events...)
+	edited[1].Outcome = OutcomeDenied
+	if err := VerifyAuditChain(ctx, key, edited); err == nil {
+		t.Error("VerifyAuditChain accepted an edited event")
+	}
+	if err := VerifyAuditChain(ctx, key, []AuditEvent{events[0], events[2]}); err == nil {
+		t.Error("VerifyAuditChain accepted a chain with an event removed")
+	}
+
+	// Rewriting an event and recomputing every hash after it takes the key
+	edited[1].Outcome = OutcomeDenied
+	if err := VerifyAuditChain(ctx, key, relink(t, edited, 1, newAuditKey(t, "other.key"))); err == nil {
+		t.Error("VerifyAuditChain accepted a chain re-signed with another key")
+	}
+	if err := VerifyAuditChain(ctx, key, relink(t, edited, 1, nil)); err == nil {
+		t.Error("VerifyAuditChain accepted a chain with its signatures stripped")
+	}
+	if err := VerifyAuditChain(ctx, key, relink(t, edited, 1, key)); err != nil {
+		t.Errorf("VerifyAuditChain on a chain re-signed with the key: %v", err)
+	}
+
+	filtered := FilterAuditEvents(events, AuditFilter{
+		SecretID: "KEY",
+		Since:    time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC),
+		Until:    time.Date(2026, 1, 2, 12, 0, 0, 0, time.UTC),
+	})
+	if len(filtered) != 1 || filtered[0].Action != AuditGet {
+		t.Errorf("FilterAuditEvents: got %+v, want only the get", filtered)
+	}
+}
+
+func TestAuditChainLegacyEvents(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMemoryEggRepository()
+	key := newAuditKey(t, "audit.key")
+
+	// Events from before the chain was keyed carry no MAC
+	for i := range 2 {
+		event := AuditEvent{Vault: "alice", Seq: i + 1, Actor: "alice", Action: AuditGet, Outcome: OutcomeSuccess}
+		if i > 0 {
+			last, _ := repo.LastAuditEvent(ctx, "alice")
+			event.PrevHash = last.Hash
+		}
+		event.Hash = event.computeHash()
+		if err := repo.PutAuditEvent(ctx, event); err != nil {
+			t.Fatal(err)
+		}
+	}
+	legacy, _ := repo.ListAuditEvents(ctx, "alice")
+	if err := VerifyAuditChain(ctx, key, legacy); err == nil {
+		t.Error("VerifyAuditChain authenticated a chain with no MAC at all")
+	}
+
+	// The first signed event vouches for them
+	if _, err := AppendAuditEvent(ctx, repo, key, AuditEvent{Vault: "alice", Actor: "alice", Action: AuditPut, Outcome: OutcomeSuccess}); err != nil {
+		t.Fatal(err)
+	}
+	events, _ := repo.ListAuditEvents(ctx, "alice")
+	if err := VerifyAuditChain(ctx, key, events); err != nil {
+		t.Fatalf("VerifyAuditChain on a keyed chain with legacy events: %v", err)
+	}
+	edited := append([]AuditEvent(nil), events...)
+	edited[0].Outcome = OutcomeDenied
+	if err := VerifyAuditChain(ctx, key, relink(t, edited, 0, nil)); err == nil {
+		t.Error("VerifyAuditChain accepted legacy events rewritten without the key")
+	}
+
+	// Nothing unsigned may follow a signed event
+	appended := append(events, AuditEvent{Vault: "alice", Seq: 4, Actor: "mallory", Action: AuditGet, Outcome: OutcomeSuccess})
+	if err := VerifyAuditChain(ctx, key, relink(t, appended, 3, nil)); err == nil {
+		t.Error("VerifyAuditChain accepted an unsigned event after a signed one")
+	}
+}
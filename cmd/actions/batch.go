@@ -0,0 +1,23 @@
+package actions
+
+import "context"
+
+// The local backends have no round trips to save, so their batch writes are
+// the single writes in a loop.
+
+func putEggsOneByOne(ctx context.Context, repo EggActions, eggs []Egg, expected []int) ([]Egg, []error) {
+	stored := make([]Egg, len(eggs))
+	errs := make([]error, len(eggs))
+	for i, egg := range eggs {
+		stored[i], errs[i] = repo.PutEgg(ctx, egg, expected[i])
+	}
+	return stored, errs
+}
+
+func breakEggsOneByOne(ctx context.Context, repo EggActions, owner string, secretIDs []string) []error {
+	errs := make([]error, len(secretIDs))
+	for i, secretID := range secretIDs {
+		errs[i] = repo.BreakEgg(ctx, owner, secretID, AnyVersion)
+	}
+	return errs
+}
@@ -0,0 +1,488 @@
+package actions
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"strconv"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+	bolterrors "go.etcd.io/bbolt/errors"
+)
+
+// eggsBucket is the top-level bucket; each owner gets a nested bucket keyed
+// by SecretID, mirroring the DynamoDB partition/sort key layout. History goes
+// in versionsBucket, one nested bucket per versionPartition. teamsBucket holds
+// one nested bucket of members per team, and auditBucket one nested bucket of
+// events per vault, keyed by auditSortKey.
+var (
+	eggsBucket     = []byte("eggs")
+	versionsBucket = []byte("versions")
+	teamsBucket    = []byte("teams")
+	auditBucket    = []byte("audit")
+)
+
+// BoltEggRepository is an EggActions implementation backed by a single bbolt
+// database file, for running egg-carton without an AWS account.
+type BoltEggRepository struct {
+	db *bolt.DB
+}
+
+func NewBoltEggRepository(path string) (*BoltEggRepository, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		log.Printf("Couldn't open bolt database %v. Here's why: %v\n", path, err)
+		return nil, err
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, bucket := range [][]byte{eggsBucket, versionsBucket, teamsBucket, auditBucket} {
+			if _, err := tx.CreateBucketIfNotExists(bucket); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &BoltEggRepository{db: db}, nil
+}
+
+// Close releases the file lock on the underlying database.
+func (r *BoltEggRepository) Close() error {
+	return r.db.Close()
+}
+
+func (r *BoltEggRepository) GetEgg(ctx context.Context, owner, secretID string) (Egg, error) {
+	var egg Egg
+	err := r.db.View(func(tx *bolt.Tx) error {
+		var err error
+		egg, err = getBoltEgg(tx, owner, secretID)
+		return err
+	})
+	return egg, err
+}
+
+func getBoltEgg(tx *bolt.Tx, owner, secretID string) (Egg, error) {
+	var egg Egg
+	ownerBucket := tx.Bucket(eggsBucket).Bucket([]byte(owner))
+	if ownerBucket == nil {
+		return egg, ErrEggNotFound
+	}
+	value := ownerBucket.Get([]byte(secretID))
+	if value == nil {
+		return egg, ErrEggNotFound
+	}
+	err := json.Unmarshal(value, &egg)
+	return egg, err
+}
+
+func (r *BoltEggRepository) GetAllEggs(ctx context.Context, owner string) ([]Egg, error) {
+	var eggs []Egg
+	err := r.db.View(func(tx *bolt.Tx) error {
+		ownerBucket := tx.Bucket(eggsBucket).Bucket([]byte(owner))
+		if ownerBucket == nil {
+			return nil
+		}
+		return ownerBucket.ForEach(func(_, value []byte) error {
+			var egg Egg
+			if err := json.Unmarshal(value, &egg); err != nil {
+				return err
+			}
+			eggs = append(eggs, egg)
+			return nil
+		})
+	})
+	if err != nil {
+		log.Printf("Couldn't get eggs for %v. Here's why: %v\n", owner, err)
+	}
+	return eggs, err
+}
+
+func (r *BoltEggRepository) GetEggsWithPrefix(ctx context.Context, owner, prefix string) ([]Egg, error) {
+	var eggs []Egg
+	err := r.db.View(func(tx *bolt.Tx) error {
+		ownerBucket := tx.Bucket(eggsBucket).Bucket([]byte(owner))
+		if ownerBucket == nil {
+			return nil
+		}
+		// Keys are sorted, so the matches are one contiguous run
+		c := ownerBucket.Cursor()
+		for key, value := c.Seek([]byte(prefix)); key != nil && bytes.HasPrefix(key, []byte(prefix)); key, value = c.Next() {
+			var egg Egg
+			if err := json.Unmarshal(value, &egg); err != nil {
+				return err
+			}
+			eggs = append(eggs, egg)
+		}
+		return nil
+	})
+	if err != nil {
+		log.Printf("Couldn't get eggs for %v with prefix %v. Here's why: %v\n", owner, prefix, err)
+	}
+	return eggs, err
+}
+
+func (r *BoltEggRepository) ListEggs(ctx context.Context, owner, prefix, cursor string, limit int) ([]Egg, string, error) {
+	eggs, err := r.GetEggsWithPrefix(ctx, owner, prefix)
+	if err != nil {
+		return nil, "", err
+	}
+	return pageOf(eggs, cursor, limit)
+}
+
+func (r *BoltEggRepository) PutEgg(ctx context.Context, egg Egg, expected int) (Egg, error) {
+	err := r.db.Update(func(tx *bolt.Tx) error {
+		ownerBucket, err := tx.Bucket(eggsBucket).CreateBucketIfNotExists([]byte(egg.Owner))
+		if err != nil {
+			return err
+		}
+		historyBucket, err := tx.Bucket(versionsBucket).CreateBucketIfNotExists(
+			[]byte(versionPartition(egg.Owner, egg.SecretID)))
+		if err != nil {
+			return err
+		}
+
+		current, err := getBoltEgg(tx, egg.Owner, egg.SecretID)
+		if err != nil && !errors.Is(err, ErrEggNotFound) {
+			return err
+		}
+		if expected != AnyVersion && currentVersion(current, err == nil) != expected {
+			return ErrEggConflict
+		}
+		egg.Version = current.Version + 1
+		if key, _ := historyBucket.Cursor().Last(); key != nil {
+			// Older versions can outlive an expired current row
+			latest, err := strconv.Atoi(string(key))
+			if err != nil {
+				return err
+			}
+			egg.Version = max(egg.Version, latest+1)
+		}
+
+		value, err := json.Marshal(egg)
+		if err != nil {
+			return err
+		}
+		if err := ownerBucket.Put([]byte(egg.SecretID), value); err != nil {
+			return err
+		}
+		return historyBucket.Put([]byte(versionSortKey(egg.Version)), value)
+	})
+	if err != nil {
+		log.Printf("Couldn't put an item. Here's why: %v\n", err)
+	}
+	return egg, err
+}
+
+func (r *BoltEggRepository) BreakEgg(ctx context.Context, owner, secretID string, expected int) error {
+	err := r.db.Update(func(tx *bolt.Tx) error {
+		if expected != AnyVersion {
+			current, err := getBoltEgg(tx, owner, secretID)
+			if err != nil && !errors.Is(err, ErrEggNotFound) {
+				return err
+			}
+			if err != nil || currentVersion(current, true) != expected {
+				return ErrEggConflict
+			}
+		}
+		err := tx.Bucket(versionsBucket).DeleteBucket([]byte(versionPartition(owner, secretID)))
+		if err != nil && !errors.Is(err, bolterrors.ErrBucketNotFound) {
+			return err
+		}
+		ownerBucket := tx.Bucket(eggsBucket).Bucket([]byte(owner))
+		if ownerBucket == nil {
+			return nil
+		}
+		return ownerBucket.Delete([]byte(secretID))
+	})
+	if err != nil {
+		log.Printf("Couldn't delete that egg from the database. Here's why: %v\n", err)
+	}
+	return err
+}
+
+func (r *BoltEggRepository) PutEggs(ctx context.Context, eggs []Egg, expected []int) ([]Egg, []error) {
+	return putEggsOneByOne(ctx, r, eggs, expected)
+}
+
+func (r *BoltEggRepository) BreakEggs(ctx context.Context, owner string, secretIDs []string) []error {
+	return breakEggsOneByOne(ctx, r, owner, secretIDs)
+}
+
+func (r *BoltEggRepository) ListEggVersions(ctx context.Context, owner, secretID string) ([]Egg, error) {
+	var eggs []Egg
+	err := r.db.View(func(tx *bolt.Tx) error {
+		historyBucket := tx.Bucket(versionsBucket).Bucket([]byte(versionPartition(owner, secretID)))
+		if historyBucket == nil {
+			return nil
+		}
+		return historyBucket.ForEach(func(_, value []byte) error {
+			var egg Egg
+			if err := json.Unmarshal(value, &egg); err != nil {
+				return err
+			}
+			egg.History = true
+			eggs = append(eggs, egg)
+			return nil
+		})
+	})
+	if err != nil {
+		log.Printf("Couldn't get versions of %v. Here's why: %v\n", secretID, err)
+	}
+	return eggs, err
+}
+
+func (r *BoltEggRepository) GetEggVersion(ctx context.Context, owner, secretID string, version int) (Egg, error) {
+	var egg Egg
+	err := r.db.View(func(tx *bolt.Tx) error {
+		historyBucket := tx.Bucket(versionsBucket).Bucket([]byte(versionPartition(owner, secretID)))
+		if historyBucket == nil {
+			return ErrEggNotFound
+		}
+		value := historyBucket.Get([]byte(versionSortKey(version)))
+		if value == nil {
+			return ErrEggNotFound
+		}
+		return json.Unmarshal(value, &egg)
+	})
+	egg.History = true
+	return egg, err
+}
+
+func (r *BoltEggRepository) ScanEggs(ctx context.Context, cursor string, limit int) ([]Egg, string, error) {
+	var all []Egg
+	err := r.db.View(func(tx *bolt.Tx) error {
+		// Current rows by owner and SecretID, then history by partition
+		for _, top := range [][]byte{eggsBucket, versionsBucket} {
+			history := bytes.Equal(top, versionsBucket)
+			err := tx.Bucket(top).ForEachBucket(func(name []byte) error {
+				return tx.Bucket(top).Bucket(name).ForEach(func(_, value []byte) error {
+					var egg Egg
+					if err := json.Unmarshal(value, &egg); err != nil {
+						return err
+					}
+					egg.History = history
+					all = append(all, egg)
+					return nil
+				})
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		log.Printf("Couldn't scan the database. Here's why: %v\n", err)
+		return nil, "", err
+	}
+	return pageOf(all, cursor, limit)
+}
+
+func (r *BoltEggRepository) UpdateEgg(ctx context.Context, egg Egg) error {
+	value, err := json.Marshal(egg)
+	if err != nil {
+		return err
+	}
+
+	return r.db.Update(func(tx *bolt.Tx) error {
+		if egg.History {
+			historyBucket := tx.Bucket(versionsBucket).Bucket([]byte(versionPartition(egg.Owner, egg.SecretID)))
+			key := []byte(versionSortKey(egg.Version))
+			if historyBucket == nil || historyBucket.Get(key) == nil {
+				return ErrEggConflict
+			}
+			return historyBucket.Put(key, value)
+		}
+
+		ownerBucket := tx.Bucket(eggsBucket).Bucket([]byte(egg.Owner))
+		if ownerBucket == nil {
+			return ErrEggConflict
+		}
+		existing := ownerBucket.Get([]byte(egg.SecretID))
+		if existing == nil {
+			return ErrEggConflict
+		}
+		var current Egg
+		if err := json.Unmarshal(existing, &current); err != nil {
+			return err
+		}
+		if current.Version != egg.Version {
+			return ErrEggConflict
+		}
+		return ownerBucket.Put([]byte(egg.SecretID), value)
+	})
+}
+
+func (r *BoltEggRepository) CreateTeam(ctx context.Context, admin TeamMember) error {
+	return r.db.Update(func(tx *bolt.Tx) error {
+		teamBucket, err := tx.Bucket(teamsBucket).CreateBucket([]byte(admin.Team))
+		if errors.Is(err, bolterrors.ErrBucketExists) {
+			return ErrTeamExists
+		}
+		if err != nil {
+			return err
+		}
+		return putJSON(teamBucket, admin.Member, admin)
+	})
+}
+
+func (r *BoltEggRepository) GetTeamMember(ctx context.Context, team, member string) (TeamMember, error) {
+	var m TeamMember
+	err := r.db.View(func(tx *bolt.Tx) error {
+		teamBucket := tx.Bucket(teamsBucket).Bucket([]byte(team))
+		if teamBucket == nil {
+			return ErrMemberNotFound
+		}
+		value := teamBucket.Get([]byte(member))
+		if value == nil {
+			return ErrMemberNotFound
+		}
+		return json.Unmarshal(value, &m)
+	})
+	return m, err
+}
+
+func (r *BoltEggRepository) ListTeamMembers(ctx context.Context, team string) ([]TeamMember, error) {
+	var members []TeamMember
+	err := r.db.View(func(tx *bolt.Tx) error {
+		var err error
+		members, err = boltTeamMembers(tx, team)
+		return err
+	})
+	return members, err
+}
+
+func boltTeamMembers(tx *bolt.Tx, team string) ([]TeamMember, error) {
+	var members []TeamMember
+	teamBucket := tx.Bucket(teamsBucket).Bucket([]byte(team))
+	if teamBucket == nil {
+		return nil, nil
+	}
+	err := teamBucket.ForEach(func(_, value []byte) error {
+		var m TeamMember
+		if err := json.Unmarshal(value, &m); err != nil {
+			return err
+		}
+		members = append(members, m)
+		return nil
+	})
+	return members, err
+}
+
+func (r *BoltEggRepository) PutTeamMember(ctx context.Context, m TeamMember) error {
+	return r.db.Update(func(tx *bolt.Tx) error {
+		members, err := boltTeamMembers(tx, m.Team)
+		if err != nil {
+			return err
+		}
+		if leavesNoAdmin(members, m.Member, m.Role) {
+			return ErrLastAdmin
+		}
+		teamBucket, err := tx.Bucket(teamsBucket).CreateBucketIfNotExists([]byte(m.Team))
+		if err != nil {
+			return err
+		}
+		return putJSON(teamBucket, m.Member, m)
+	})
+}
+
+func (r *BoltEggRepository) RemoveTeamMember(ctx context.Context, team, member string) error {
+	return r.db.Update(func(tx *bolt.Tx) error {
+		members, err := boltTeamMembers(tx, team)
+		if err != nil {
+			return err
+		}
+		if leavesNoAdmin(members, member, "") {
+			return ErrLastAdmin
+		}
+		teamBucket := tx.Bucket(teamsBucket).Bucket([]byte(team))
+		if teamBucket == nil {
+			return nil
+		}
+		return teamBucket.Delete([]byte(member))
+	})
+}
+
+func (r *BoltEggRepository) ListMemberTeams(ctx context.Context, member string) ([]TeamMember, error) {
+	var teams []TeamMember
+	err := r.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(teamsBucket).ForEachBucket(func(team []byte) error {
+			value := tx.Bucket(teamsBucket).Bucket(team).Get([]byte(member))
+			if value == nil {
+				return nil
+			}
+			var m TeamMember
+			if err := json.Unmarshal(value, &m); err != nil {
+				return err
+			}
+			teams = append(teams, m)
+			return nil
+		})
+	})
+	return teams, err
+}
+
+func (r *BoltEggRepository) PutAuditEvent(ctx context.Context, event AuditEvent) error {
+	return r.db.Update(func(tx *bolt.Tx) error {
+		vaultBucket, err := tx.Bucket(auditBucket).CreateBucketIfNotExists([]byte(event.Vault))
+		if err != nil {
+			return err
+		}
+		key := auditSortKey(event.Seq)
+		if vaultBucket.Get([]byte(key)) != nil {
+			return ErrAuditConflict
+		}
+		return putJSON(vaultBucket, key, event)
+	})
+}
+
+func (r *BoltEggRepository) LastAuditEvent(ctx context.Context, vault string) (AuditEvent, error) {
+	var event AuditEvent
+	err := r.db.View(func(tx *bolt.Tx) error {
+		vaultBucket := tx.Bucket(auditBucket).Bucket([]byte(vault))
+		if vaultBucket == nil {
+			return nil
+		}
+		_, value := vaultBucket.Cursor().Last()
+		if value == nil {
+			return nil
+		}
+		return json.Unmarshal(value, &event)
+	})
+	return event, err
+}
+
+func (r *BoltEggRepository) ListAuditEvents(ctx context.Context, vault string) ([]AuditEvent, error) {
+	var events []AuditEvent
+	err := r.db.View(func(tx *bolt.Tx) error {
+		vaultBucket := tx.Bucket(auditBucket).Bucket([]byte(vault))
+		if vaultBucket == nil {
+			return nil
+		}
+		return vaultBucket.ForEach(func(_, value []byte) error {
+			var event AuditEvent
+			if err := json.Unmarshal(value, &event); err != nil {
+				return err
+			}
+			events = append(events, event)
+			return nil
+		})
+	})
+	return events, err
+}
+
+func putJSON(bucket *bolt.Bucket, key string, v any) error {
+	value, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return bucket.Put([]byte(key), value)
+}
@@ -0,0 +1,800 @@
+package actions
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	bolt "go.etcd.io/bbolt"
+)
+
+// runConformance exercises the EggActions contract that every storage
+// backend must satisfy. newRepo must return an empty store.
+func runConformance(t *testing.T, newRepo func(t *testing.T) EggActions) {
+	ctx := context.Background()
+	newEgg := func(owner, secretID, value string) Egg {
+		return Egg{
+			Owner:            owner,
+			SecretID:         secretID,
+			Ciphertext:       []byte(value),
+			EncryptedDataKey: []byte("key-" + value),
+			CreatedAt:        time.Now().UTC().Format(time.RFC3339),
+		}
+	}
+
+	t.Run("PutThenGetAll", func(t *testing.T) {
+		repo := newRepo(t)
+		for _, egg := range []Egg{
+			newEgg("alice", "B_KEY", "b"),
+			newEgg("alice", "A_KEY", "a"),
+			newEgg("bob", "A_KEY", "bob"),
+		} {
+			if _, err := repo.PutEgg(ctx, egg, AnyVersion); err != nil {
+				t.Fatalf("PutEgg: %v", err)
+			}
+		}
+
+		eggs, err := repo.GetAllEggs(ctx, "alice")
+		if err != nil {
+			t.Fatalf("GetAllEggs: %v", err)
+		}
+		if len(eggs) != 2 {
+			t.Fatalf("got %d eggs, want 2", len(eggs))
+		}
+		if eggs[0].SecretID != "A_KEY" || eggs[1].SecretID != "B_KEY" {
+			t.Errorf("eggs not ordered by SecretID: %q, %q", eggs[0].SecretID, eggs[1].SecretID)
+		}
+		if !bytes.Equal(eggs[0].Ciphertext, []byte("a")) || !bytes.Equal(eggs[0].EncryptedDataKey, []byte("key-a")) {
+			t.Errorf("egg round trip mismatch: %+v", eggs[0])
+		}
+		if eggs[0].Owner != "alice" || eggs[0].CreatedAt == "" {
+			t.Errorf("egg attributes not preserved: %+v", eggs[0])
+		}
+	})
+
+	t.Run("GetAllEmptyOwner", func(t *testing.T) {
+		repo := newRepo(t)
+		eggs, err := repo.GetAllEggs(ctx, "nobody")
+		if err != nil {
+			t.Fatalf("GetAllEggs: %v", err)
+		}
+		if len(eggs) != 0 {
+			t.Fatalf("got %d eggs, want 0", len(eggs))
+		}
+	})
+
+	t.Run("GetEgg", func(t *testing.T) {
+		repo := newRepo(t)
+		if _, err := repo.GetEgg(ctx, "alice", "A_KEY"); !errors.Is(err, ErrEggNotFound) {
+			t.Fatalf("GetEgg on empty owner: got %v, want ErrEggNotFound", err)
+		}
+		for _, egg := range []Egg{newEgg("alice", "A_KEY", "a"), newEgg("alice", "B_KEY", "b")} {
+			if _, err := repo.PutEgg(ctx, egg, AnyVersion); err != nil {
+				t.Fatalf("PutEgg: %v", err)
+			}
+		}
+		egg, err := repo.GetEgg(ctx, "alice", "B_KEY")
+		if err != nil {
+			t.Fatalf("GetEgg: %v", err)
+		}
+		if egg.SecretID != "B_KEY" || string(egg.Ciphertext) != "b" || egg.Version != 1 {
+			t.Errorf("got %+v, want B_KEY holding b at version 1", egg)
+		}
+		if _, err := repo.GetEgg(ctx, "alice", "C_KEY"); !errors.Is(err, ErrEggNotFound) {
+			t.Errorf("GetEgg on missing key: got %v, want ErrEggNotFound", err)
+		}
+		if _, err := repo.GetEgg(ctx, "bob", "A_KEY"); !errors.Is(err, ErrEggNotFound) {
+			t.Errorf("GetEgg on another owner: got %v, want ErrEggNotFound", err)
+		}
+	})
+
+	t.Run("PutOverwrites", func(t *testing.T) {
+		repo := newRepo(t)
+		if _, err := repo.PutEgg(ctx, newEgg("alice", "KEY", "old"), AnyVersion); err != nil {
+			t.Fatalf("PutEgg: %v", err)
+		}
+		if _, err := repo.PutEgg(ctx, newEgg("alice", "KEY", "new"), AnyVersion); err != nil {
+			t.Fatalf("PutEgg: %v", err)
+		}
+		eggs, err := repo.GetAllEggs(ctx, "alice")
+		if err != nil {
+			t.Fatalf("GetAllEggs: %v", err)
+		}
+		if len(eggs) != 1 || string(eggs[0].Ciphertext) != "new" {
+			t.Fatalf("got %+v, want a single egg holding the new value", eggs)
+		}
+	})
+
+	t.Run("BreakEgg", func(t *testing.T) {
+		repo := newRepo(t)
+		if _, err := repo.PutEgg(ctx, newEgg("alice", "KEEP", "k"), AnyVersion); err != nil {
+			t.Fatalf("PutEgg: %v", err)
+		}
+		if _, err := repo.PutEgg(ctx, newEgg("alice", "DROP", "d"), AnyVersion); err != nil {
+			t.Fatalf("PutEgg: %v", err)
+		}
+		if err := repo.BreakEgg(ctx, "alice", "DROP", AnyVersion); err != nil {
+			t.Fatalf("BreakEgg: %v", err)
+		}
+		// Breaking a missing egg is not an error
+		if err := repo.BreakEgg(ctx, "alice", "DROP", AnyVersion); err != nil {
+			t.Fatalf("BreakEgg twice: %v", err)
+		}
+		eggs, err := repo.GetAllEggs(ctx, "alice")
+		if err != nil {
+			t.Fatalf("GetAllEggs: %v", err)
+		}
+		if len(eggs) != 1 || eggs[0].SecretID != "KEEP" {
+			t.Fatalf("got %+v, want only KEEP", eggs)
+		}
+		versions, err := repo.ListEggVersions(ctx, "alice", "DROP")
+		if err != nil {
+			t.Fatalf("ListEggVersions: %v", err)
+		}
+		if len(versions) != 0 {
+			t.Fatalf("got %d versions of a broken egg, want 0", len(versions))
+		}
+	})
+
+	t.Run("Preconditions", func(t *testing.T) {
+		repo := newRepo(t)
+		if _, err := repo.PutEgg(ctx, newEgg("alice", "KEY", "v1"), NoVersion); err != nil {
+			t.Fatalf("PutEgg create-only on a new secret: %v", err)
+		}
+		if _, err := repo.PutEgg(ctx, newEgg("alice", "KEY", "again"), NoVersion); !errors.Is(err, ErrEggConflict) {
+			t.Fatalf("PutEgg create-only on an existing secret: got %v, want ErrEggConflict", err)
+		}
+		if _, err := repo.PutEgg(ctx, newEgg("alice", "KEY", "v2"), 1); err != nil {
+			t.Fatalf("PutEgg at the current version: %v", err)
+		}
+		if _, err := repo.PutEgg(ctx, newEgg("alice", "KEY", "stale"), 1); !errors.Is(err, ErrEggConflict) {
+			t.Fatalf("PutEgg at a stale version: got %v, want ErrEggConflict", err)
+		}
+		if err := repo.BreakEgg(ctx, "alice", "KEY", 1); !errors.Is(err, ErrEggConflict) {
+			t.Fatalf("BreakEgg at a stale version: got %v, want ErrEggConflict", err)
+		}
+		if err := repo.BreakEgg(ctx, "alice", "KEY", 2); err != nil {
+			t.Fatalf("BreakEgg at the current version: %v", err)
+		}
+		if err := repo.BreakEgg(ctx, "alice", "KEY", 2); !errors.Is(err, ErrEggConflict) {
+			t.Fatalf("BreakEgg of a missing secret at a version: got %v, want ErrEggConflict", err)
+		}
+	})
+
+	t.Run("BreakLegacyEgg", func(t *testing.T) {
+		repo := newRepo(t)
+		putLegacyRow(t, repo, newEgg("alice", "OLD", "o"))
+		if err := repo.BreakEgg(ctx, "alice", "OLD", 2); !errors.Is(err, ErrEggConflict) {
+			t.Fatalf("BreakEgg of a legacy egg at version 2: got %v, want ErrEggConflict", err)
+		}
+		if err := repo.BreakEgg(ctx, "alice", "OLD", 1); err != nil {
+			t.Fatalf("BreakEgg of a legacy egg at version 1: %v", err)
+		}
+		if _, err := repo.GetEgg(ctx, "alice", "OLD"); !errors.Is(err, ErrEggNotFound) {
+			t.Fatalf("GetEgg after BreakEgg: got %v, want ErrEggNotFound", err)
+		}
+	})
+
+	t.Run("Batch", func(t *testing.T) {
+		repo := newRepo(t)
+		if _, err := repo.PutEgg(ctx, newEgg("alice", "TAKEN", "old"), AnyVersion); err != nil {
+			t.Fatalf("PutEgg: %v", err)
+		}
+
+		eggs := []Egg{newEgg("alice", "NEW", "n"), newEgg("alice", "TAKEN", "t"), newEgg("alice", "OVER", "o")}
+		stored, errs := repo.PutEggs(ctx, eggs, []int{NoVersion, NoVersion, AnyVersion})
+		if errs[0] != nil || errs[2] != nil || !errors.Is(errs[1], ErrEggConflict) {
+			t.Fatalf("PutEggs: got %v, want only TAKEN to conflict", errs)
+		}
+		if stored[0].Version != 1 || stored[2].Version != 1 {
+			t.Fatalf("PutEggs stored versions %d and %d, want 1", stored[0].Version, stored[2].Version)
+		}
+		if egg, err := repo.GetEgg(ctx, "alice", "TAKEN"); err != nil || string(egg.Ciphertext) != "old" {
+			t.Fatalf("the conflicting egg was overwritten: %+v, %v", egg, err)
+		}
+
+		for i, err := range repo.BreakEggs(ctx, "alice", []string{"NEW", "TAKEN", "MISSING"}) {
+			if err != nil {
+				t.Fatalf("BreakEggs item %d: %v", i, err)
+			}
+		}
+		remaining, err := repo.GetAllEggs(ctx, "alice")
+		if err != nil {
+			t.Fatalf("GetAllEggs: %v", err)
+		}
+		if len(remaining) != 1 || remaining[0].SecretID != "OVER" {
+			t.Fatalf("got %+v, want only OVER left", remaining)
+		}
+		if versions, _ := repo.ListEggVersions(ctx, "alice", "TAKEN"); len(versions) != 0 {
+			t.Fatalf("got %d versions of a broken egg, want 0", len(versions))
+		}
+	})
+
+	t.Run("Versions", func(t *testing.T) {
+		repo := newRepo(t)
+		for i, value := range []string{"v1", "v2", "v3"} {
+			stored, err := repo.PutEgg(ctx, newEgg("alice", "KEY", value), AnyVersion)
+			if err != nil {
+				t.Fatalf("PutEgg: %v", err)
+			}
+			if stored.Version != i+1 {
+				t.Fatalf("PutEgg returned version %d, want %d", stored.Version, i+1)
+			}
+		}
+
+		versions, err := repo.ListEggVersions(ctx, "alice", "KEY")
+		if err != nil {
+			t.Fatalf("ListEggVersions: %v", err)
+		}
+		if len(versions) != 3 {
+			t.Fatalf("got %d versions, want 3", len(versions))
+		}
+		for i, version := range versions {
+			if version.Version != i+1 || version.Owner != "alice" || version.SecretID != "KEY" {
+				t.Errorf("version %d: got %+v", i+1, version)
+			}
+		}
+
+		first, err := repo.GetEggVersion(ctx, "alice", "KEY", 1)
+		if err != nil {
+			t.Fatalf("GetEggVersion: %v", err)
+		}
+		if string(first.Ciphertext) != "v1" {
+			t.Errorf("version 1 holds %q, want v1", first.Ciphertext)
+		}
+		if _, err := repo.GetEggVersion(ctx, "alice", "KEY", 9); !errors.Is(err, ErrEggNotFound) {
+			t.Errorf("GetEggVersion(9): got %v, want ErrEggNotFound", err)
+		}
+
+		restored, err := RestoreEggVersion(ctx, repo, "alice", "KEY", 1, "bob")
+		if err != nil {
+			t.Fatalf("RestoreEggVersion: %v", err)
+		}
+		if restored.Version != 4 || restored.CreatedBy != "bob" {
+			t.Errorf("restored egg: got version %d by %q, want 4 by bob", restored.Version, restored.CreatedBy)
+		}
+		eggs, err := repo.GetAllEggs(ctx, "alice")
+		if err != nil {
+			t.Fatalf("GetAllEggs: %v", err)
+		}
+		if len(eggs) != 1 || string(eggs[0].Ciphertext) != "v1" || eggs[0].Version != 4 {
+			t.Fatalf("got %+v, want current value v1 at version 4", eggs)
+		}
+	})
+	t.Run("ScanEggs", func(t *testing.T) {
+		repo := newRepo(t)
+		for _, egg := range []Egg{
+			newEgg("alice", "A_KEY", "a1"),
+			newEgg("alice", "A_KEY", "a2"),
+			newEgg("bob", "B_KEY", "b1"),
+		} {
+			if _, err := repo.PutEgg(ctx, egg, AnyVersion); err != nil {
+				t.Fatalf("PutEgg: %v", err)
+			}
+		}
+
+		var current, history int
+		cursor := ""
+		for pages := 0; ; pages++ {
+			if pages > 10 {
+				t.Fatal("ScanEggs never returned an empty cursor")
+			}
+			eggs, next, err := repo.ScanEggs(ctx, cursor, 2)
+			if err != nil {
+				t.Fatalf("ScanEggs: %v", err)
+			}
+			for _, egg := range eggs {
+				if egg.History {
+					history++
+				} else {
+					current++
+				}
+			}
+			if next == "" {
+				break
+			}
+			cursor = next
+		}
+		// Two current rows, plus three history rows (A_KEY v1, v2 and B_KEY v1)
+		if current != 2 || history != 3 {
+			t.Fatalf("scanned %d current and %d history rows, want 2 and 3", current, history)
+		}
+	})
+
+	t.Run("GetEggsWithPrefix", func(t *testing.T) {
+		repo := newRepo(t)
+		prod := Namespace{Project: "api", Env: "prod"}
+		dev := Namespace{Project: "api", Env: "dev"}
+		for _, egg := range []Egg{
+			newEgg("alice", prod.SecretID("DATABASE_URL"), "prod-db"),
+			newEgg("alice", dev.SecretID("DATABASE_URL"), "dev-db"),
+			newEgg("alice", prod.SecretID("API_KEY"), "prod-key"),
+			newEgg("alice", "DATABASE_URL", "flat"),
+			newEgg("bob", prod.SecretID("DATABASE_URL"), "bob-db"),
+		} {
+			if _, err := repo.PutEgg(ctx, egg, AnyVersion); err != nil {
+				t.Fatalf("PutEgg: %v", err)
+			}
+		}
+
+		eggs, err := repo.GetEggsWithPrefix(ctx, "alice", prod.Prefix())
+		if err != nil {
+			t.Fatalf("GetEggsWithPrefix: %v", err)
+		}
+		if len(eggs) != 2 || eggs[0].SecretID != "api#prod#API_KEY" || eggs[1].SecretID != "api#prod#DATABASE_URL" {
+			t.Fatalf("got %+v, want alice's two prod eggs in order", eggs)
+		}
+		if ns, name := SplitSecretID(eggs[1].SecretID); ns != prod || name != "DATABASE_URL" {
+			t.Errorf("SplitSecretID = %+v, %q", ns, name)
+		}
+
+		if eggs, _ := repo.GetEggsWithPrefix(ctx, "alice", "staging#"); len(eggs) != 0 {
+			t.Errorf("got %d eggs for an empty namespace, want 0", len(eggs))
+		}
+	})
+
+	t.Run("ListEggs", func(t *testing.T) {
+		repo := newRepo(t)
+		prod := Namespace{Project: "api", Env: "prod"}
+		for _, name := range []string{"A", "B", "C", "D", "E"} {
+			if _, err := repo.PutEgg(ctx, newEgg("alice", prod.SecretID(name), name), AnyVersion); err != nil {
+				t.Fatalf("PutEgg: %v", err)
+			}
+		}
+		if _, err := repo.PutEgg(ctx, newEgg("alice", "FLAT", "flat"), AnyVersion); err != nil {
+			t.Fatalf("PutEgg: %v", err)
+		}
+
+		// Walk the namespace two at a time; every egg comes back once, in order
+		var names []string
+		cursor := ""
+		for pages := 0; ; pages++ {
+			if pages > 5 {
+				t.Fatal("ListEggs never returned an empty cursor")
+			}
+			eggs, next, err := repo.ListEggs(ctx, "alice", prod.Prefix(), cursor, 2)
+			if err != nil {
+				t.Fatalf("ListEggs: %v", err)
+			}
+			if len(eggs) > 2 {
+				t.Fatalf("got a page of %d eggs, want at most 2", len(eggs))
+			}
+			for _, egg := range eggs {
+				_, name := SplitSecretID(egg.SecretID)
+				names = append(names, name)
+			}
+			if next == "" {
+				break
+			}
+			cursor = next
+		}
+		if strings.Join(names, ",") != "A,B,C,D,E" {
+			t.Fatalf("walked %v, want A to E", names)
+		}
+
+		all, next, err := repo.ListEggs(ctx, "alice", "", "", 0)
+		if err != nil || len(all) != 6 || next != "" {
+			t.Fatalf("ListEggs of the whole vault: got %d eggs, %v", len(all), err)
+		}
+		if _, _, err := repo.ListEggs(ctx, "alice", "", "not a cursor", 2); !errors.Is(err, ErrInvalidCursor) {
+			t.Fatalf("ListEggs with a bad cursor: got %v, want ErrInvalidCursor", err)
+		}
+	})
+
+	t.Run("Teams", func(t *testing.T) {
+		repo := newRepo(t)
+		admin := TeamMember{Team: "backend", Member: "alice", Role: RoleAdmin, AddedAt: "2026-01-01T00:00:00Z"}
+		if err := repo.CreateTeam(ctx, admin); err != nil {
+			t.Fatalf("CreateTeam: %v", err)
+		}
+		if err := repo.CreateTeam(ctx, TeamMember{Team: "backend", Member: "mallory", Role: RoleAdmin}); !errors.Is(err, ErrTeamExists) {
+			t.Errorf("CreateTeam existing: got %v, want ErrTeamExists", err)
+		}
+
+		if err := repo.PutTeamMember(ctx, TeamMember{Team: "backend", Member: "bob", Role: RoleReader, AddedBy: "alice"}); err != nil {
+			t.Fatalf("PutTeamMember: %v", err)
+		}
+		if err := repo.PutTeamMember(ctx, TeamMember{Team: "backend", Member: "bob", Role: RoleWriter, AddedBy: "alice"}); err != nil {
+			t.Fatalf("PutTeamMember role change: %v", err)
+		}
+		bob, err := repo.GetTeamMember(ctx, "backend", "bob")
+		if err != nil || bob.Role != RoleWriter || bob.AddedBy != "alice" {
+			t.Errorf("GetTeamMember: got %+v, %v", bob, err)
+		}
+		if _, err := repo.GetTeamMember(ctx, "backend", "mallory"); !errors.Is(err, ErrMemberNotFound) {
+			t.Errorf("GetTeamMember missing: got %v, want ErrMemberNotFound", err)
+		}
+
+		members, err := repo.ListTeamMembers(ctx, "backend")
+		if err != nil || len(members) != 2 {
+			t.Errorf("ListTeamMembers: got %+v, %v", members, err)
+		}
+		if err := repo.CreateTeam(ctx, TeamMember{Team: "frontend", Member: "bob", Role: RoleAdmin}); err != nil {
+			t.Fatalf("CreateTeam: %v", err)
+		}
+		teams, err := repo.ListMemberTeams(ctx, "bob")
+		if err != nil || len(teams) != 2 || teams[0].Team != "backend" || teams[1].Team != "frontend" {
+			t.Errorf("ListMemberTeams: got %+v, %v", teams, err)
+		}
+
+		if err := repo.RemoveTeamMember(ctx, "backend", "bob"); err != nil {
+			t.Fatalf("RemoveTeamMember: %v", err)
+		}
+		if teams, _ := repo.ListMemberTeams(ctx, "bob"); len(teams) != 1 {
+			t.Errorf("ListMemberTeams after remove: got %+v", teams)
+		}
+		if eggs, _, err := repo.ScanEggs(ctx, "", 100); err != nil || len(eggs) != 0 {
+			t.Errorf("ScanEggs returned membership rows: %+v, %v", eggs, err)
+		}
+	})
+
+	t.Run("LastAdmin", func(t *testing.T) {
+		repo := newRepo(t)
+		if err := repo.CreateTeam(ctx, TeamMember{Team: "backend", Member: "alice", Role: RoleAdmin}); err != nil {
+			t.Fatalf("CreateTeam: %v", err)
+		}
+		if err := repo.RemoveTeamMember(ctx, "backend", "alice"); !errors.Is(err, ErrLastAdmin) {
+			t.Errorf("removing the last admin: got %v, want ErrLastAdmin", err)
+		}
+		if err := repo.PutTeamMember(ctx, TeamMember{Team: "backend", Member: "alice", Role: RoleWriter}); !errors.Is(err, ErrLastAdmin) {
+			t.Errorf("demoting the last admin: got %v, want ErrLastAdmin", err)
+		}
+
+		// Of two admins demoting each other at once, only one can win
+		if err := repo.PutTeamMember(ctx, TeamMember{Team: "backend", Member: "bob", Role: RoleAdmin}); err != nil {
+			t.Fatalf("PutTeamMember: %v", err)
+		}
+		errs := make(chan error, 2)
+		for _, member := range []string{"alice", "bob"} {
+			go func() {
+				errs <- repo.PutTeamMember(ctx, TeamMember{Team: "backend", Member: member, Role: RoleReader})
+			}()
+		}
+		var demoted int
+		for range 2 {
+			err := <-errs
+			if err == nil {
+				demoted++
+			} else if !errors.Is(err, ErrLastAdmin) && !errors.Is(err, ErrTeamConflict) {
+				t.Errorf("concurrent demotion: %v", err)
+			}
+		}
+		members, err := repo.ListTeamMembers(ctx, "backend")
+		if err != nil {
+			t.Fatalf("ListTeamMembers: %v", err)
+		}
+		admins := 0
+		for _, m := range members {
+			if m.Role == RoleAdmin {
+				admins++
+			}
+		}
+		if demoted != 1 || admins != 1 {
+			t.Errorf("concurrent demotions: %d succeeded leaving %d admins, want 1 and 1", demoted, admins)
+		}
+	})
+
+	t.Run("UpdateEgg", func(t *testing.T) {
+		repo := newRepo(t)
+		stored, err := repo.PutEgg(ctx, newEgg("alice", "KEY", "old"), AnyVersion)
+		if err != nil {
+			t.Fatalf("PutEgg: %v", err)
+		}
+
+		stored.Ciphertext = []byte("rewrapped")
+		stored.Bound = true
+		stored.KeyID = "new-key"
+		if err := repo.UpdateEgg(ctx, stored); err != nil {
+			t.Fatalf("UpdateEgg current: %v", err)
+		}
+		history, err := repo.GetEggVersion(ctx, "alice", "KEY", 1)
+		if err != nil {
+			t.Fatalf("GetEggVersion: %v", err)
+		}
+		history.Ciphertext = []byte("rewrapped")
+		history.Bound = true
+		history.KeyID = "new-key"
+		if err := repo.UpdateEgg(ctx, history); err != nil {
+			t.Fatalf("UpdateEgg history: %v", err)
+		}
+
+		current, err := repo.GetEgg(ctx, "alice", "KEY")
+		if err != nil {
+			t.Fatalf("GetEgg: %v", err)
+		}
+		if string(current.Ciphertext) != "rewrapped" || !current.Bound || current.KeyID != "new-key" || current.Version != 1 {
+			t.Errorf("current row: got %+v, want rewrapped and bound at version 1", current)
+		}
+		if versions, _ := repo.ListEggVersions(ctx, "alice", "KEY"); len(versions) != 1 || !versions[0].Bound || versions[0].KeyID != "new-key" {
+			t.Errorf("history: got %+v, want one bound version", versions)
+		}
+
+		// A stale version must not overwrite a newer write
+		if _, err := repo.PutEgg(ctx, newEgg("alice", "KEY", "newer"), AnyVersion); err != nil {
+			t.Fatalf("PutEgg: %v", err)
+		}
+		if err := repo.UpdateEgg(ctx, stored); !errors.Is(err, ErrEggConflict) {
+			t.Errorf("UpdateEgg with stale version: got %v, want ErrEggConflict", err)
+		}
+	})
+
+	t.Run("Expiry", func(t *testing.T) {
+		repo := newRepo(t)
+		expiring := newEgg("alice", "TOKEN", "t")
+		expiring.ExpiresAt = time.Now().Add(time.Hour).Unix()
+		if _, err := repo.PutEgg(ctx, expiring, AnyVersion); err != nil {
+			t.Fatalf("PutEgg: %v", err)
+		}
+
+		got, err := repo.GetEgg(ctx, "alice", "TOKEN")
+		if err != nil {
+			t.Fatalf("GetEgg: %v", err)
+		}
+		if got.ExpiresAt != expiring.ExpiresAt {
+			t.Errorf("GetEgg: got ExpiresAt %d, want %d", got.ExpiresAt, expiring.ExpiresAt)
+		}
+		if version, err := repo.GetEggVersion(ctx, "alice", "TOKEN", 1); err != nil || version.ExpiresAt != expiring.ExpiresAt {
+			t.Errorf("GetEggVersion: got %+v, %v, want ExpiresAt %d", version, err, expiring.ExpiresAt)
+		}
+		if !got.Expired(time.Now().Add(2*time.Hour)) || got.Expired(time.Now()) {
+			t.Errorf("Expired: want false now and true in two hours")
+		}
+	})
+
+	t.Run("RelayAfterExpiry", func(t *testing.T) {
+		repo := newRepo(t)
+		if _, err := repo.PutEgg(ctx, newEgg("alice", "TOKEN", "forever"), AnyVersion); err != nil {
+			t.Fatalf("PutEgg: %v", err)
+		}
+		expiring := newEgg("alice", "TOKEN", "short-lived")
+		expiring.ExpiresAt = time.Now().Add(-time.Minute).Unix()
+		if _, err := repo.PutEgg(ctx, expiring, AnyVersion); err != nil {
+			t.Fatalf("PutEgg: %v", err)
+		}
+
+		// TTL removes version 2 and the current row but leaves version 1
+		expireRows(t, repo, "alice", "TOKEN")
+		if _, err := repo.GetEgg(ctx, "alice", "TOKEN"); !errors.Is(err, ErrEggNotFound) {
+			t.Fatalf("GetEgg after expiry: got %v, want ErrEggNotFound", err)
+		}
+
+		relaid, err := repo.PutEgg(ctx, newEgg("alice", "TOKEN", "again"), NoVersion)
+		if err != nil {
+			t.Fatalf("PutEgg after expiry: %v", err)
+		}
+		if relaid.Version != 2 {
+			t.Errorf("PutEgg after expiry: got version %d, want 2", relaid.Version)
+		}
+		if old, err := repo.GetEggVersion(ctx, "alice", "TOKEN", 1); err != nil || string(old.Ciphertext) != "forever" {
+			t.Errorf("GetEggVersion 1: got %q, %v, want the untouched first version", old.Ciphertext, err)
+		}
+
+		stored, errs := repo.PutEggs(ctx, []Egg{newEgg("alice", "TOKEN", "batched")}, []int{AnyVersion})
+		if errs[0] != nil || stored[0].Version != 3 {
+			t.Errorf("PutEggs: got version %d, %v, want 3", stored[0].Version, errs[0])
+		}
+	})
+
+	t.Run("Audit", func(t *testing.T) {
+		repo := newRepo(t)
+		if last, err := repo.LastAuditEvent(ctx, "alice"); err != nil || last.Seq != 0 {
+			t.Fatalf("LastAuditEvent on empty chain: got %+v, %v", last, err)
+		}
+
+		first := AuditEvent{Vault: "alice", Seq: 1, Actor: "alice", Action: AuditPut, SecretID: "KEY", Version: 1, Outcome: OutcomeSuccess, Hash: "h1"}
+		if err := repo.PutAuditEvent(ctx, first); err != nil {
+			t.Fatalf("PutAuditEvent: %v", err)
+		}
+		if err := repo.PutAuditEvent(ctx, AuditEvent{Vault: "alice", Seq: 1, Hash: "forged"}); !errors.Is(err, ErrAuditConflict) {
+			t.Errorf("PutAuditEvent over an existing event: got %v, want ErrAuditConflict", err)
+		}
+		second := AuditEvent{Vault: "alice", Seq: 2, Actor: "bob", Action: AuditGet, SecretID: "KEY", Outcome: OutcomeDenied, PrevHash: "h1", Hash: "h2"}
+		if err := repo.PutAuditEvent(ctx, second); err != nil {
+			t.Fatalf("PutAuditEvent: %v", err)
+		}
+
+		if last, err := repo.LastAuditEvent(ctx, "alice"); err != nil || last != second {
+			t.Errorf("LastAuditEvent: got %+v, %v, want %+v", last, err, second)
+		}
+		events, err := repo.ListAuditEvents(ctx, "alice")
+		if err != nil {
+			t.Fatalf("ListAuditEvents: %v", err)
+		}
+		if len(events) != 2 || events[0] != first || events[1] != second {
+			t.Errorf("ListAuditEvents: got %+v, want the two events in order", events)
+		}
+		if others, _ := repo.ListAuditEvents(ctx, "bob"); len(others) != 0 {
+			t.Errorf("ListAuditEvents for another vault: got %+v, want none", others)
+		}
+
+		// Audit rows mustn't show up as eggs
+		if eggs, _, err := repo.ScanEggs(ctx, "", 10); err != nil || len(eggs) != 0 {
+			t.Errorf("ScanEggs: got %+v, %v, want no eggs", eggs, err)
+		}
+	})
+}
+
+// expireRows deletes what DynamoDB's TTL would once a secret has expired:
+// its current row and every history row that has an expiry, leaving older
+// versions that never expired.
+func expireRows(t *testing.T, repo EggActions, owner, secretID string) {
+	t.Helper()
+	ctx := context.Background()
+	versions, err := repo.ListEggVersions(ctx, owner, secretID)
+	if err != nil {
+		t.Fatalf("ListEggVersions: %v", err)
+	}
+
+	switch r := repo.(type) {
+	case *MemoryEggRepository:
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		delete(r.eggs[owner], secretID)
+		partition := versionPartition(owner, secretID)
+		var kept []Egg
+		for _, version := range r.versions[partition] {
+			if version.ExpiresAt == 0 {
+				kept = append(kept, version)
+			}
+		}
+		r.versions[partition] = kept
+	case *BoltEggRepository:
+		err = r.db.Update(func(tx *bolt.Tx) error {
+			if err := tx.Bucket(eggsBucket).Bucket([]byte(owner)).Delete([]byte(secretID)); err != nil {
+				return err
+			}
+			history := tx.Bucket(versionsBucket).Bucket([]byte(versionPartition(owner, secretID)))
+			for _, version := range versions {
+				if version.ExpiresAt != 0 {
+					if err := history.Delete([]byte(versionSortKey(version.Version))); err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		})
+	case EggRepository:
+		keys := []map[string]types.AttributeValue{Egg{Owner: owner, SecretID: secretID}.GetKey()}
+		for _, version := range versions {
+			if version.ExpiresAt != 0 {
+				keys = append(keys, map[string]types.AttributeValue{
+					"Owner":    &types.AttributeValueMemberS{Value: versionPartition(owner, secretID)},
+					"SecretID": &types.AttributeValueMemberS{Value: versionSortKey(version.Version)},
+				})
+			}
+		}
+		for _, key := range keys {
+			if _, err = r.DynamoDbClient.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+				TableName: aws.String(r.TableName),
+				Key:       key,
+			}); err != nil {
+				break
+			}
+		}
+	default:
+		t.Fatalf("expireRows: unsupported repository %T", repo)
+	}
+	if err != nil {
+		t.Fatalf("expireRows: %v", err)
+	}
+}
+
+// putLegacyRow stores egg as a row laid before versioning, with no Version.
+func putLegacyRow(t *testing.T, repo EggActions, egg Egg) {
+	t.Helper()
+	egg.Version = 0
+	var err error
+	switch r := repo.(type) {
+	case *MemoryEggRepository:
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		if r.eggs[egg.Owner] == nil {
+			r.eggs[egg.Owner] = make(map[string]Egg)
+		}
+		r.eggs[egg.Owner][egg.SecretID] = egg
+	case *BoltEggRepository:
+		err = r.db.Update(func(tx *bolt.Tx) error {
+			ownerBucket, err := tx.Bucket(eggsBucket).CreateBucketIfNotExists([]byte(egg.Owner))
+			if err != nil {
+				return err
+			}
+			value, err := json.Marshal(egg)
+			if err != nil {
+				return err
+			}
+			return ownerBucket.Put([]byte(egg.SecretID), value)
+		})
+	case EggRepository:
+		var item map[string]types.AttributeValue
+		if item, err = attributevalue.MarshalMap(egg); err == nil {
+			_, err = r.DynamoDbClient.PutItem(context.Background(), &dynamodb.PutItemInput{
+				TableName: aws.String(r.TableName),
+				Item:      item,
+			})
+		}
+	default:
+		t.Fatalf("putLegacyRow: unsupported repository %T", repo)
+	}
+	if err != nil {
+		t.Fatalf("putLegacyRow: %v", err)
+	}
+}
+
+func TestMemoryEggRepository(t *testing.T) {
+	runConformance(t, func(t *testing.T) EggActions {
+		return NewMemoryEggRepository()
+	})
+}
+
+func TestBoltEggRepository(t *testing.T) {
+	runConformance(t, func(t *testing.T) EggActions {
+		repo, err := NewBoltEggRepository(filepath.Join(t.TempDir(), "eggs.db"))
+		if err != nil {
+			t.Fatalf("NewBoltEggRepository: %v", err)
+		}
+		t.Cleanup(func() { repo.Close() })
+		return repo
+	})
+}
+
+// TestDynamoEggRepository runs against DynamoDB Local (or a real account)
+// when DYNAMODB_ENDPOINT is set. Each subtest gets a throwaway table.
+func TestDynamoEggRepository(t *testing.T) {
+	endpoint := os.Getenv("DYNAMODB_ENDPOINT")
+	if endpoint == "" {
+		t.Skip("DYNAMODB_ENDPOINT not set")
+	}
+	cfg, err := config.LoadDefaultConfig(context.Background())
+	if err != nil {
+		t.Fatalf("load SDK config: %v", err)
+	}
+	client := dynamodb.NewFromConfig(cfg, func(o *dynamodb.Options) {
+		o.BaseEndpoint = aws.String(endpoint)
+	})
+
+	runConformance(t, func(t *testing.T) EggActions {
+		tableName := fmt.Sprintf("EggCarton-Test-%d", time.Now().UnixNano())
+		createTestTable(t, client, tableName)
+		return NewEggRepository(client, tableName)
+	})
+}
+
+// createTestTable creates a table with the same key schema as main.tf and
+// drops it when the test finishes.
+func createTestTable(t *testing.T, client *dynamodb.Client, tableName string) {
+	ctx := context.Background()
+	_, err := client.CreateTable(ctx, &dynamodb.CreateTableInput{
+		TableName:   aws.String(tableName),
+		BillingMode: types.BillingModePayPerRequest,
+		AttributeDefinitions: []types.AttributeDefinition{
+			{AttributeName: aws.String("Owner"), AttributeType: types.ScalarAttributeTypeS},
+			{AttributeName: aws.String("SecretID"), AttributeType: types.ScalarAttributeTypeS},
+		},
+		KeySchema: []types.KeySchemaElement{
+			{AttributeName: aws.String("Owner"), KeyType: types.KeyTypeHash},
+			{AttributeName: aws.String("SecretID"), KeyType: types.KeyTypeRange},
+		},
+	})
+	if err != nil {
+		t.Fatalf("CreateTable: %v", err)
+	}
+	waiter := dynamodb.NewTableExistsWaiter(client)
+	if err := waiter.Wait(ctx, &dynamodb.DescribeTableInput{TableName: aws.String(tableName)}, time.Minute); err != nil {
+		t.Fatalf("wait for table: %v", err)
+	}
+	t.Cleanup(func() {
+		client.DeleteTable(ctx, &dynamodb.DeleteTableInput{TableName: aws.String(tableName)})
+	})
+}
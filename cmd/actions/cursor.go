@@ -0,0 +1,74 @@
+package actions
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// ErrInvalidCursor is returned for a cursor that wasn't handed out by the
+// same listing.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// encodeCursor turns a DynamoDB LastEvaluatedKey into an opaque string that
+// can be handed to callers. A nil key (no more pages) encodes to "".
+func encodeCursor(key map[string]types.AttributeValue) (string, error) {
+	if len(key) == 0 {
+		return "", nil
+	}
+	var fields map[string]string
+	if err := attributevalue.UnmarshalMap(key, &fields); err != nil {
+		return "", err
+	}
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// decodeCursor reverses encodeCursor. An empty cursor decodes to a nil key,
+// meaning "start from the beginning".
+func decodeCursor(cursor string) (map[string]types.AttributeValue, error) {
+	if cursor == "" {
+		return nil, nil
+	}
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCursor, err)
+	}
+	var fields map[string]string
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCursor, err)
+	}
+	return attributevalue.MarshalMap(fields)
+}
+
+// pageOf slices one page out of a fully materialised listing, using the
+// offset of the next row as the cursor. The local backends use this; their
+// data sets are small enough to rebuild the listing for every page.
+func pageOf(eggs []Egg, cursor string, limit int) ([]Egg, string, error) {
+	offset := 0
+	if cursor != "" {
+		var err error
+		if offset, err = strconv.Atoi(cursor); err != nil || offset < 0 {
+			return nil, "", fmt.Errorf("%w %q", ErrInvalidCursor, cursor)
+		}
+	}
+	if offset >= len(eggs) {
+		return nil, "", nil
+	}
+	end := len(eggs)
+	if limit > 0 {
+		end = min(offset+limit, len(eggs))
+	}
+	if end == len(eggs) {
+		return eggs[offset:], "", nil
+	}
+	return eggs[offset:end], strconv.Itoa(end), nil
+}
@@ -2,9 +2,11 @@ package actions
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/owenHochwald/egg-carton/pkg/crypto"
 )
 
 // Attribute,Type,Purpose,Example Value
@@ -13,6 +15,14 @@ import (
 // Ciphertext,B,The actual encrypted API key,[Binary Data]
 // EncryptedDataKey,B,The KMS-wrapped key used for this specific secret,[Binary Data]
 // CreatedAt,S,ISO Timestamp,2026-02-15T08:00:00Z
+// Version,N,Monotonic version number of the current value,3
+// CreatedBy,S,Cognito sub of whoever wrote this version,3f2a...
+// Bound,BOOL,Ciphertext and data key are bound to Owner and SecretID,true
+// KeyID,S,Master key that wraps EncryptedDataKey,arn:aws:kms:...:key/1234...
+// ExpiresAt,N,Unix time after which the egg is gone (the table's TTL attribute),1798675200
+// DeletedAt,S,When the egg was broken into the trash,2026-02-16T09:00:00Z
+// DeletedBy,S,Cognito sub of whoever broke it,3f2a...
+// TrashedExpiresAt,N,ExpiresAt from before the egg was trashed,0
 
 type Egg struct {
 	Owner            string `dynamodbav:"Owner"`
@@ -20,6 +30,99 @@ type Egg struct {
 	Ciphertext       []byte `dynamodbav:"Ciphertext"`
 	EncryptedDataKey []byte `dynamodbav:"EncryptedDataKey"`
 	CreatedAt        string `dynamodbav:"CreatedAt"`
+	Version          int    `dynamodbav:"Version,omitempty"`
+	CreatedBy        string `dynamodbav:"CreatedBy,omitempty"`
+	// Bound is set once the ciphertext is sealed with AdditionalData and the
+	// data key wrapped under EncryptionContext. Older eggs decrypt without
+	// either until they are migrated.
+	Bound bool `dynamodbav:"Bound,omitempty"`
+	// KeyID is the master key EncryptedDataKey is currently wrapped under.
+	// Eggs written before it was recorded leave it empty.
+	KeyID string `dynamodbav:"KeyID,omitempty"`
+	// ExpiresAt is the Unix time the egg expires at, or zero if it never
+	// does. Handlers stop returning it from then on; DynamoDB's TTL deletes
+	// the row some time later.
+	ExpiresAt int64 `dynamodbav:"ExpiresAt,omitempty"`
+	// DeletedAt is set while the egg is in the trash (see TrashEgg). Until
+	// it is restored, ExpiresAt is when it gets purged and TrashedExpiresAt
+	// holds the expiry to put back.
+	DeletedAt        string `dynamodbav:"DeletedAt,omitempty"`
+	DeletedBy        string `dynamodbav:"DeletedBy,omitempty"`
+	TrashedExpiresAt int64  `dynamodbav:"TrashedExpiresAt,omitempty"`
+
+	// History marks an egg read from the version history rather than the
+	// current row. It is never stored.
+	History bool `dynamodbav:"-" json:"-"`
+}
+
+// versionItem is how a historical copy of an egg is laid out in the table.
+// Every version of a secret lives in its own partition (see versionPartition)
+// so queries on the owner partition never see history rows.
+type versionItem struct {
+	Partition        string `dynamodbav:"Owner"`
+	SortKey          string `dynamodbav:"SecretID"`
+	EggOwner         string `dynamodbav:"EggOwner"`
+	EggSecretID      string `dynamodbav:"EggSecretID"`
+	Ciphertext       []byte `dynamodbav:"Ciphertext"`
+	EncryptedDataKey []byte `dynamodbav:"EncryptedDataKey"`
+	CreatedAt        string `dynamodbav:"CreatedAt"`
+	Version          int    `dynamodbav:"Version"`
+	CreatedBy        string `dynamodbav:"CreatedBy,omitempty"`
+	Bound            bool   `dynamodbav:"Bound,omitempty"`
+	KeyID            string `dynamodbav:"KeyID,omitempty"`
+	ExpiresAt        int64  `dynamodbav:"ExpiresAt,omitempty"`
+	DeletedAt        string `dynamodbav:"DeletedAt,omitempty"`
+	DeletedBy        string `dynamodbav:"DeletedBy,omitempty"`
+	TrashedExpiresAt int64  `dynamodbav:"TrashedExpiresAt,omitempty"`
+}
+
+// versionPartition returns the partition key holding the history of one secret.
+func versionPartition(owner, secretID string) string {
+	return "VERSIONS#" + owner + "#" + secretID
+}
+
+// versionSortKey zero-pads the version so lexical order matches numeric order.
+func versionSortKey(version int) string {
+	return fmt.Sprintf("%010d", version)
+}
+
+func newVersionItem(egg Egg) versionItem {
+	return versionItem{
+		Partition:        versionPartition(egg.Owner, egg.SecretID),
+		SortKey:          versionSortKey(egg.Version),
+		EggOwner:         egg.Owner,
+		EggSecretID:      egg.SecretID,
+		Ciphertext:       egg.Ciphertext,
+		EncryptedDataKey: egg.EncryptedDataKey,
+		CreatedAt:        egg.CreatedAt,
+		Version:          egg.Version,
+		CreatedBy:        egg.CreatedBy,
+		Bound:            egg.Bound,
+		KeyID:            egg.KeyID,
+		ExpiresAt:        egg.ExpiresAt,
+		DeletedAt:        egg.DeletedAt,
+		DeletedBy:        egg.DeletedBy,
+		TrashedExpiresAt: egg.TrashedExpiresAt,
+	}
+}
+
+func (v versionItem) egg() Egg {
+	return Egg{
+		Owner:            v.EggOwner,
+		SecretID:         v.EggSecretID,
+		Ciphertext:       v.Ciphertext,
+		EncryptedDataKey: v.EncryptedDataKey,
+		CreatedAt:        v.CreatedAt,
+		Version:          v.Version,
+		CreatedBy:        v.CreatedBy,
+		Bound:            v.Bound,
+		KeyID:            v.KeyID,
+		ExpiresAt:        v.ExpiresAt,
+		DeletedAt:        v.DeletedAt,
+		DeletedBy:        v.DeletedBy,
+		TrashedExpiresAt: v.TrashedExpiresAt,
+		History:          true,
+	}
 }
 
 // GetKey returns the composite primary key of the egg in a format that can be
@@ -36,8 +139,56 @@ func (e Egg) GetKey() map[string]types.AttributeValue {
 	return map[string]types.AttributeValue{"Owner": owner, "SecretID": secretID}
 }
 
+// EncryptionContext returns the KMS encryption context the egg's data key is
+// wrapped under, or nil for eggs written before binding.
+func (e Egg) EncryptionContext() map[string]string {
+	if !e.Bound {
+		return nil
+	}
+	return crypto.EncryptionContext(e.Owner, e.SecretID)
+}
+
+// AdditionalData returns the AES-GCM additional data the egg's ciphertext is
+// sealed with, or nil for eggs written before binding.
+func (e Egg) AdditionalData() []byte {
+	if !e.Bound {
+		return nil
+	}
+	return crypto.AdditionalData(e.Owner, e.SecretID)
+}
+
+// Expired reports whether the egg has an expiry and it has passed.
+func (e Egg) Expired(now time.Time) bool {
+	return e.ExpiresAt != 0 && now.Unix() >= e.ExpiresAt
+}
+
+// Expiry formats ExpiresAt as an RFC 3339 timestamp, or returns "" if the egg
+// never expires.
+func (e Egg) Expiry() string {
+	if e.ExpiresAt == 0 {
+		return ""
+	}
+	return time.Unix(e.ExpiresAt, 0).UTC().Format(time.RFC3339)
+}
+
+// Trashed reports whether the egg is in the trash.
+func (e Egg) Trashed() bool {
+	return e.DeletedAt != ""
+}
+
+// LiveEggs returns the eggs that are neither expired nor in the trash.
+func LiveEggs(eggs []Egg, now time.Time) []Egg {
+	var live []Egg
+	for _, egg := range eggs {
+		if !egg.Expired(now) && !egg.Trashed() {
+			live = append(live, egg)
+		}
+	}
+	return live
+}
+
 // String returns the owner, secret ID, and created at timestamp of the egg.
 func (e Egg) String() string {
 	return fmt.Sprintf("%v\n\tOwner: %v\n\tSecret ID: %v\n\tCreated At: %v\n",
-		e.Ciphertext, e.EncryptedDataKey, e.CreatedAt)
+		e.SecretID, e.Owner, e.SecretID, e.CreatedAt)
 }
@@ -2,21 +2,88 @@ package actions
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"slices"
+	"strconv"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 )
 
+var (
+	// ErrEggNotFound is returned when no egg matches the requested key.
+	ErrEggNotFound = errors.New("egg not found")
+	// ErrEggConflict is returned when an egg changed between being read and
+	// being written.
+	ErrEggConflict = errors.New("egg was modified concurrently")
+	// ErrEggExpired is returned when acting on an egg whose expiry has
+	// passed but which hasn't been cleaned up yet.
+	ErrEggExpired = errors.New("egg has expired")
+)
+
+// Version preconditions for writes. Any other expected value is the version
+// the secret must currently be at, as last seen by the writer; the write
+// fails with ErrEggConflict if it has moved on.
+const (
+	AnyVersion = -1 // Write whatever the current version is
+	NoVersion  = 0  // Only write if the secret doesn't exist yet
+)
+
+// EggActions is the storage seam used by the Lambda handlers. Every backend
+// (DynamoDB, in-memory, bolt) must pass the conformance suite in
+// conformance_test.go.
 type EggActions interface {
-	GetEgg(ctx context.Context, owner string) (Egg, error)
+	TeamActions
+	AuditActions
+
+	// GetEgg looks up a single egg by its composite key.
+	GetEgg(ctx context.Context, owner, secretID string) (Egg, error)
 	GetAllEggs(ctx context.Context, owner string) ([]Egg, error)
-	PutEgg(ctx context.Context, egg Egg) error
-	BreakEgg(ctx context.Context, owner, secretID string) error
+	// GetEggsWithPrefix returns the eggs of owner whose SecretID starts with
+	// prefix, ordered by SecretID. See Namespace.
+	GetEggsWithPrefix(ctx context.Context, owner, prefix string) ([]Egg, error)
+	// ListEggs returns one page of the eggs GetEggsWithPrefix would, roughly
+	// limit at a time (an empty prefix lists them all). Pass the returned
+	// cursor back in to continue; an empty cursor means there are no more.
+	ListEggs(ctx context.Context, owner, prefix, cursor string, limit int) ([]Egg, string, error)
+	// PutEgg stores egg as the newest version of its secret and returns it
+	// with Version filled in. Earlier versions are kept as history. It
+	// returns ErrEggConflict unless the secret is at the expected version
+	// (AnyVersion, NoVersion or a version number).
+	PutEgg(ctx context.Context, egg Egg, expected int) (Egg, error)
+	// BreakEgg permanently deletes a secret along with its whole version
+	// history. See TrashEgg for the recoverable kind of delete. Unless
+	// expected is AnyVersion, it returns ErrEggConflict if the secret isn't
+	// at that version.
+	BreakEgg(ctx context.Context, owner, secretID string, expected int) error
+	// PutEggs stores several eggs as PutEgg would, each at its own expected
+	// version, in as few round trips as the backend allows. The results line
+	// up with eggs, and one egg failing doesn't stop the others.
+	PutEggs(ctx context.Context, eggs []Egg, expected []int) ([]Egg, []error)
+	// BreakEggs permanently deletes several secrets of owner as BreakEgg
+	// would with AnyVersion. The errors line up with secretIDs; a secret
+	// that failed may be partly deleted, and breaking it again finishes
+	// the job.
+	BreakEggs(ctx context.Context, owner string, secretIDs []string) []error
+	// ListEggVersions returns every stored version of a secret, oldest first.
+	ListEggVersions(ctx context.Context, owner, secretID string) ([]Egg, error)
+	GetEggVersion(ctx context.Context, owner, secretID string, version int) (Egg, error)
+	// ScanEggs walks every stored egg of every owner, current rows and
+	// version history alike, roughly limit rows at a time. Pass the returned
+	// cursor back in to continue; an empty cursor means the walk is done.
+	ScanEggs(ctx context.Context, cursor string, limit int) ([]Egg, string, error)
+	// UpdateEgg rewrites the exact row egg was read from (current or
+	// history) in place, e.g. with re-encrypted material or trash markers,
+	// without creating a new version. It returns ErrEggConflict if that row
+	// changed since it was read.
+	UpdateEgg(ctx context.Context, egg Egg) error
 }
 
+// EggRepository is the DynamoDB implementation of EggActions.
 type EggRepository struct {
 	DynamoDbClient *dynamodb.Client
 	TableName      string
@@ -26,87 +93,961 @@ func NewEggRepository(dynamoDbClient *dynamodb.Client, tableName string) EggRepo
 	return EggRepository{DynamoDbClient: dynamoDbClient, TableName: tableName}
 }
 
-func (r EggRepository) GetEgg(ctx context.Context, owner string) (Egg, error) {
-	var egg Egg
-	params, err := attributevalue.MarshalList([]interface{}{owner})
+func (r EggRepository) GetEgg(ctx context.Context, owner, secretID string) (Egg, error) {
+	return r.getItem(ctx, Egg{Owner: owner, SecretID: secretID}.GetKey())
+}
+
+func (r EggRepository) GetAllEggs(ctx context.Context, owner string) ([]Egg, error) {
+	return r.GetEggsWithPrefix(ctx, owner, "")
+}
+
+func (r EggRepository) GetEggsWithPrefix(ctx context.Context, owner, prefix string) ([]Egg, error) {
+	var eggs []Egg
+	cursor := ""
+	for {
+		page, next, err := r.ListEggs(ctx, owner, prefix, cursor, 0)
+		if err != nil {
+			return eggs, err
+		}
+		eggs = append(eggs, page...)
+		if next == "" {
+			return eggs, nil
+		}
+		cursor = next
+	}
+}
+
+// ListEggs queries one page of the owner's partition. A limit of 0 leaves the
+// page size to DynamoDB, which stops at 1 MB.
+func (r EggRepository) ListEggs(ctx context.Context, owner, prefix, cursor string, limit int) ([]Egg, string, error) {
+	startKey, err := decodeCursor(cursor)
 	if err != nil {
-		panic(err)
+		return nil, "", err
+	}
+	// A cursor can only continue a listing of the same vault
+	var start struct{ Owner, SecretID string }
+	if err := attributevalue.UnmarshalMap(startKey, &start); err != nil || (startKey != nil && start.Owner != owner) {
+		return nil, "", ErrInvalidCursor
+	}
+
+	input := &dynamodb.QueryInput{
+		TableName:              aws.String(r.TableName),
+		KeyConditionExpression: aws.String("#owner = :owner"),
+		ExpressionAttributeNames: map[string]string{
+			"#owner": "Owner", // OWNER is a reserved word
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":owner": &types.AttributeValueMemberS{Value: owner},
+		},
+		ExclusiveStartKey: startKey,
+	}
+	if prefix != "" {
+		input.KeyConditionExpression = aws.String("#owner = :owner AND begins_with(SecretID, :prefix)")
+		input.ExpressionAttributeValues[":prefix"] = &types.AttributeValueMemberS{Value: prefix}
+	}
+	if limit > 0 {
+		input.Limit = aws.Int32(int32(limit))
+	}
+	response, err := r.DynamoDbClient.Query(ctx, input)
+	if err != nil {
+		log.Printf("Couldn't query eggs for %v with prefix %v. Here's why: %v\n", owner, prefix, err)
+		return nil, "", err
+	}
+
+	var eggs []Egg
+	if err := attributevalue.UnmarshalListOfMaps(response.Items, &eggs); err != nil {
+		log.Printf("Couldn't unmarshal response. Here's why: %v\n", err)
+		return nil, "", err
+	}
+	next, err := encodeCursor(response.LastEvaluatedKey)
+	return eggs, next, err
+}
+
+func (r EggRepository) PutEgg(ctx context.Context, egg Egg, expected int) (Egg, error) {
+	current, err := r.getItem(ctx, Egg{Owner: egg.Owner, SecretID: egg.SecretID}.GetKey())
+	if err != nil && !errors.Is(err, ErrEggNotFound) {
+		return egg, err
+	}
+	exists := err == nil
+	if expected != AnyVersion && currentVersion(current, exists) != expected {
+		return egg, ErrEggConflict
+	}
+	if !exists {
+		if current.Version, err = r.latestVersion(ctx, egg.Owner, egg.SecretID); err != nil {
+			return egg, err
+		}
 	}
-	response, err := r.DynamoDbClient.ExecuteStatement(ctx, &dynamodb.ExecuteStatementInput{
-		Statement: aws.String(
-			fmt.Sprintf("SELECT * FROM \"%v\" WHERE Owner=?",
-				r.TableName)),
-		Parameters: params,
+
+	writes, egg, err := r.putWrites(egg, current, exists)
+	if err != nil {
+		return egg, err
+	}
+
+	// Write the current value and its history row atomically, guarded on the
+	// version we read so concurrent writers can't hand out the same number
+	// and the expected version still holds when the write lands.
+	_, err = r.DynamoDbClient.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{
+		TransactItems: writes,
 	})
 	if err != nil {
-		log.Printf("Couldn't get info about %v. Here's why: %v\n", owner, err)
-	} else {
-		err = attributevalue.UnmarshalMap(response.Items[0], &egg)
-		if err != nil {
-			log.Printf("Couldn't unmarshal response. Here's why: %v\n", err)
+		log.Printf("Couldn't put an item. Here's why: %v\n", err)
+		var canceled *types.TransactionCanceledException
+		if errors.As(err, &canceled) {
+			return egg, ErrEggConflict
 		}
 	}
 	return egg, err
 }
 
-func (r EggRepository) GetAllEggs(ctx context.Context, owner string) ([]Egg, error) {
+// latestVersion returns the highest version left in a secret's history, or
+// 0 if it has none. History rows expire one by one, so when TTL has removed
+// the current row older rows that never expired can still be there, and the
+// next write has to be numbered after them.
+func (r EggRepository) latestVersion(ctx context.Context, owner, secretID string) (int, error) {
+	response, err := r.DynamoDbClient.Query(ctx, &dynamodb.QueryInput{
+		TableName:                aws.String(r.TableName),
+		KeyConditionExpression:   aws.String("#owner = :partition"),
+		ExpressionAttributeNames: map[string]string{"#owner": "Owner"},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":partition": &types.AttributeValueMemberS{Value: versionPartition(owner, secretID)},
+		},
+		ScanIndexForward: aws.Bool(false),
+		Limit:            aws.Int32(1),
+		ConsistentRead:   aws.Bool(true),
+	})
+	if err != nil {
+		log.Printf("Couldn't get the latest version of %v. Here's why: %v\n", secretID, err)
+		return 0, err
+	}
+	if len(response.Items) == 0 {
+		return 0, nil
+	}
+	var item versionItem
+	if err := attributevalue.UnmarshalMap(response.Items[0], &item); err != nil {
+		return 0, err
+	}
+	return item.Version, nil
+}
+
+// putWrites builds the transaction that stores egg as the version after
+// current, returning egg with its Version filled in. Every row is guarded on
+// current being what we read.
+func (r EggRepository) putWrites(egg, current Egg, exists bool) ([]types.TransactWriteItem, Egg, error) {
+	var history []types.TransactWriteItem
+	condition := "attribute_not_exists(SecretID)"
+	var conditionValues map[string]types.AttributeValue
+	if exists && current.Version == 0 {
+		// Eggs laid before versioning existed become version 1 so overwriting
+		// them doesn't lose the old value.
+		current.Version = 1
+		put, err := r.versionPut(current)
+		if err != nil {
+			return nil, egg, err
+		}
+		history = append(history, put)
+		condition = "attribute_not_exists(Version)"
+	} else if exists {
+		condition = "Version = :prev"
+		conditionValues = map[string]types.AttributeValue{
+			":prev": &types.AttributeValueMemberN{Value: strconv.Itoa(current.Version)},
+		}
+	}
+	egg.Version = current.Version + 1
+
+	item, err := attributevalue.MarshalMap(egg)
+	if err != nil {
+		log.Printf("Couldn't marshal egg to DynamoDB item. Here's why: %v\n", err)
+		return nil, egg, err
+	}
+	versionPut, err := r.versionPut(egg)
+	if err != nil {
+		return nil, egg, err
+	}
+	return append(history, types.TransactWriteItem{
+		Put: &types.Put{
+			TableName:                 aws.String(r.TableName),
+			Item:                      item,
+			ConditionExpression:       aws.String(condition),
+			ExpressionAttributeValues: conditionValues,
+		},
+	}, versionPut), egg, nil
+}
+
+// PutEggs reads every current row with BatchGetItem, then writes as many
+// eggs per transaction as fit in its 100 rows. A cancelled transaction
+// names the rows whose condition failed: those eggs lost a race with
+// another writer, and the rest of the transaction is tried again without
+// them.
+func (r EggRepository) PutEggs(ctx context.Context, eggs []Egg, expected []int) ([]Egg, []error) {
+	stored := slices.Clone(eggs)
+	errs := make([]error, len(eggs))
+
+	keys := make([]map[string]types.AttributeValue, len(eggs))
+	for i, egg := range eggs {
+		keys[i] = egg.GetKey()
+	}
+	current, err := r.batchGet(ctx, keys)
+	if err != nil {
+		for i := range errs {
+			errs[i] = err
+		}
+		return stored, errs
+	}
+
+	writes := make([][]types.TransactWriteItem, len(eggs))
+	var pending []int
+	for i, egg := range eggs {
+		previous, exists := current[[2]string{egg.Owner, egg.SecretID}]
+		if expected[i] != AnyVersion && currentVersion(previous, exists) != expected[i] {
+			errs[i] = ErrEggConflict
+			continue
+		}
+		if !exists {
+			if previous.Version, errs[i] = r.latestVersion(ctx, egg.Owner, egg.SecretID); errs[i] != nil {
+				continue
+			}
+		}
+		writes[i], stored[i], errs[i] = r.putWrites(egg, previous, exists)
+		if errs[i] == nil {
+			pending = append(pending, i)
+		}
+	}
+
+	for len(pending) > 0 {
+		var batch []int
+		var items []types.TransactWriteItem
+		for len(pending) > 0 && len(items)+len(writes[pending[0]]) <= 100 {
+			batch = append(batch, pending[0])
+			items = append(items, writes[pending[0]]...)
+			pending = pending[1:]
+		}
+
+		_, err := r.DynamoDbClient.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{
+			TransactItems: items,
+		})
+		var canceled *types.TransactionCanceledException
+		if errors.As(err, &canceled) {
+			var retry []int
+			row := 0
+			for _, i := range batch {
+				for range writes[i] {
+					if row < len(canceled.CancellationReasons) {
+						switch aws.ToString(canceled.CancellationReasons[row].Code) {
+						case "ConditionalCheckFailed", "TransactionConflict":
+							errs[i] = ErrEggConflict
+						}
+					}
+					row++
+				}
+				if errs[i] == nil {
+					retry = append(retry, i)
+				}
+			}
+			if len(retry) < len(batch) {
+				pending = append(retry, pending...)
+				continue
+			}
+		}
+		if err != nil {
+			log.Printf("Couldn't put a batch of items. Here's why: %v\n", err)
+			for _, i := range batch {
+				errs[i] = err
+			}
+		}
+	}
+	return stored, errs
+}
+
+func (r EggRepository) BreakEgg(ctx context.Context, owner, secretID string, expected int) error {
+	input := &dynamodb.DeleteItemInput{
+		TableName: aws.String(r.TableName),
+		Key:       Egg{Owner: owner, SecretID: secretID}.GetKey(),
+	}
+	if expected != AnyVersion {
+		condition := "Version = :version"
+		if expected == 1 {
+			// Eggs laid before versioning have no Version and count as 1
+			condition = "attribute_exists(SecretID) AND (attribute_not_exists(Version) OR Version = :version)"
+		}
+		input.ConditionExpression = aws.String(condition)
+		input.ExpressionAttributeValues = map[string]types.AttributeValue{
+			":version": &types.AttributeValueMemberN{Value: strconv.Itoa(expected)},
+		}
+	}
+	_, err := r.DynamoDbClient.DeleteItem(ctx, input)
+	var conditionFailed *types.ConditionalCheckFailedException
+	if errors.As(err, &conditionFailed) {
+		return ErrEggConflict
+	}
+	if err != nil {
+		log.Printf("Couldn't delete that egg from the table. Here's why: %v\n", err)
+		return err
+	}
+
+	versions, err := r.ListEggVersions(ctx, owner, secretID)
+	if err != nil {
+		return err
+	}
+	var deletes []types.WriteRequest
+	for _, version := range versions {
+		item := newVersionItem(version)
+		key, err := attributevalue.MarshalMap(map[string]string{"Owner": item.Partition, "SecretID": item.SortKey})
+		if err != nil {
+			return err
+		}
+		deletes = append(deletes, types.WriteRequest{DeleteRequest: &types.DeleteRequest{Key: key}})
+	}
+	return r.batchWrite(ctx, deletes)
+}
+
+// BreakEggs deletes every current row and its history with BatchWriteItem.
+func (r EggRepository) BreakEggs(ctx context.Context, owner string, secretIDs []string) []error {
+	errs := make([]error, len(secretIDs))
+	var deletes []types.WriteRequest
+	for i, secretID := range secretIDs {
+		versions, err := r.ListEggVersions(ctx, owner, secretID)
+		if err != nil {
+			errs[i] = err
+			continue
+		}
+		secretDeletes := []types.WriteRequest{{DeleteRequest: &types.DeleteRequest{
+			Key: Egg{Owner: owner, SecretID: secretID}.GetKey(),
+		}}}
+		for _, version := range versions {
+			item := newVersionItem(version)
+			var key map[string]types.AttributeValue
+			key, err = attributevalue.MarshalMap(map[string]string{"Owner": item.Partition, "SecretID": item.SortKey})
+			if err != nil {
+				break
+			}
+			secretDeletes = append(secretDeletes, types.WriteRequest{DeleteRequest: &types.DeleteRequest{Key: key}})
+		}
+		if err != nil {
+			errs[i] = err
+			continue
+		}
+		deletes = append(deletes, secretDeletes...)
+	}
+
+	// BatchWriteItem doesn't say which chunk failed, so an error fails
+	// every secret that hadn't already
+	if err := r.batchWrite(ctx, deletes); err != nil {
+		for i := range errs {
+			if errs[i] == nil {
+				errs[i] = err
+			}
+		}
+	}
+	return errs
+}
+
+func (r EggRepository) ListEggVersions(ctx context.Context, owner, secretID string) ([]Egg, error) {
+	params, err := attributevalue.MarshalList([]interface{}{versionPartition(owner, secretID)})
+	if err != nil {
+		panic(err)
+	}
+
 	var eggs []Egg
-	params, err := attributevalue.MarshalList([]interface{}{owner})
+	var nextToken *string
+	for {
+		response, err := r.DynamoDbClient.ExecuteStatement(ctx, &dynamodb.ExecuteStatementInput{
+			Statement: aws.String(
+				fmt.Sprintf("SELECT * FROM \"%v\" WHERE Owner=?",
+					r.TableName)),
+			Parameters: params,
+			NextToken:  nextToken,
+		})
+		if err != nil {
+			log.Printf("Couldn't get versions of %v. Here's why: %v\n", secretID, err)
+			return eggs, err
+		}
+
+		var items []versionItem
+		if err := attributevalue.UnmarshalListOfMaps(response.Items, &items); err != nil {
+			log.Printf("Couldn't unmarshal response. Here's why: %v\n", err)
+			return eggs, err
+		}
+		for _, item := range items {
+			eggs = append(eggs, item.egg())
+		}
+
+		if response.NextToken == nil {
+			return eggs, nil
+		}
+		nextToken = response.NextToken
+	}
+}
+
+func (r EggRepository) GetEggVersion(ctx context.Context, owner, secretID string, version int) (Egg, error) {
+	key, err := attributevalue.MarshalMap(map[string]string{
+		"Owner":    versionPartition(owner, secretID),
+		"SecretID": versionSortKey(version),
+	})
 	if err != nil {
 		panic(err)
 	}
-	response, err := r.DynamoDbClient.ExecuteStatement(ctx, &dynamodb.ExecuteStatementInput{
-		Statement: aws.String(
-			fmt.Sprintf("SELECT * FROM \"%v\" WHERE Owner=?",
-				r.TableName)),
-		Parameters: params,
+	response, err := r.DynamoDbClient.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(r.TableName),
+		Key:       key,
 	})
 	if err != nil {
-		log.Printf("Couldn't get eggs for %v. Here's why: %v\n", owner, err)
-		return eggs, err
+		log.Printf("Couldn't get version %v of %v. Here's why: %v\n", version, secretID, err)
+		return Egg{}, err
+	}
+	if response.Item == nil {
+		return Egg{}, ErrEggNotFound
 	}
 
-	// Unmarshal all items
-	err = attributevalue.UnmarshalListOfMaps(response.Items, &eggs)
-	if err != nil {
+	var item versionItem
+	if err := attributevalue.UnmarshalMap(response.Item, &item); err != nil {
 		log.Printf("Couldn't unmarshal response. Here's why: %v\n", err)
-		return eggs, err
+		return Egg{}, err
 	}
+	return item.egg(), nil
+}
 
-	return eggs, nil
+func (r EggRepository) ScanEggs(ctx context.Context, cursor string, limit int) ([]Egg, string, error) {
+	startKey, err := decodeCursor(cursor)
+	if err != nil {
+		return nil, "", err
+	}
+	response, err := r.DynamoDbClient.Scan(ctx, &dynamodb.ScanInput{
+		TableName:         aws.String(r.TableName),
+		Limit:             aws.Int32(int32(limit)),
+		ExclusiveStartKey: startKey,
+		ConsistentRead:    aws.Bool(true),
+	})
+	if err != nil {
+		log.Printf("Couldn't scan the table. Here's why: %v\n", err)
+		return nil, "", err
+	}
+
+	var eggs []Egg
+	for _, item := range response.Items {
+		// Team membership and audit rows share the table but aren't eggs
+		if _, ok := item["ItemType"]; ok {
+			continue
+		}
+		// History rows carry the real owner in EggOwner
+		if _, ok := item["EggOwner"]; ok {
+			var version versionItem
+			if err := attributevalue.UnmarshalMap(item, &version); err != nil {
+				return nil, "", err
+			}
+			eggs = append(eggs, version.egg())
+			continue
+		}
+		var egg Egg
+		if err := attributevalue.UnmarshalMap(item, &egg); err != nil {
+			return nil, "", err
+		}
+		eggs = append(eggs, egg)
+	}
+
+	next, err := encodeCursor(response.LastEvaluatedKey)
+	return eggs, next, err
 }
 
-func (r EggRepository) PutEgg(ctx context.Context, egg Egg) error {
-	// Use standard PutItem instead of PartiQL for proper upsert behavior
-	item, err := attributevalue.MarshalMap(egg)
+func (r EggRepository) UpdateEgg(ctx context.Context, egg Egg) error {
+	var item map[string]types.AttributeValue
+	var condition string
+	var conditionValues map[string]types.AttributeValue
+	var err error
+	switch {
+	case egg.History:
+		item, err = attributevalue.MarshalMap(newVersionItem(egg))
+		condition = "attribute_exists(SecretID)"
+	case egg.Version == 0:
+		item, err = attributevalue.MarshalMap(egg)
+		condition = "attribute_exists(SecretID) AND attribute_not_exists(Version)"
+	default:
+		item, err = attributevalue.MarshalMap(egg)
+		condition = "Version = :version"
+		conditionValues = map[string]types.AttributeValue{
+			":version": &types.AttributeValueMemberN{Value: strconv.Itoa(egg.Version)},
+		}
+	}
 	if err != nil {
 		log.Printf("Couldn't marshal egg to DynamoDB item. Here's why: %v\n", err)
 		return err
 	}
 
 	_, err = r.DynamoDbClient.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName:                 aws.String(r.TableName),
+		Item:                      item,
+		ConditionExpression:       aws.String(condition),
+		ExpressionAttributeValues: conditionValues,
+	})
+	var conditionFailed *types.ConditionalCheckFailedException
+	if errors.As(err, &conditionFailed) {
+		return ErrEggConflict
+	}
+	if err != nil {
+		log.Printf("Couldn't update egg %v. Here's why: %v\n", egg.SecretID, err)
+	}
+	return err
+}
+
+// getItem does a strongly consistent point lookup of one egg.
+func (r EggRepository) getItem(ctx context.Context, key map[string]types.AttributeValue) (Egg, error) {
+	var egg Egg
+	response, err := r.DynamoDbClient.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName:      aws.String(r.TableName),
+		Key:            key,
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		log.Printf("Couldn't get egg. Here's why: %v\n", err)
+		return egg, err
+	}
+	if response.Item == nil {
+		return egg, ErrEggNotFound
+	}
+	err = attributevalue.UnmarshalMap(response.Item, &egg)
+	if err != nil {
+		log.Printf("Couldn't unmarshal response. Here's why: %v\n", err)
+	}
+	return egg, err
+}
+
+// batchGet reads the eggs at keys with BatchGetItem, 100 at a time,
+// retrying anything DynamoDB reports as unprocessed. Missing eggs are left
+// out of the result, which is keyed by owner and SecretID.
+func (r EggRepository) batchGet(ctx context.Context, keys []map[string]types.AttributeValue) (map[[2]string]Egg, error) {
+	eggs := make(map[[2]string]Egg)
+	for len(keys) > 0 {
+		n := min(len(keys), 100)
+		pending := map[string]types.KeysAndAttributes{r.TableName: {Keys: keys[:n], ConsistentRead: aws.Bool(true)}}
+		keys = keys[n:]
+		for len(pending[r.TableName].Keys) > 0 {
+			response, err := r.DynamoDbClient.BatchGetItem(ctx, &dynamodb.BatchGetItemInput{
+				RequestItems: pending,
+			})
+			if err != nil {
+				log.Printf("Couldn't batch get items. Here's why: %v\n", err)
+				return nil, err
+			}
+			var page []Egg
+			if err := attributevalue.UnmarshalListOfMaps(response.Responses[r.TableName], &page); err != nil {
+				log.Printf("Couldn't unmarshal response. Here's why: %v\n", err)
+				return nil, err
+			}
+			for _, egg := range page {
+				eggs[[2]string{egg.Owner, egg.SecretID}] = egg
+			}
+			pending = response.UnprocessedKeys
+		}
+	}
+	return eggs, nil
+}
+
+// versionPut builds the transaction entry that records egg in its history
+// partition. It fails if that version number was already taken.
+func (r EggRepository) versionPut(egg Egg) (types.TransactWriteItem, error) {
+	item, err := attributevalue.MarshalMap(newVersionItem(egg))
+	if err != nil {
+		log.Printf("Couldn't marshal egg version to DynamoDB item. Here's why: %v\n", err)
+		return types.TransactWriteItem{}, err
+	}
+	return types.TransactWriteItem{
+		Put: &types.Put{
+			TableName:           aws.String(r.TableName),
+			Item:                item,
+			ConditionExpression: aws.String("attribute_not_exists(SecretID)"),
+		},
+	}, nil
+}
+
+// batchWrite sends write requests in chunks of 25, the BatchWriteItem limit,
+// retrying anything DynamoDB reports as unprocessed.
+func (r EggRepository) batchWrite(ctx context.Context, requests []types.WriteRequest) error {
+	for len(requests) > 0 {
+		n := min(len(requests), 25)
+		pending := map[string][]types.WriteRequest{r.TableName: requests[:n]}
+		requests = requests[n:]
+		for len(pending[r.TableName]) > 0 {
+			response, err := r.DynamoDbClient.BatchWriteItem(ctx, &dynamodb.BatchWriteItemInput{
+				RequestItems: pending,
+			})
+			if err != nil {
+				log.Printf("Couldn't batch write items. Here's why: %v\n", err)
+				return err
+			}
+			pending = response.UnprocessedItems
+		}
+	}
+	return nil
+}
+
+// Team membership lives in the same table as the eggs. Each membership is
+// stored twice so both directions are a single query: once in the team's
+// partition (keyed by member) and once in the member's partition (keyed by
+// team). The team partition also holds a header row that claims the name,
+// and whose Revision goes up with every membership change.
+// Every such row has an ItemType so ScanEggs can tell it from an egg.
+const (
+	teamHeaderSortKey  = "#TEAM"
+	itemTypeTeam       = "team"
+	itemTypeMember     = "member"
+	itemTypeMembership = "membership"
+)
+
+type teamItem struct {
+	Partition string `dynamodbav:"Owner"`
+	SortKey   string `dynamodbav:"SecretID"`
+	ItemType  string `dynamodbav:"ItemType"`
+	TeamMember
+}
+
+func teamPartition(team string) string {
+	return "MEMBERS#" + team
+}
+
+func memberTeamsPartition(member string) string {
+	return "TEAMS#" + member
+}
+
+// memberPuts builds the two rows recording m.
+func (r EggRepository) memberPuts(m TeamMember) ([]types.TransactWriteItem, error) {
+	var puts []types.TransactWriteItem
+	for _, row := range []teamItem{
+		{Partition: teamPartition(m.Team), SortKey: m.Member, ItemType: itemTypeMember, TeamMember: m},
+		{Partition: memberTeamsPartition(m.Member), SortKey: m.Team, ItemType: itemTypeMembership, TeamMember: m},
+	} {
+		item, err := attributevalue.MarshalMap(row)
+		if err != nil {
+			return nil, err
+		}
+		puts = append(puts, types.TransactWriteItem{Put: &types.Put{TableName: aws.String(r.TableName), Item: item}})
+	}
+	return puts, nil
+}
+
+func (r EggRepository) CreateTeam(ctx context.Context, admin TeamMember) error {
+	header, err := attributevalue.MarshalMap(teamItem{
+		Partition:  teamPartition(admin.Team),
+		SortKey:    teamHeaderSortKey,
+		ItemType:   itemTypeTeam,
+		TeamMember: TeamMember{Team: admin.Team, AddedBy: admin.Member, AddedAt: admin.AddedAt},
+	})
+	if err != nil {
+		return err
+	}
+	puts, err := r.memberPuts(admin)
+	if err != nil {
+		return err
+	}
+	_, err = r.DynamoDbClient.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{
+		TransactItems: append([]types.TransactWriteItem{{Put: &types.Put{
+			TableName:           aws.String(r.TableName),
+			Item:                header,
+			ConditionExpression: aws.String("attribute_not_exists(SecretID)"),
+		}}}, puts...),
+	})
+	var canceled *types.TransactionCanceledException
+	if errors.As(err, &canceled) {
+		return ErrTeamExists
+	}
+	if err != nil {
+		log.Printf("Couldn't create team %v. Here's why: %v\n", admin.Team, err)
+	}
+	return err
+}
+
+func (r EggRepository) GetTeamMember(ctx context.Context, team, member string) (TeamMember, error) {
+	response, err := r.DynamoDbClient.GetItem(ctx, &dynamodb.GetItemInput{
 		TableName: aws.String(r.TableName),
-		Item:      item,
+		Key: map[string]types.AttributeValue{
+			"Owner":    &types.AttributeValueMemberS{Value: teamPartition(team)},
+			"SecretID": &types.AttributeValueMemberS{Value: member},
+		},
+		ConsistentRead: aws.Bool(true),
 	})
 	if err != nil {
-		log.Printf("Couldn't put an item. Here's why: %v\n", err)
+		log.Printf("Couldn't get member %v of team %v. Here's why: %v\n", member, team, err)
+		return TeamMember{}, err
+	}
+	if response.Item == nil {
+		return TeamMember{}, ErrMemberNotFound
+	}
+	var row teamItem
+	err = attributevalue.UnmarshalMap(response.Item, &row)
+	return row.TeamMember, err
+}
+
+func (r EggRepository) ListTeamMembers(ctx context.Context, team string) ([]TeamMember, error) {
+	return r.queryTeamItems(ctx, teamPartition(team), itemTypeMember)
+}
+
+func (r EggRepository) ListMemberTeams(ctx context.Context, member string) ([]TeamMember, error) {
+	return r.queryTeamItems(ctx, memberTeamsPartition(member), itemTypeMembership)
+}
+
+func (r EggRepository) PutTeamMember(ctx context.Context, m TeamMember) error {
+	puts, err := r.memberPuts(m)
+	if err != nil {
+		return err
+	}
+	err = r.changeMembers(ctx, m.Team, m.Member, m.Role, puts)
+	if err != nil && !errors.Is(err, ErrLastAdmin) {
+		log.Printf("Couldn't put member %v of team %v. Here's why: %v\n", m.Member, m.Team, err)
 	}
 	return err
 }
 
-func (r EggRepository) BreakEgg(ctx context.Context, owner, secretID string) error {
-	params, err := attributevalue.MarshalList([]interface{}{owner, secretID})
+func (r EggRepository) RemoveTeamMember(ctx context.Context, team, member string) error {
+	var deletes []types.TransactWriteItem
+	for _, key := range [][2]string{
+		{teamPartition(team), member},
+		{memberTeamsPartition(member), team},
+	} {
+		deletes = append(deletes, types.TransactWriteItem{Delete: &types.Delete{
+			TableName: aws.String(r.TableName),
+			Key: map[string]types.AttributeValue{
+				"Owner":    &types.AttributeValueMemberS{Value: key[0]},
+				"SecretID": &types.AttributeValueMemberS{Value: key[1]},
+			},
+		}})
+	}
+	err := r.changeMembers(ctx, team, member, "", deletes)
+	if err != nil && !errors.Is(err, ErrLastAdmin) {
+		log.Printf("Couldn't remove member %v of team %v. Here's why: %v\n", member, team, err)
+	}
+	return err
+}
+
+// changeMembers writes a change to member's rows, giving them role or
+// removing them if role is empty, unless that leaves the team without an
+// admin. Every change bumps the Revision of the team's header row in the same
+// transaction, guarded on the revision read before the check, so of two
+// concurrent changes one fails and is checked again against the other's
+// result.
+func (r EggRepository) changeMembers(ctx context.Context, team, member string, role Role, writes []types.TransactWriteItem) error {
+	for attempt := 0; attempt < 5; attempt++ {
+		revision, err := r.teamRevision(ctx, team)
+		if err != nil {
+			return err
+		}
+		members, err := r.ListTeamMembers(ctx, team)
+		if err != nil {
+			return err
+		}
+		if leavesNoAdmin(members, member, role) {
+			return ErrLastAdmin
+		}
+
+		condition := "Revision = :revision"
+		if revision == 0 {
+			// Teams created before revisions were kept
+			condition = "attribute_exists(SecretID) AND attribute_not_exists(Revision)"
+		}
+		bump := types.TransactWriteItem{Update: &types.Update{
+			TableName: aws.String(r.TableName),
+			Key: map[string]types.AttributeValue{
+				"Owner":    &types.AttributeValueMemberS{Value: teamPartition(team)},
+				"SecretID": &types.AttributeValueMemberS{Value: teamHeaderSortKey},
+			},
+			UpdateExpression:    aws.String("SET Revision = :next"),
+			ConditionExpression: aws.String(condition),
+			ExpressionAttributeValues: map[string]types.AttributeValue{
+				":revision": &types.AttributeValueMemberN{Value: strconv.Itoa(revision)},
+				":next":     &types.AttributeValueMemberN{Value: strconv.Itoa(revision + 1)},
+			},
+		}}
+		if revision == 0 {
+			delete(bump.Update.ExpressionAttributeValues, ":revision")
+		}
+
+		_, err = r.DynamoDbClient.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{
+			TransactItems: append([]types.TransactWriteItem{bump}, writes...),
+		})
+		var canceled *types.TransactionCanceledException
+		if !errors.As(err, &canceled) {
+			return err
+		}
+	}
+	return ErrTeamConflict
+}
+
+// teamRevision reads the Revision of a team's header row, which is zero
+// until its membership first changes.
+func (r EggRepository) teamRevision(ctx context.Context, team string) (int, error) {
+	response, err := r.DynamoDbClient.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(r.TableName),
+		Key: map[string]types.AttributeValue{
+			"Owner":    &types.AttributeValueMemberS{Value: teamPartition(team)},
+			"SecretID": &types.AttributeValueMemberS{Value: teamHeaderSortKey},
+		},
+		ConsistentRead: aws.Bool(true),
+	})
 	if err != nil {
-		panic(err)
+		return 0, err
+	}
+	if response.Item == nil {
+		return 0, fmt.Errorf("team %s has no header row", team)
 	}
-	_, err = r.DynamoDbClient.ExecuteStatement(ctx, &dynamodb.ExecuteStatementInput{
-		Statement: aws.String(
-			fmt.Sprintf("DELETE FROM \"%v\" WHERE Owner=? AND SecretID=?",
-				r.TableName)),
-		Parameters: params,
+	var header struct {
+		Revision int `dynamodbav:"Revision"`
+	}
+	err = attributevalue.UnmarshalMap(response.Item, &header)
+	return header.Revision, err
+}
+
+// queryTeamItems returns the rows of one ItemType in a membership partition.
+func (r EggRepository) queryTeamItems(ctx context.Context, partition, itemType string) ([]TeamMember, error) {
+	var members []TeamMember
+	paginator := dynamodb.NewQueryPaginator(r.DynamoDbClient, &dynamodb.QueryInput{
+		TableName:                aws.String(r.TableName),
+		KeyConditionExpression:   aws.String("#owner = :partition"),
+		FilterExpression:         aws.String("ItemType = :type"),
+		ExpressionAttributeNames: map[string]string{"#owner": "Owner"},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":partition": &types.AttributeValueMemberS{Value: partition},
+			":type":      &types.AttributeValueMemberS{Value: itemType},
+		},
+		ConsistentRead: aws.Bool(true),
+	})
+	for paginator.HasMorePages() {
+		response, err := paginator.NextPage(ctx)
+		if err != nil {
+			log.Printf("Couldn't query %v. Here's why: %v\n", partition, err)
+			return members, err
+		}
+		var rows []teamItem
+		if err := attributevalue.UnmarshalListOfMaps(response.Items, &rows); err != nil {
+			return members, err
+		}
+		for _, row := range rows {
+			members = append(members, row.TeamMember)
+		}
+	}
+	return members, nil
+}
+
+// Audit events live in the same table, one partition per vault, sorted by
+// sequence number.
+const itemTypeAudit = "audit"
+
+type auditItem struct {
+	Partition string `dynamodbav:"Owner"`
+	SortKey   string `dynamodbav:"SecretID"`
+	ItemType  string `dynamodbav:"ItemType"`
+	AuditEvent
+}
+
+func auditPartition(vault string) string {
+	return "AUDIT#" + vault
+}
+
+// auditSortKey zero-pads the sequence number so lexical order matches numeric
+// order.
+func auditSortKey(seq int) string {
+	return fmt.Sprintf("%020d", seq)
+}
+
+func (r EggRepository) PutAuditEvent(ctx context.Context, event AuditEvent) error {
+	// AuditEvent.SecretID would clash with the sort key, so store it apart
+	item, err := attributevalue.MarshalMap(auditItem{
+		Partition:  auditPartition(event.Vault),
+		SortKey:    auditSortKey(event.Seq),
+		ItemType:   itemTypeAudit,
+		AuditEvent: event,
 	})
 	if err != nil {
-		log.Printf("Couldn't delete that egg from the table. Here's why: %v\n", err)
+		return err
+	}
+	item["SecretID"] = &types.AttributeValueMemberS{Value: auditSortKey(event.Seq)}
+	item["AuditSecretID"] = &types.AttributeValueMemberS{Value: event.SecretID}
+
+	// This keeps concurrent appends from replacing each other. IAM can't
+	// require it, so a plain PutItem with the Lambda role can still replace an
+	// event; the MAC it can't forge offline is what exposes that
+	_, err = r.DynamoDbClient.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName:           aws.String(r.TableName),
+		Item:                item,
+		ConditionExpression: aws.String("attribute_not_exists(SecretID)"),
+	})
+	var conditionFailed *types.ConditionalCheckFailedException
+	if errors.As(err, &conditionFailed) {
+		return ErrAuditConflict
+	}
+	if err != nil {
+		log.Printf("Couldn't put audit event for %v. Here's why: %v\n", event.Vault, err)
 	}
 	return err
 }
+
+func (r EggRepository) LastAuditEvent(ctx context.Context, vault string) (AuditEvent, error) {
+	response, err := r.DynamoDbClient.Query(ctx, &dynamodb.QueryInput{
+		TableName:                aws.String(r.TableName),
+		KeyConditionExpression:   aws.String("#owner = :partition"),
+		ExpressionAttributeNames: map[string]string{"#owner": "Owner"},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":partition": &types.AttributeValueMemberS{Value: auditPartition(vault)},
+		},
+		ScanIndexForward: aws.Bool(false),
+		Limit:            aws.Int32(1),
+		ConsistentRead:   aws.Bool(true),
+	})
+	if err != nil {
+		log.Printf("Couldn't get the last audit event for %v. Here's why: %v\n", vault, err)
+		return AuditEvent{}, err
+	}
+	if len(response.Items) == 0 {
+		return AuditEvent{}, nil
+	}
+	return unmarshalAuditItem(response.Items[0])
+}
+
+func (r EggRepository) ListAuditEvents(ctx context.Context, vault string) ([]AuditEvent, error) {
+	var events []AuditEvent
+	paginator := dynamodb.NewQueryPaginator(r.DynamoDbClient, &dynamodb.QueryInput{
+		TableName:                aws.String(r.TableName),
+		KeyConditionExpression:   aws.String("#owner = :partition"),
+		ExpressionAttributeNames: map[string]string{"#owner": "Owner"},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":partition": &types.AttributeValueMemberS{Value: auditPartition(vault)},
+		},
+		ConsistentRead: aws.Bool(true),
+	})
+	for paginator.HasMorePages() {
+		response, err := paginator.NextPage(ctx)
+		if err != nil {
+			log.Printf("Couldn't list audit events for %v. Here's why: %v\n", vault, err)
+			return events, err
+		}
+		for _, item := range response.Items {
+			event, err := unmarshalAuditItem(item)
+			if err != nil {
+				return events, err
+			}
+			events = append(events, event)
+		}
+	}
+	return events, nil
+}
+
+func unmarshalAuditItem(item map[string]types.AttributeValue) (AuditEvent, error) {
+	var row auditItem
+	if err := attributevalue.UnmarshalMap(item, &row); err != nil {
+		return AuditEvent{}, err
+	}
+	row.AuditEvent.SecretID = ""
+	if secretID, ok := item["AuditSecretID"].(*types.AttributeValueMemberS); ok {
+		row.AuditEvent.SecretID = secretID.Value
+	}
+	return row.AuditEvent, nil
+}
+
+// currentVersion is the version a write has to expect to replace current.
+// Eggs laid before versioning existed count as version 1.
+func currentVersion(current Egg, exists bool) int {
+	switch {
+	case !exists:
+		return NoVersion
+	case current.Version == 0:
+		return 1
+	default:
+		return current.Version
+	}
+}
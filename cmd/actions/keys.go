@@ -0,0 +1,62 @@
+package actions
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+	"github.com/owenHochwald/egg-carton/pkg/crypto"
+)
+
+// NewKeyProviderFromEnv builds the data key provider selected by the
+// environment:
+//
+//	KEY_PROVIDER    kms (default) or local
+//	KMS_KEY_ID      KMS key ID, ARN or alias for the kms provider
+//	MASTER_KEY_FILE master key for the local provider (default eggcarton.key),
+//	                created on first use
+func NewKeyProviderFromEnv(ctx context.Context) (crypto.KeyProvider, error) {
+	switch provider := os.Getenv("KEY_PROVIDER"); provider {
+	case "", "kms":
+		cfg, err := config.LoadDefaultConfig(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("unable to load SDK config: %w", err)
+		}
+		return crypto.NewKMSKeyProvider(kms.NewFromConfig(cfg), os.Getenv("KMS_KEY_ID")), nil
+	case "local":
+		path := os.Getenv("MASTER_KEY_FILE")
+		if path == "" {
+			path = "eggcarton.key"
+		}
+		return crypto.NewLocalKeyProvider(path)
+	default:
+		return nil, fmt.Errorf("unknown KEY_PROVIDER %q", provider)
+	}
+}
+
+// NewAuditKeyFromEnv builds the key audit events are signed with, from the
+// same KEY_PROVIDER as NewKeyProviderFromEnv:
+//
+//	AUDIT_KEY_ID   KMS HMAC key ID, ARN or alias for the kms provider
+//	AUDIT_KEY_FILE key for the local provider (default eggcarton-audit.key),
+//	               created on first use
+func NewAuditKeyFromEnv(ctx context.Context) (crypto.MACKey, error) {
+	switch provider := os.Getenv("KEY_PROVIDER"); provider {
+	case "", "kms":
+		cfg, err := config.LoadDefaultConfig(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("unable to load SDK config: %w", err)
+		}
+		return crypto.NewKMSMACKey(kms.NewFromConfig(cfg), os.Getenv("AUDIT_KEY_ID")), nil
+	case "local":
+		path := os.Getenv("AUDIT_KEY_FILE")
+		if path == "" {
+			path = "eggcarton-audit.key"
+		}
+		return crypto.NewLocalMACKey(path)
+	default:
+		return nil, fmt.Errorf("unknown KEY_PROVIDER %q", provider)
+	}
+}
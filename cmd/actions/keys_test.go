@@ -0,0 +1,60 @@
+package actions
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/owenHochwald/egg-carton/pkg/crypto"
+)
+
+// TestLocalLayAndHatch runs the put_egg/get_egg encryption path end to end
+// with no AWS access: a local master key and the in-memory backend.
+func TestLocalLayAndHatch(t *testing.T) {
+	ctx := context.Background()
+	t.Setenv("KEY_PROVIDER", "local")
+	t.Setenv("MASTER_KEY_FILE", filepath.Join(t.TempDir(), "master.key"))
+	keyProvider, err := NewKeyProviderFromEnv(ctx)
+	if err != nil {
+		t.Fatalf("NewKeyProviderFromEnv: %v", err)
+	}
+	repo := NewMemoryEggRepository()
+
+	// Lay
+	egg := Egg{
+		Owner:     "alice",
+		SecretID:  "API_KEY",
+		CreatedAt: time.Now().Format(time.RFC3339),
+		Bound:     true,
+	}
+	dataKey, err := keyProvider.GenerateDataKey(ctx, egg.EncryptionContext())
+	if err != nil {
+		t.Fatalf("GenerateDataKey: %v", err)
+	}
+	egg.Ciphertext, err = crypto.Seal(crypto.AES256GCM, dataKey.Plaintext, dataKey.KeyRef, []byte("sk-secret"), egg.AdditionalData())
+	if err != nil {
+		t.Fatalf("Seal: %v", err)
+	}
+	egg.EncryptedDataKey = dataKey.Encrypted
+	if _, err := repo.PutEgg(ctx, egg, AnyVersion); err != nil {
+		t.Fatalf("PutEgg: %v", err)
+	}
+
+	// Get and hatch
+	stored, err := repo.GetEgg(ctx, "alice", "API_KEY")
+	if err != nil {
+		t.Fatalf("GetEgg: %v", err)
+	}
+	key, err := keyProvider.DecryptDataKey(ctx, stored.EncryptedDataKey, stored.EncryptionContext())
+	if err != nil {
+		t.Fatalf("DecryptDataKey: %v", err)
+	}
+	plaintext, err := crypto.Open(stored.Ciphertext, key, stored.AdditionalData())
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if string(plaintext) != "sk-secret" {
+		t.Errorf("got %q, want sk-secret", plaintext)
+	}
+}
@@ -0,0 +1,307 @@
+package actions
+
+import (
+	"bytes"
+	"context"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// MemoryEggRepository is an in-memory implementation of EggActions. It is
+// intended for tests and local development; nothing survives a restart.
+type MemoryEggRepository struct {
+	mu       sync.RWMutex
+	eggs     map[string]map[string]Egg
+	versions map[string][]Egg // keyed by versionPartition, oldest first
+	teams    map[string]map[string]TeamMember
+	audit    map[string][]AuditEvent // keyed by vault, oldest first
+}
+
+func NewMemoryEggRepository() *MemoryEggRepository {
+	return &MemoryEggRepository{
+		eggs:     make(map[string]map[string]Egg),
+		versions: make(map[string][]Egg),
+		teams:    make(map[string]map[string]TeamMember),
+		audit:    make(map[string][]AuditEvent),
+	}
+}
+
+func (r *MemoryEggRepository) GetEgg(ctx context.Context, owner, secretID string) (Egg, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	egg, ok := r.eggs[owner][secretID]
+	if !ok {
+		return Egg{}, ErrEggNotFound
+	}
+	return copyEgg(egg), nil
+}
+
+func (r *MemoryEggRepository) GetAllEggs(ctx context.Context, owner string) ([]Egg, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var eggs []Egg
+	for _, egg := range r.eggs[owner] {
+		eggs = append(eggs, copyEgg(egg))
+	}
+
+	// Match DynamoDB, which returns a partition ordered by sort key
+	sort.Slice(eggs, func(i, j int) bool { return eggs[i].SecretID < eggs[j].SecretID })
+	return eggs, nil
+}
+
+func (r *MemoryEggRepository) GetEggsWithPrefix(ctx context.Context, owner, prefix string) ([]Egg, error) {
+	eggs, err := r.GetAllEggs(ctx, owner)
+	if err != nil {
+		return nil, err
+	}
+	var matched []Egg
+	for _, egg := range eggs {
+		if strings.HasPrefix(egg.SecretID, prefix) {
+			matched = append(matched, egg)
+		}
+	}
+	return matched, nil
+}
+
+func (r *MemoryEggRepository) ListEggs(ctx context.Context, owner, prefix, cursor string, limit int) ([]Egg, string, error) {
+	eggs, err := r.GetEggsWithPrefix(ctx, owner, prefix)
+	if err != nil {
+		return nil, "", err
+	}
+	return pageOf(eggs, cursor, limit)
+}
+
+func (r *MemoryEggRepository) PutEgg(ctx context.Context, egg Egg, expected int) (Egg, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	current, exists := r.eggs[egg.Owner][egg.SecretID]
+	if expected != AnyVersion && currentVersion(current, exists) != expected {
+		return egg, ErrEggConflict
+	}
+	if r.eggs[egg.Owner] == nil {
+		r.eggs[egg.Owner] = make(map[string]Egg)
+	}
+	partition := versionPartition(egg.Owner, egg.SecretID)
+	egg.Version = current.Version + 1
+	if history := r.versions[partition]; len(history) > 0 {
+		// Older versions can outlive an expired current row
+		egg.Version = max(egg.Version, history[len(history)-1].Version+1)
+	}
+	r.eggs[egg.Owner][egg.SecretID] = copyEgg(egg)
+	history := copyEgg(egg)
+	history.History = true
+	r.versions[partition] = append(r.versions[partition], history)
+	return egg, nil
+}
+
+func (r *MemoryEggRepository) BreakEgg(ctx context.Context, owner, secretID string, expected int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	current, exists := r.eggs[owner][secretID]
+	if expected != AnyVersion && (!exists || currentVersion(current, exists) != expected) {
+		return ErrEggConflict
+	}
+	delete(r.eggs[owner], secretID)
+	delete(r.versions, versionPartition(owner, secretID))
+	return nil
+}
+
+func (r *MemoryEggRepository) PutEggs(ctx context.Context, eggs []Egg, expected []int) ([]Egg, []error) {
+	return putEggsOneByOne(ctx, r, eggs, expected)
+}
+
+func (r *MemoryEggRepository) BreakEggs(ctx context.Context, owner string, secretIDs []string) []error {
+	return breakEggsOneByOne(ctx, r, owner, secretIDs)
+}
+
+func (r *MemoryEggRepository) ListEggVersions(ctx context.Context, owner, secretID string) ([]Egg, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var eggs []Egg
+	for _, egg := range r.versions[versionPartition(owner, secretID)] {
+		eggs = append(eggs, copyEgg(egg))
+	}
+	return eggs, nil
+}
+
+func (r *MemoryEggRepository) GetEggVersion(ctx context.Context, owner, secretID string, version int) (Egg, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, egg := range r.versions[versionPartition(owner, secretID)] {
+		if egg.Version == version {
+			return copyEgg(egg), nil
+		}
+	}
+	return Egg{}, ErrEggNotFound
+}
+
+func (r *MemoryEggRepository) ScanEggs(ctx context.Context, cursor string, limit int) ([]Egg, string, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	// Current rows by owner and SecretID, then history by partition
+	var all []Egg
+	for _, owner := range sortedKeys(r.eggs) {
+		for _, secretID := range sortedKeys(r.eggs[owner]) {
+			all = append(all, copyEgg(r.eggs[owner][secretID]))
+		}
+	}
+	for _, partition := range sortedKeys(r.versions) {
+		for _, egg := range r.versions[partition] {
+			all = append(all, copyEgg(egg))
+		}
+	}
+	return pageOf(all, cursor, limit)
+}
+
+func (r *MemoryEggRepository) UpdateEgg(ctx context.Context, egg Egg) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if egg.History {
+		versions := r.versions[versionPartition(egg.Owner, egg.SecretID)]
+		for i := range versions {
+			if versions[i].Version == egg.Version {
+				versions[i] = copyEgg(egg)
+				return nil
+			}
+		}
+		return ErrEggConflict
+	}
+
+	current, ok := r.eggs[egg.Owner][egg.SecretID]
+	if !ok || current.Version != egg.Version {
+		return ErrEggConflict
+	}
+	r.eggs[egg.Owner][egg.SecretID] = copyEgg(egg)
+	return nil
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// copyEgg detaches the byte slices of an egg so callers can't mutate stored
+// state through a shared backing array.
+func copyEgg(egg Egg) Egg {
+	egg.Ciphertext = bytes.Clone(egg.Ciphertext)
+	egg.EncryptedDataKey = bytes.Clone(egg.EncryptedDataKey)
+	return egg
+}
+
+func (r *MemoryEggRepository) CreateTeam(ctx context.Context, admin TeamMember) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.teams[admin.Team]; ok {
+		return ErrTeamExists
+	}
+	r.teams[admin.Team] = map[string]TeamMember{admin.Member: admin}
+	return nil
+}
+
+func (r *MemoryEggRepository) GetTeamMember(ctx context.Context, team, member string) (TeamMember, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	m, ok := r.teams[team][member]
+	if !ok {
+		return TeamMember{}, ErrMemberNotFound
+	}
+	return m, nil
+}
+
+func (r *MemoryEggRepository) ListTeamMembers(ctx context.Context, team string) ([]TeamMember, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.teamMembers(team), nil
+}
+
+func (r *MemoryEggRepository) PutTeamMember(ctx context.Context, m TeamMember) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if leavesNoAdmin(r.teamMembers(m.Team), m.Member, m.Role) {
+		return ErrLastAdmin
+	}
+	if r.teams[m.Team] == nil {
+		r.teams[m.Team] = make(map[string]TeamMember)
+	}
+	r.teams[m.Team][m.Member] = m
+	return nil
+}
+
+func (r *MemoryEggRepository) RemoveTeamMember(ctx context.Context, team, member string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if leavesNoAdmin(r.teamMembers(team), member, "") {
+		return ErrLastAdmin
+	}
+	delete(r.teams[team], member)
+	return nil
+}
+
+// teamMembers lists a team's members; the caller holds r.mu.
+func (r *MemoryEggRepository) teamMembers(team string) []TeamMember {
+	var members []TeamMember
+	for _, member := range sortedKeys(r.teams[team]) {
+		members = append(members, r.teams[team][member])
+	}
+	return members
+}
+
+func (r *MemoryEggRepository) ListMemberTeams(ctx context.Context, member string) ([]TeamMember, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var teams []TeamMember
+	for _, team := range sortedKeys(r.teams) {
+		if m, ok := r.teams[team][member]; ok {
+			teams = append(teams, m)
+		}
+	}
+	return teams, nil
+}
+
+func (r *MemoryEggRepository) PutAuditEvent(ctx context.Context, event AuditEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if event.Seq != len(r.audit[event.Vault])+1 {
+		return ErrAuditConflict
+	}
+	r.audit[event.Vault] = append(r.audit[event.Vault], event)
+	return nil
+}
+
+func (r *MemoryEggRepository) LastAuditEvent(ctx context.Context, vault string) (AuditEvent, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	chain := r.audit[vault]
+	if len(chain) == 0 {
+		return AuditEvent{}, nil
+	}
+	return chain[len(chain)-1], nil
+}
+
+func (r *MemoryEggRepository) ListAuditEvents(ctx context.Context, vault string) ([]AuditEvent, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return append([]AuditEvent(nil), r.audit[vault]...), nil
+}
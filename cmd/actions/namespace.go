@@ -0,0 +1,65 @@
+package actions
+
+import (
+	"fmt"
+	"strings"
+)
+
+// namespaceSeparator joins project, environment and name in a SecretID.
+const namespaceSeparator = "#"
+
+// Namespace scopes secrets to a project and environment, so that the prod and
+// dev DATABASE_URL of one owner don't collide. It is stored as a prefix of the
+// SecretID sort key ("api#prod#DATABASE_URL"), which keeps every secret of a
+// namespace in one contiguous range that GetEggsWithPrefix can query.
+//
+// The zero Namespace is the flat, unscoped list that existed before
+// namespaces: its SecretIDs carry no prefix.
+type Namespace struct {
+	Project string
+	Env     string
+}
+
+// NewNamespace validates project and env. Neither may contain the separator.
+func NewNamespace(project, env string) (Namespace, error) {
+	if strings.Contains(project, namespaceSeparator) || strings.Contains(env, namespaceSeparator) {
+		return Namespace{}, fmt.Errorf("project and env may not contain %q", namespaceSeparator)
+	}
+	return Namespace{Project: project, Env: env}, nil
+}
+
+// IsZero reports whether n is the unscoped namespace.
+func (n Namespace) IsZero() bool {
+	return n.Project == "" && n.Env == ""
+}
+
+// Prefix returns the SecretID prefix shared by every secret in n.
+func (n Namespace) Prefix() string {
+	if n.IsZero() {
+		return ""
+	}
+	return n.Project + namespaceSeparator + n.Env + namespaceSeparator
+}
+
+// SecretID returns the stored SecretID of the secret called name in n.
+func (n Namespace) SecretID(name string) string {
+	return n.Prefix() + name
+}
+
+// SplitSecretID splits a stored SecretID into its namespace and name.
+func SplitSecretID(secretID string) (Namespace, string) {
+	parts := strings.SplitN(secretID, namespaceSeparator, 3)
+	if len(parts) != 3 {
+		return Namespace{}, secretID
+	}
+	return Namespace{Project: parts[0], Env: parts[1]}, parts[2]
+}
+
+// ValidateSecretName rejects names that would be mistaken for a namespaced
+// SecretID.
+func ValidateSecretName(name string) error {
+	if strings.Contains(name, namespaceSeparator) {
+		return fmt.Errorf("secret_id may not contain %q", namespaceSeparator)
+	}
+	return nil
+}
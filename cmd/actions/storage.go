@@ -0,0 +1,49 @@
+package actions
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+)
+
+const defaultTableName = "EggCarton-Eggs"
+
+// NewEggActionsFromEnv builds the storage backend selected by the environment:
+//
+//	STORAGE_BACKEND   dynamodb (default), memory or bolt
+//	TABLE_NAME        DynamoDB table name (default EggCarton-Eggs)
+//	DYNAMODB_ENDPOINT optional endpoint override, e.g. DynamoDB Local
+//	BOLT_PATH         database file for the bolt backend (default eggcarton.db)
+func NewEggActionsFromEnv(ctx context.Context) (EggActions, error) {
+	switch backend := os.Getenv("STORAGE_BACKEND"); backend {
+	case "", "dynamodb":
+		cfg, err := config.LoadDefaultConfig(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("unable to load SDK config: %w", err)
+		}
+		dynamoClient := dynamodb.NewFromConfig(cfg, func(o *dynamodb.Options) {
+			if endpoint := os.Getenv("DYNAMODB_ENDPOINT"); endpoint != "" {
+				o.BaseEndpoint = aws.String(endpoint)
+			}
+		})
+		tableName := os.Getenv("TABLE_NAME")
+		if tableName == "" {
+			tableName = defaultTableName
+		}
+		return NewEggRepository(dynamoClient, tableName), nil
+	case "memory":
+		return NewMemoryEggRepository(), nil
+	case "bolt":
+		path := os.Getenv("BOLT_PATH")
+		if path == "" {
+			path = "eggcarton.db"
+		}
+		return NewBoltEggRepository(path)
+	default:
+		return nil, fmt.Errorf("unknown STORAGE_BACKEND %q", backend)
+	}
+}
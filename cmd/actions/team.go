@@ -0,0 +1,169 @@
+package actions
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var (
+	ErrForbidden      = errors.New("forbidden")
+	ErrTeamExists     = errors.New("team already exists")
+	ErrMemberNotFound = errors.New("team member not found")
+	ErrLastAdmin      = errors.New("a team must keep at least one admin")
+	ErrTeamConflict   = errors.New("team membership was modified concurrently")
+)
+
+// Role is a team member's level of access to the team vault. Each role
+// includes the ones before it.
+type Role string
+
+const (
+	RoleReader Role = "reader" // Read and hatch secrets
+	RoleWriter Role = "writer" // Also lay, break and roll back secrets
+	RoleAdmin  Role = "admin"  // Also manage members
+)
+
+var roleRank = map[Role]int{RoleReader: 1, RoleWriter: 2, RoleAdmin: 3}
+
+// Valid reports whether r is one of the defined roles.
+func (r Role) Valid() bool {
+	return roleRank[r] > 0
+}
+
+// Allows reports whether r grants at least the access of required.
+func (r Role) Allows(required Role) bool {
+	return roleRank[r] >= roleRank[required]
+}
+
+// TeamMember is one member's role in one team.
+type TeamMember struct {
+	Team    string `dynamodbav:"Team" json:"team"`
+	Member  string `dynamodbav:"Member" json:"member"` // Cognito sub
+	Role    Role   `dynamodbav:"Role" json:"role"`
+	AddedBy string `dynamodbav:"AddedBy,omitempty" json:"added_by,omitempty"`
+	AddedAt string `dynamodbav:"AddedAt" json:"added_at"`
+}
+
+// TeamActions manages team membership. Every storage backend implements it
+// alongside EggActions.
+type TeamActions interface {
+	// CreateTeam registers a new team with admin as its first member. It
+	// returns ErrTeamExists if the name is taken.
+	CreateTeam(ctx context.Context, admin TeamMember) error
+	GetTeamMember(ctx context.Context, team, member string) (TeamMember, error)
+	ListTeamMembers(ctx context.Context, team string) ([]TeamMember, error)
+	// PutTeamMember adds a member or changes their role. It returns
+	// ErrLastAdmin, changing nothing, rather than demote the team's last
+	// admin. The check and the write are atomic, so concurrent changes
+	// can't both pass it.
+	PutTeamMember(ctx context.Context, m TeamMember) error
+	// RemoveTeamMember removes a member, returning ErrLastAdmin instead if
+	// they are the team's last admin. As with PutTeamMember, the check and
+	// the write are atomic.
+	RemoveTeamMember(ctx context.Context, team, member string) error
+	// ListMemberTeams returns every team member belongs to.
+	ListMemberTeams(ctx context.Context, member string) ([]TeamMember, error)
+}
+
+// teamOwnerPrefix marks the Owner of a team vault. Cognito subs are UUIDs, so
+// they can never collide with it.
+const teamOwnerPrefix = "team:"
+
+var teamNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,63}$`)
+
+// ValidateTeamName checks that name is usable as a team name.
+func ValidateTeamName(name string) error {
+	if !teamNamePattern.MatchString(name) {
+		return fmt.Errorf("team name must be 1-64 lowercase letters, digits, '-' or '_'")
+	}
+	return nil
+}
+
+// TeamOwner returns the Owner under which a team's secrets are stored.
+func TeamOwner(team string) string {
+	return teamOwnerPrefix + team
+}
+
+// TeamFromOwner returns the team a vault Owner belongs to, if it is a team
+// vault.
+func TeamFromOwner(owner string) (string, bool) {
+	return strings.CutPrefix(owner, teamOwnerPrefix)
+}
+
+// cognitoGroupPrefix names Cognito groups that grant team roles, e.g.
+// members of "eggcarton-backend-writer" are writers on team "backend".
+const cognitoGroupPrefix = "eggcarton-"
+
+// groupRole returns the highest role the caller's Cognito groups grant on
+// team. API Gateway passes the cognito:groups claim as "[group1 group2]".
+func groupRole(claims map[string]string, team string) Role {
+	var best Role
+	groups := strings.Trim(claims["cognito:groups"], "[]")
+	for _, group := range strings.FieldsFunc(groups, func(r rune) bool { return r == ' ' || r == ',' }) {
+		rest, ok := strings.CutPrefix(group, cognitoGroupPrefix)
+		if !ok {
+			continue
+		}
+		i := strings.LastIndex(rest, "-")
+		if i < 0 || rest[:i] != team {
+			continue
+		}
+		if role := Role(rest[i+1:]); role.Valid() && !best.Allows(role) {
+			best = role
+		}
+	}
+	return best
+}
+
+// Authorize checks that the caller identified by the JWT claims holds at least
+// required on owner's vault. Everyone is an admin of their own vault. On a
+// team vault the caller's role is the higher of their membership role and any
+// role granted by Cognito groups. It returns ErrForbidden if access is denied.
+func Authorize(ctx context.Context, teams TeamActions, claims map[string]string, owner string, required Role) error {
+	caller := claims["sub"]
+	if caller == "" {
+		return ErrForbidden
+	}
+	if owner == caller {
+		return nil
+	}
+	team, ok := TeamFromOwner(owner)
+	if !ok {
+		return ErrForbidden
+	}
+
+	role := groupRole(claims, team)
+	if role.Allows(required) {
+		return nil
+	}
+	member, err := teams.GetTeamMember(ctx, team, caller)
+	if errors.Is(err, ErrMemberNotFound) {
+		return ErrForbidden
+	}
+	if err != nil {
+		return err
+	}
+	if !member.Role.Allows(required) {
+		return ErrForbidden
+	}
+	return nil
+}
+
+// leavesNoAdmin reports whether giving member role, or removing them if
+// role is empty, would leave a team of members without an admin.
+func leavesNoAdmin(members []TeamMember, member string, role Role) bool {
+	if role == RoleAdmin {
+		return false
+	}
+	admins, isAdmin := 0, false
+	for _, m := range members {
+		if m.Role == RoleAdmin {
+			admins++
+			isAdmin = isAdmin || m.Member == member
+		}
+	}
+	return isAdmin && admins == 1
+}
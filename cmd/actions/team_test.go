@@ -0,0 +1,47 @@
+package actions
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestAuthorize(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMemoryEggRepository()
+	if err := repo.CreateTeam(ctx, TeamMember{Team: "backend", Member: "alice", Role: RoleAdmin}); err != nil {
+		t.Fatalf("CreateTeam: %v", err)
+	}
+	if err := repo.PutTeamMember(ctx, TeamMember{Team: "backend", Member: "bob", Role: RoleReader}); err != nil {
+		t.Fatalf("PutTeamMember: %v", err)
+	}
+
+	for _, tc := range []struct {
+		name     string
+		claims   map[string]string
+		owner    string
+		required Role
+		allowed  bool
+	}{
+		{"own vault", map[string]string{"sub": "bob"}, "bob", RoleAdmin, true},
+		{"someone else's vault", map[string]string{"sub": "bob"}, "alice", RoleReader, false},
+		{"reader reads", map[string]string{"sub": "bob"}, TeamOwner("backend"), RoleReader, true},
+		{"reader writes", map[string]string{"sub": "bob"}, TeamOwner("backend"), RoleWriter, false},
+		{"admin writes", map[string]string{"sub": "alice"}, TeamOwner("backend"), RoleWriter, true},
+		{"non-member", map[string]string{"sub": "mallory"}, TeamOwner("backend"), RoleReader, false},
+		{"group grants writer", map[string]string{"sub": "bob", "cognito:groups": "[staff eggcarton-backend-writer]"}, TeamOwner("backend"), RoleWriter, true},
+		{"group for another team", map[string]string{"sub": "mallory", "cognito:groups": "[eggcarton-frontend-admin]"}, TeamOwner("backend"), RoleReader, false},
+		{"no sub", map[string]string{}, TeamOwner("backend"), RoleReader, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := Authorize(ctx, repo, tc.claims, tc.owner, tc.required)
+			if tc.allowed && err != nil {
+				t.Errorf("got %v, want allowed", err)
+			}
+			if !tc.allowed && !errors.Is(err, ErrForbidden) {
+				t.Errorf("got %v, want ErrForbidden", err)
+			}
+		})
+	}
+
+}
@@ -0,0 +1,128 @@
+package actions
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+)
+
+// DefaultTrashRetention is how long a broken egg stays recoverable unless
+// TRASH_RETENTION says otherwise.
+const DefaultTrashRetention = 30 * 24 * time.Hour
+
+// TrashRetentionFromEnv reads the trash retention window from TRASH_RETENTION
+// (a Go duration such as 168h).
+func TrashRetentionFromEnv() (time.Duration, error) {
+	value := os.Getenv("TRASH_RETENTION")
+	if value == "" {
+		return DefaultTrashRetention, nil
+	}
+	retention, err := time.ParseDuration(value)
+	if err != nil || retention <= 0 {
+		return 0, fmt.Errorf("TRASH_RETENTION must be a positive duration, got %q", value)
+	}
+	return retention, nil
+}
+
+// TrashEgg moves a secret and its version history into the trash. The rows
+// stay where they are, marked with DeletedAt, and their ExpiresAt is brought
+// forward to the end of the retention window so DynamoDB's TTL purges them
+// if nobody restores the secret. It returns ErrEggNotFound if the secret
+// doesn't exist or is already in the trash, and ErrEggConflict if it isn't
+// at the expected version (or AnyVersion).
+//
+// History rows are marked before the current row, so if a write fails part
+// way the secret is still live and breaking it again finishes the job.
+func TrashEgg(ctx context.Context, repo EggActions, owner, secretID, deletedBy string, retention time.Duration, expected int) (Egg, error) {
+	current, err := repo.GetEgg(ctx, owner, secretID)
+	if err != nil {
+		return Egg{}, err
+	}
+	now := time.Now()
+	if current.Trashed() || current.Expired(now) {
+		return Egg{}, ErrEggNotFound
+	}
+	if expected != AnyVersion && current.Version != expected {
+		return Egg{}, ErrEggConflict
+	}
+
+	versions, err := repo.ListEggVersions(ctx, owner, secretID)
+	if err != nil {
+		return Egg{}, err
+	}
+	purgeAt := now.Add(retention).Unix()
+	for _, version := range versions {
+		if version.Trashed() {
+			continue
+		}
+		if err := repo.UpdateEgg(ctx, version.trash(deletedBy, now, purgeAt)); err != nil {
+			return Egg{}, err
+		}
+	}
+
+	trashed := current.trash(deletedBy, now, purgeAt)
+	return trashed, repo.UpdateEgg(ctx, trashed)
+}
+
+// RestoreTrashedEgg takes a secret back out of the trash with the expiry it
+// had before. It returns ErrEggNotFound if the secret isn't in the trash.
+//
+// The current row is restored last, so a failed restore can be retried.
+func RestoreTrashedEgg(ctx context.Context, repo EggActions, owner, secretID string) (Egg, error) {
+	current, err := repo.GetEgg(ctx, owner, secretID)
+	if err != nil {
+		return Egg{}, err
+	}
+	if !current.Trashed() || current.Expired(time.Now()) {
+		return Egg{}, ErrEggNotFound
+	}
+
+	versions, err := repo.ListEggVersions(ctx, owner, secretID)
+	if err != nil {
+		return Egg{}, err
+	}
+	for _, version := range versions {
+		if !version.Trashed() {
+			continue
+		}
+		if err := repo.UpdateEgg(ctx, version.restore()); err != nil {
+			return Egg{}, err
+		}
+	}
+
+	restored := current.restore()
+	return restored, repo.UpdateEgg(ctx, restored)
+}
+
+// TrashedEggs returns the eggs that are in the trash and not yet purged.
+func TrashedEggs(eggs []Egg, now time.Time) []Egg {
+	var trashed []Egg
+	for _, egg := range eggs {
+		if egg.Trashed() && !egg.Expired(now) {
+			trashed = append(trashed, egg)
+		}
+	}
+	return trashed
+}
+
+// trash marks the egg as deleted, to be purged at purgeAt or at its own
+// expiry if that comes first.
+func (e Egg) trash(deletedBy string, at time.Time, purgeAt int64) Egg {
+	e.DeletedAt = at.UTC().Format(time.RFC3339)
+	e.DeletedBy = deletedBy
+	e.TrashedExpiresAt = e.ExpiresAt
+	if e.ExpiresAt == 0 || purgeAt < e.ExpiresAt {
+		e.ExpiresAt = purgeAt
+	}
+	return e
+}
+
+// restore undoes trash.
+func (e Egg) restore() Egg {
+	e.ExpiresAt = e.TrashedExpiresAt
+	e.DeletedAt = ""
+	e.DeletedBy = ""
+	e.TrashedExpiresAt = 0
+	return e
+}
@@ -0,0 +1,63 @@
+package actions
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestTrashAndRestore(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMemoryEggRepository()
+	expiresAt := time.Now().Add(90 * 24 * time.Hour).Unix()
+	for _, value := range []string{"v1", "v2"} {
+		if _, err := repo.PutEgg(ctx, Egg{Owner: "alice", SecretID: "KEY", Ciphertext: []byte(value), ExpiresAt: expiresAt}, AnyVersion); err != nil {
+			t.Fatalf("PutEgg: %v", err)
+		}
+	}
+
+	trashed, err := TrashEgg(ctx, repo, "alice", "KEY", "bob", time.Hour, AnyVersion)
+	if err != nil {
+		t.Fatalf("TrashEgg: %v", err)
+	}
+	if !trashed.Trashed() || trashed.DeletedBy != "bob" || trashed.ExpiresAt >= expiresAt {
+		t.Errorf("TrashEgg: got %+v, want trashed by bob and purged within the hour", trashed)
+	}
+	eggs, _ := repo.GetAllEggs(ctx, "alice")
+	if live := LiveEggs(eggs, time.Now()); len(live) != 0 {
+		t.Errorf("LiveEggs: got %+v, want the trashed egg left out", live)
+	}
+	if inTrash := TrashedEggs(eggs, time.Now()); len(inTrash) != 1 {
+		t.Errorf("TrashedEggs: got %+v, want the trashed egg", inTrash)
+	}
+	if inTrash := TrashedEggs(eggs, time.Now().Add(2*time.Hour)); len(inTrash) != 0 {
+		t.Errorf("TrashedEggs after retention: got %+v, want it purged", inTrash)
+	}
+	versions, _ := repo.ListEggVersions(ctx, "alice", "KEY")
+	for _, version := range versions {
+		if !version.Trashed() {
+			t.Errorf("version %d wasn't trashed with the egg", version.Version)
+		}
+	}
+	if _, err := TrashEgg(ctx, repo, "alice", "KEY", "bob", time.Hour, AnyVersion); !errors.Is(err, ErrEggNotFound) {
+		t.Errorf("TrashEgg twice: got %v, want ErrEggNotFound", err)
+	}
+
+	restored, err := RestoreTrashedEgg(ctx, repo, "alice", "KEY")
+	if err != nil {
+		t.Fatalf("RestoreTrashedEgg: %v", err)
+	}
+	if restored.Trashed() || restored.ExpiresAt != expiresAt || restored.Version != 2 {
+		t.Errorf("RestoreTrashedEgg: got %+v, want version 2 live with its original expiry", restored)
+	}
+	versions, _ = repo.ListEggVersions(ctx, "alice", "KEY")
+	for _, version := range versions {
+		if version.Trashed() || version.ExpiresAt != expiresAt {
+			t.Errorf("version %d wasn't restored with the egg: %+v", version.Version, version)
+		}
+	}
+	if _, err := RestoreTrashedEgg(ctx, repo, "alice", "KEY"); !errors.Is(err, ErrEggNotFound) {
+		t.Errorf("RestoreTrashedEgg of a live egg: got %v, want ErrEggNotFound", err)
+	}
+}
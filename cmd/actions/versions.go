@@ -0,0 +1,33 @@
+package actions
+
+import (
+	"context"
+	"time"
+)
+
+// RestoreEggVersion makes an older version of a secret current again. The
+// restored value is written as a brand new version, so the history stays
+// append-only and the version being replaced can itself be restored later.
+// The restored value keeps its original expiry, so expired versions can't be
+// brought back.
+func RestoreEggVersion(ctx context.Context, repo EggActions, owner, secretID string, version int, restoredBy string) (Egg, error) {
+	old, err := repo.GetEggVersion(ctx, owner, secretID, version)
+	if err != nil {
+		return Egg{}, err
+	}
+	if old.Expired(time.Now()) {
+		return Egg{}, ErrEggExpired
+	}
+
+	return repo.PutEgg(ctx, Egg{
+		Owner:            owner,
+		SecretID:         secretID,
+		Ciphertext:       old.Ciphertext,
+		EncryptedDataKey: old.EncryptedDataKey,
+		Bound:            old.Bound,
+		KeyID:            old.KeyID,
+		ExpiresAt:        old.ExpiresAt,
+		CreatedAt:        time.Now().Format(time.RFC3339),
+		CreatedBy:        restoredBy,
+	}, AnyVersion)
+}
@@ -0,0 +1,119 @@
+// Command bind_eggs migrates eggs written before owner/secret binding. Each
+// unbound egg (current value and every history version) has its data key
+// re-wrapped under the KMS encryption context for its Owner and SecretID and
+// its ciphertext re-sealed with matching AES-GCM additional data.
+//
+// It uses the same environment as the Lambdas (STORAGE_BACKEND, TABLE_NAME,
+// KMS_KEY_ID, ...) and is safe to re-run: bound eggs are skipped.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"log"
+	"os"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+	"github.com/owenHochwald/egg-carton/cmd/actions"
+	"github.com/owenHochwald/egg-carton/pkg/crypto"
+)
+
+func main() {
+	dryRun := flag.Bool("dry-run", false, "report unbound eggs without changing them")
+	pageSize := flag.Int("page-size", 100, "eggs to read per page")
+	flag.Parse()
+
+	ctx := context.Background()
+	cfg, err := config.LoadDefaultConfig(ctx)
+	if err != nil {
+		log.Fatal(err)
+	}
+	kmsClient := kms.NewFromConfig(cfg)
+	kmsKeyID := os.Getenv("KMS_KEY_ID")
+	if kmsKeyID == "" {
+		log.Fatal("KMS_KEY_ID must be set")
+	}
+
+	eggRepo, err := actions.NewEggActionsFromEnv(ctx)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var scanned, bound, skipped int
+	cursor := ""
+	for {
+		eggs, next, err := eggRepo.ScanEggs(ctx, cursor, *pageSize)
+		if err != nil {
+			log.Fatalf("Failed to scan eggs: %v", err)
+		}
+
+		for _, egg := range eggs {
+			scanned++
+			if egg.Bound {
+				continue
+			}
+			if *dryRun {
+				log.Printf("Would bind %s/%s version %d", egg.Owner, egg.SecretID, egg.Version)
+				bound++
+				continue
+			}
+
+			err := bindEgg(ctx, kmsClient, kmsKeyID, eggRepo, egg)
+			if errors.Is(err, actions.ErrEggConflict) {
+				// Written concurrently, which already produced a bound egg
+				log.Printf("Skipping %s/%s version %d: changed during migration", egg.Owner, egg.SecretID, egg.Version)
+				skipped++
+				continue
+			}
+			if err != nil {
+				log.Fatalf("Failed to bind %s/%s version %d: %v", egg.Owner, egg.SecretID, egg.Version, err)
+			}
+			bound++
+		}
+
+		if next == "" {
+			break
+		}
+		cursor = next
+	}
+
+	log.Printf("Scanned %d eggs, bound %d, skipped %d", scanned, bound, skipped)
+}
+
+// bindEgg re-encrypts one legacy egg in place. The data key itself is kept;
+// only its KMS wrapping and the GCM seal change.
+func bindEgg(ctx context.Context, kmsClient *kms.Client, kmsKeyID string, eggRepo actions.EggActions, egg actions.Egg) error {
+	// Unwrap the legacy data key, which has no encryption context
+	decryptResp, err := kmsClient.Decrypt(ctx, &kms.DecryptInput{
+		CiphertextBlob: egg.EncryptedDataKey,
+	})
+	if err != nil {
+		return err
+	}
+	plaintext, err := crypto.Open(egg.Ciphertext, decryptResp.Plaintext, nil)
+	if err != nil {
+		return err
+	}
+
+	egg.Bound = true
+	encryptResp, err := kmsClient.Encrypt(ctx, &kms.EncryptInput{
+		KeyId:             &kmsKeyID,
+		Plaintext:         decryptResp.Plaintext,
+		EncryptionContext: egg.EncryptionContext(),
+	})
+	if err != nil {
+		return err
+	}
+	ciphertext, err := crypto.Seal(crypto.AES256GCM, decryptResp.Plaintext, kmsKeyID, plaintext, egg.AdditionalData())
+	if err != nil {
+		return err
+	}
+
+	egg.Ciphertext = ciphertext
+	egg.EncryptedDataKey = encryptResp.CiphertextBlob
+	egg.KeyID = aws.ToString(encryptResp.KeyId)
+	return eggRepo.UpdateEgg(ctx, egg)
+}
@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"context"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/owenHochwald/egg-carton/cmd/actions"
+)
+
+type AuditResponse struct {
+	Vault      string               `json:"vault"`
+	Events     []actions.AuditEvent `json:"events"`
+	ChainValid bool                 `json:"chain_valid"`           // Whether the vault's whole chain verified
+	ChainError string               `json:"chain_error,omitempty"` // The first broken link, if not
+}
+
+// Audit serves GET /audit. The caller's own vault is read unless ?team= is
+// given, which needs the admin role. ?secret= (with ?project= and ?env=),
+// ?since= and ?until= (RFC 3339) narrow the events returned; the whole chain
+// is verified either way.
+func (h *Handlers) Audit(ctx context.Context, request events.APIGatewayV2HTTPRequest) (events.APIGatewayV2HTTPResponse, error) {
+	return h.serve(h.audit)(ctx, request)
+}
+
+func (h *Handlers) audit(ctx context.Context, r *Request) (Response, error) {
+	query := r.QueryStringParameters
+	vault := r.Caller.ID
+	if team := query["team"]; team != "" {
+		if err := actions.ValidateTeamName(team); err != nil {
+			return Response{}, invalidRequest(err.Error())
+		}
+		vault = actions.TeamOwner(team)
+	}
+	if err := h.authorize(ctx, r, vault, actions.RoleAdmin, "Forbidden: only team admins can read its audit log"); err != nil {
+		return Response{}, err
+	}
+
+	var filter actions.AuditFilter
+	if secret := query["secret"]; secret != "" {
+		namespace, err := namespaceParam(r)
+		if err != nil {
+			return Response{}, err
+		}
+		filter.SecretID = namespace.SecretID(secret)
+	}
+	for param, t := range map[string]*time.Time{"since": &filter.Since, "until": &filter.Until} {
+		if query[param] == "" {
+			continue
+		}
+		parsed, err := time.Parse(time.RFC3339, query[param])
+		if err != nil {
+			return Response{}, invalidRequest(param + " must be an RFC 3339 time")
+		}
+		*t = parsed
+	}
+
+	chain, err := h.Repo.ListAuditEvents(ctx, vault)
+	if err != nil {
+		return Response{}, internalError("Failed to read audit log", err)
+	}
+
+	response := AuditResponse{
+		Vault:      vault,
+		Events:     actions.FilterAuditEvents(chain, filter),
+		ChainValid: true,
+	}
+	if err := actions.VerifyAuditChain(ctx, h.AuditKey, chain); err != nil {
+		r.Log.Error("audit chain broken", "vault", vault, "error", err.Error())
+		response.ChainValid = false
+		response.ChainError = err.Error()
+	}
+	return ok(response)
+}
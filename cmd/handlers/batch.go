@@ -0,0 +1,276 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/owenHochwald/egg-carton/cmd/actions"
+)
+
+// Routes served by PutEgg and BreakEgg for many secrets at once
+const (
+	putEggsRoute   = "POST /eggs:batch"
+	breakEggsRoute = "DELETE /eggs:batch"
+)
+
+// maxBatchSize is the most secrets one batch request may carry
+const maxBatchSize = 100
+
+type PutEggsRequest struct {
+	Project string         `json:"project,omitempty"`
+	Env     string         `json:"env,omitempty"`
+	Team    string         `json:"team,omitempty"` // Lay into this team's vault instead of your own
+	Eggs    []BatchPutItem `json:"eggs"`
+}
+
+// BatchPutItem is one secret of a batch put
+type BatchPutItem struct {
+	SecretID  string `json:"secret_id"`
+	Plaintext string `json:"plaintext"`
+	ExpiresAt string `json:"expires_at,omitempty"` // RFC 3339; the egg never expires if empty
+	IfAbsent  bool   `json:"if_absent,omitempty"`  // As If-None-Match: *
+	IfMatch   int    `json:"if_match,omitempty"`   // As If-Match
+}
+
+type BreakEggsRequest struct {
+	Project   string   `json:"project,omitempty"`
+	Env       string   `json:"env,omitempty"`
+	Team      string   `json:"team,omitempty"` // Break in this team's vault instead of your own
+	SecretIDs []string `json:"secret_ids"`
+}
+
+// BatchResponse reports every secret of a batch, in request order
+type BatchResponse struct {
+	Owner     string        `json:"owner"`
+	Project   string        `json:"project,omitempty"`
+	Env       string        `json:"env,omitempty"`
+	Succeeded int           `json:"succeeded"`
+	Failed    int           `json:"failed"`
+	Results   []BatchResult `json:"results"`
+}
+
+// BatchResult is the outcome for one secret. Status is what the single
+// secret endpoint would have returned, and a failure carries the code,
+// message and retryable of its ErrorResponse.
+type BatchResult struct {
+	SecretID  string `json:"secret_id"`
+	Status    int    `json:"status"`
+	Version   int    `json:"version,omitempty"`
+	PurgeAt   string `json:"purge_at,omitempty"` // When a trashed egg is deleted for good
+	Code      string `json:"code,omitempty"`
+	Message   string `json:"message,omitempty"`
+	Retryable bool   `json:"retryable,omitempty"`
+}
+
+// putEggs lays up to maxBatchSize secrets in one vault. Every secret is
+// validated before any is written; after that each succeeds or fails on its
+// own, as if laid by itself.
+func (h *Handlers) putEggs(ctx context.Context, r *Request) (Response, error) {
+	var req PutEggsRequest
+	if err := decodeBody(r, &req); err != nil {
+		return Response{}, err
+	}
+	namespace, err := actions.NewNamespace(req.Project, req.Env)
+	if err != nil {
+		return Response{}, invalidRequest(err.Error())
+	}
+	if len(req.Eggs) == 0 || len(req.Eggs) > maxBatchSize {
+		return Response{}, invalidRequest(fmt.Sprintf("eggs must hold from 1 to %d secrets", maxBatchSize))
+	}
+
+	expiries := make([]int64, len(req.Eggs))
+	preconditions := make([]int, len(req.Eggs))
+	seen := make(map[string]bool)
+	for i, item := range req.Eggs {
+		invalid := func(message string) error {
+			return invalidRequest(fmt.Sprintf("eggs[%d]: %s", i, message))
+		}
+		switch {
+		case item.SecretID == "" || item.Plaintext == "":
+			return Response{}, invalid("secret_id and plaintext are required")
+		case seen[item.SecretID]:
+			return Response{}, invalid("secret_id " + item.SecretID + " appears more than once")
+		case item.IfAbsent && item.IfMatch != 0:
+			return Response{}, invalid("if_absent and if_match can't both be set")
+		case item.IfMatch < 0:
+			return Response{}, invalid("if_match must be an egg version")
+		}
+		if err := actions.ValidateSecretName(item.SecretID); err != nil {
+			return Response{}, invalid(err.Error())
+		}
+		if expiries[i], err = parseExpiry(item.ExpiresAt); err != nil {
+			return Response{}, invalid(asError(err).Message)
+		}
+		seen[item.SecretID] = true
+
+		preconditions[i] = actions.AnyVersion
+		if item.IfAbsent {
+			preconditions[i] = actions.NoVersion
+		} else if item.IfMatch > 0 {
+			preconditions[i] = item.IfMatch
+		}
+	}
+
+	owner, err := vaultOwner(r, req.Team)
+	if err != nil {
+		return Response{}, err
+	}
+	r.Audit.Vault = owner
+	if err := h.authorize(ctx, r, owner, actions.RoleWriter, "Forbidden: you don't have write access to this vault"); err != nil {
+		return Response{}, err
+	}
+
+	// Encrypt each secret under its own data key, then store them together
+	results := make([]BatchResult, len(req.Eggs))
+	var eggs []actions.Egg
+	var expected, sealed []int
+	for i, item := range req.Eggs {
+		secretID := namespace.SecretID(item.SecretID)
+		want, err := h.prepareOverwrite(ctx, owner, secretID, preconditions[i])
+		var egg actions.Egg
+		if err == nil {
+			egg, err = h.sealEgg(ctx, r, owner, secretID, item.Plaintext, expiries[i])
+		}
+		if err != nil {
+			results[i] = batchResult(r, item.SecretID, 0, err)
+			continue
+		}
+		eggs = append(eggs, egg)
+		expected = append(expected, want)
+		sealed = append(sealed, i)
+	}
+
+	stored, errs := h.Repo.PutEggs(ctx, eggs, expected)
+	for j, i := range sealed {
+		err := errs[j]
+		if errors.Is(err, actions.ErrEggConflict) {
+			err = versionConflict(preconditions[i])
+		} else if err != nil {
+			err = internalError("Failed to store egg", err)
+		}
+		results[i] = batchResult(r, req.Eggs[i].SecretID, http.StatusCreated, err)
+		if err == nil {
+			results[i].Version = stored[j].Version
+		}
+	}
+	return batchResponse(r, owner, namespace, results)
+}
+
+// breakEggs moves up to maxBatchSize secrets of one vault to the trash, or
+// with ?purge=true deletes them for good. As with putEggs, every secret is
+// validated first and then each succeeds or fails on its own.
+func (h *Handlers) breakEggs(ctx context.Context, r *Request) (Response, error) {
+	var req BreakEggsRequest
+	if err := decodeBody(r, &req); err != nil {
+		return Response{}, err
+	}
+	namespace, err := actions.NewNamespace(req.Project, req.Env)
+	if err != nil {
+		return Response{}, invalidRequest(err.Error())
+	}
+	if len(req.SecretIDs) == 0 || len(req.SecretIDs) > maxBatchSize {
+		return Response{}, invalidRequest(fmt.Sprintf("secret_ids must hold from 1 to %d secrets", maxBatchSize))
+	}
+	seen := make(map[string]bool)
+	for i, name := range req.SecretIDs {
+		if name == "" {
+			return Response{}, invalidRequest(fmt.Sprintf("secret_ids[%d]: secret_id is required", i))
+		}
+		if err := actions.ValidateSecretName(name); err != nil {
+			return Response{}, invalidRequest(fmt.Sprintf("secret_ids[%d]: %s", i, err))
+		}
+		if seen[name] {
+			return Response{}, invalidRequest(fmt.Sprintf("secret_ids[%d]: %s appears more than once", i, name))
+		}
+		seen[name] = true
+	}
+
+	purge := r.QueryStringParameters["purge"] == "true"
+	if purge {
+		r.Audit.Action = actions.AuditPurge
+	}
+	owner, err := vaultOwner(r, req.Team)
+	if err != nil {
+		return Response{}, err
+	}
+	r.Audit.Vault = owner
+	if err := h.authorize(ctx, r, owner, actions.RoleWriter, "Forbidden: you don't have writer access to this vault"); err != nil {
+		return Response{}, err
+	}
+
+	results := make([]BatchResult, len(req.SecretIDs))
+	if purge {
+		var secretIDs []string
+		var found []int
+		versions := make([]int, len(req.SecretIDs))
+		for i, name := range req.SecretIDs {
+			egg, err := h.findPurgeable(ctx, owner, namespace.SecretID(name))
+			if err != nil {
+				results[i] = batchResult(r, name, 0, err)
+				continue
+			}
+			versions[i] = egg.Version
+			secretIDs = append(secretIDs, namespace.SecretID(name))
+			found = append(found, i)
+		}
+		for j, err := range h.Repo.BreakEggs(ctx, owner, secretIDs) {
+			i := found[j]
+			if err != nil {
+				err = internalError("Failed to delete egg", err)
+			}
+			results[i] = batchResult(r, req.SecretIDs[i], http.StatusOK, err)
+			if err == nil {
+				results[i].Version = versions[i]
+			}
+		}
+		return batchResponse(r, owner, namespace, results)
+	}
+
+	for i, name := range req.SecretIDs {
+		egg, err := h.trash(ctx, r, owner, namespace.SecretID(name), actions.AnyVersion)
+		results[i] = batchResult(r, name, http.StatusOK, err)
+		if err == nil {
+			results[i].Version = egg.Version
+			results[i].PurgeAt = egg.Expiry()
+		}
+	}
+	return batchResponse(r, owner, namespace, results)
+}
+
+// batchResult reports one secret's outcome, or err as the single secret
+// endpoint would have.
+func batchResult(r *Request, secretID string, status int, err error) BatchResult {
+	if err == nil {
+		return BatchResult{SecretID: secretID, Status: status}
+	}
+	apiErr := asError(err)
+	if apiErr.StatusCode >= 500 {
+		r.Log.Error(apiErr.Message, "secret_id", secretID, "error", err.Error())
+	}
+	return BatchResult{
+		SecretID:  secretID,
+		Status:    apiErr.StatusCode,
+		Code:      apiErr.Code,
+		Message:   apiErr.Message,
+		Retryable: apiErr.Retryable,
+	}
+}
+
+// batchResponse totals up results and audits each secret on its own.
+func batchResponse(r *Request, owner string, namespace actions.Namespace, results []BatchResult) (Response, error) {
+	response := BatchResponse{Owner: owner, Project: namespace.Project, Env: namespace.Env, Results: results}
+	for _, result := range results {
+		if result.Status < 300 {
+			response.Succeeded++
+		} else {
+			response.Failed++
+		}
+		event := *r.Audit
+		event.SecretID = namespace.SecretID(result.SecretID)
+		event.Version = result.Version
+		r.AuditItems = append(r.AuditItems, AuditItem{Event: event, StatusCode: result.Status})
+	}
+	return ok(response)
+}
@@ -0,0 +1,179 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/owenHochwald/egg-carton/cmd/actions"
+)
+
+func newBatchRequest(caller, routeKey string, body any) events.APIGatewayV2HTTPRequest {
+	request := newTestRequest(caller)
+	request.RouteKey = routeKey
+	data, _ := json.Marshal(body)
+	request.Body = string(data)
+	return request
+}
+
+func decodeBatch(t *testing.T, response events.APIGatewayV2HTTPResponse) BatchResponse {
+	t.Helper()
+	if response.StatusCode != 200 {
+		t.Fatalf("batch: got %d %s, want 200", response.StatusCode, response.Body)
+	}
+	var body BatchResponse
+	if err := json.Unmarshal([]byte(response.Body), &body); err != nil {
+		t.Fatalf("decode batch body %q: %v", response.Body, err)
+	}
+	return body
+}
+
+func TestBatchValidation(t *testing.T) {
+	ctx := context.Background()
+	h := newTestHandlers(t)
+
+	tooMany := make([]BatchPutItem, maxBatchSize+1)
+	for i := range tooMany {
+		tooMany[i] = BatchPutItem{SecretID: fmt.Sprintf("KEY_%d", i), Plaintext: "v"}
+	}
+	tests := []struct {
+		name     string
+		routeKey string
+		body     any
+	}{
+		{"no eggs", putEggsRoute, PutEggsRequest{}},
+		{"too many eggs", putEggsRoute, PutEggsRequest{Eggs: tooMany}},
+		{"duplicate egg", putEggsRoute, PutEggsRequest{Eggs: []BatchPutItem{
+			{SecretID: "API_KEY", Plaintext: "a"}, {SecretID: "API_KEY", Plaintext: "b"},
+		}}},
+		{"if_absent with if_match", putEggsRoute, PutEggsRequest{Eggs: []BatchPutItem{
+			{SecretID: "DB_URL", Plaintext: "a"}, {SecretID: "API_KEY", Plaintext: "b", IfAbsent: true, IfMatch: 2},
+		}}},
+		{"empty plaintext", putEggsRoute, PutEggsRequest{Eggs: []BatchPutItem{{SecretID: "API_KEY"}}}},
+		{"bad expiry", putEggsRoute, PutEggsRequest{Eggs: []BatchPutItem{{SecretID: "API_KEY", Plaintext: "a", ExpiresAt: "soon"}}}},
+		{"no secret_ids", breakEggsRoute, BreakEggsRequest{}},
+		{"too many secret_ids", breakEggsRoute, BreakEggsRequest{SecretIDs: make([]string, maxBatchSize+1)}},
+		{"empty secret_id", breakEggsRoute, BreakEggsRequest{SecretIDs: []string{"DB_URL", ""}}},
+		{"duplicate secret_id", breakEggsRoute, BreakEggsRequest{SecretIDs: []string{"DB_URL", "DB_URL"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := h.PutEgg
+			if tt.routeKey == breakEggsRoute {
+				handler = h.BreakEgg
+			}
+			response, _ := handler(ctx, newBatchRequest("alice", tt.routeKey, tt.body))
+			if body := decodeError(t, response); response.StatusCode != 400 || body.Code != CodeInvalidRequest {
+				t.Errorf("got %d %+v, want 400 %s", response.StatusCode, body, CodeInvalidRequest)
+			}
+		})
+	}
+
+	// Nothing was written by any of them
+	if eggs, err := h.Repo.GetAllEggs(ctx, "alice"); err != nil || len(eggs) != 0 {
+		t.Errorf("got %d eggs, %v, want none", len(eggs), err)
+	}
+}
+
+func TestPutEggsPerItem(t *testing.T) {
+	ctx := context.Background()
+	h := newTestHandlers(t)
+	for _, key := range []string{"API_KEY", "TOKEN", "OLD"} {
+		if response, _ := h.PutEgg(ctx, newPutRequest("alice", key, "v1")); response.StatusCode != 201 {
+			t.Fatalf("PutEgg %s: %d %s", key, response.StatusCode, response.Body)
+		}
+	}
+	if _, err := actions.TrashEgg(ctx, h.Repo, "alice", "OLD", "alice", h.TrashRetention, actions.AnyVersion); err != nil {
+		t.Fatalf("TrashEgg: %v", err)
+	}
+
+	response, _ := h.PutEgg(ctx, newBatchRequest("alice", putEggsRoute, PutEggsRequest{Eggs: []BatchPutItem{
+		{SecretID: "DB_URL", Plaintext: "postgres://", IfAbsent: true},
+		{SecretID: "API_KEY", Plaintext: "v2", IfMatch: 1},
+		{SecretID: "TOKEN", Plaintext: "stale", IfMatch: 3},
+		{SecretID: "OLD", Plaintext: "v2"},
+	}}))
+	body := decodeBatch(t, response)
+	want := []BatchResult{
+		{SecretID: "DB_URL", Status: 201, Version: 1},
+		{SecretID: "API_KEY", Status: 201, Version: 2},
+		{SecretID: "TOKEN", Status: 409, Code: CodeConflict},
+		{SecretID: "OLD", Status: 409, Code: CodeTrashed},
+	}
+	if body.Succeeded != 2 || body.Failed != 2 || len(body.Results) != len(want) {
+		t.Fatalf("got %+v, want 2 succeeded and 2 failed", body)
+	}
+	for i, result := range body.Results {
+		result.Message = ""
+		if result != want[i] {
+			t.Errorf("result %d: got %+v, want %+v", i, result, want[i])
+		}
+	}
+
+	// The failures left their eggs as they were
+	if egg, err := h.Repo.GetEgg(ctx, "alice", "TOKEN"); err != nil || egg.Version != 1 {
+		t.Errorf("TOKEN: got version %d, %v, want 1", egg.Version, err)
+	}
+	if egg, err := h.Repo.GetEgg(ctx, "alice", "OLD"); err != nil || !egg.Trashed() {
+		t.Errorf("OLD: got %v, want it still in the trash", err)
+	}
+}
+
+func TestBreakEggsTrashOrPurge(t *testing.T) {
+	ctx := context.Background()
+	h := newTestHandlers(t)
+	for _, key := range []string{"DB_URL", "API_KEY"} {
+		if response, _ := h.PutEgg(ctx, newPutRequest("alice", key, "v1")); response.StatusCode != 201 {
+			t.Fatalf("PutEgg %s: %d %s", key, response.StatusCode, response.Body)
+		}
+	}
+
+	// Without ?purge=true the eggs go to the trash, history and all
+	request := newBatchRequest("alice", breakEggsRoute, BreakEggsRequest{SecretIDs: []string{"DB_URL", "MISSING"}})
+	response, _ := h.BreakEgg(ctx, request)
+	body := decodeBatch(t, response)
+	if body.Succeeded != 1 || body.Failed != 1 || body.Results[0].PurgeAt == "" || body.Results[1].Code != CodeNotFound {
+		t.Errorf("trash: got %+v, want DB_URL trashed and MISSING not found", body)
+	}
+	if egg, err := h.Repo.GetEgg(ctx, "alice", "DB_URL"); err != nil || !egg.Trashed() {
+		t.Errorf("DB_URL: got %v, want it in the trash", err)
+	}
+	if versions, _ := h.Repo.ListEggVersions(ctx, "alice", "DB_URL"); len(versions) != 1 {
+		t.Errorf("DB_URL history: got %d versions, want 1 kept in the trash", len(versions))
+	}
+
+	// With it they are deleted for good, trashed or not
+	request = newBatchRequest("alice", breakEggsRoute, BreakEggsRequest{SecretIDs: []string{"DB_URL", "API_KEY", "MISSING"}})
+	request.QueryStringParameters = map[string]string{"purge": "true"}
+	response, _ = h.BreakEgg(ctx, request)
+	body = decodeBatch(t, response)
+	if body.Succeeded != 2 || body.Failed != 1 || body.Results[2].Status != 404 || body.Results[2].Code != CodeNotFound {
+		t.Errorf("purge: got %+v, want DB_URL and API_KEY purged and MISSING not found", body)
+	}
+	for _, key := range []string{"DB_URL", "API_KEY"} {
+		if _, err := h.Repo.GetEgg(ctx, "alice", key); !errors.Is(err, actions.ErrEggNotFound) {
+			t.Errorf("%s: got %v, want it gone", key, err)
+		}
+		if versions, _ := h.Repo.ListEggVersions(ctx, "alice", key); len(versions) != 0 {
+			t.Errorf("%s history: got %d versions, want none", key, len(versions))
+		}
+	}
+
+	// Each secret was audited on its own, with its own outcome
+	events, err := h.Repo.ListAuditEvents(ctx, "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var purges []string
+	for _, event := range events {
+		if event.Action == actions.AuditPurge {
+			purges = append(purges, event.SecretID+" "+event.Outcome)
+		}
+	}
+	if len(purges) != 3 || purges[2] != "MISSING "+actions.OutcomeNotFound {
+		t.Errorf("purge audit events: got %v", purges)
+	}
+}
@@ -0,0 +1,239 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/owenHochwald/egg-carton/cmd/actions"
+)
+
+// Routes served by BreakEgg
+const (
+	breakEggRoute   = "DELETE /eggs/{owner}/{secretId}"
+	listTrashRoute  = "GET /trash/{owner}"
+	restoreEggRoute = "POST /trash/{owner}/{secretId}/restore"
+)
+
+type BreakEggResponse struct {
+	Message  string `json:"message"`
+	Owner    string `json:"owner"`
+	SecretID string `json:"secret_id"`
+	Project  string `json:"project,omitempty"`
+	Env      string `json:"env,omitempty"`
+	PurgeAt  string `json:"purge_at,omitempty"` // When a trashed egg is deleted for good
+}
+
+// TrashedEgg describes an egg in the trash without its value
+type TrashedEgg struct {
+	Owner     string `json:"owner"`
+	SecretID  string `json:"secret_id"`
+	Project   string `json:"project,omitempty"`
+	Env       string `json:"env,omitempty"`
+	Version   int    `json:"version"`
+	DeletedAt string `json:"deleted_at"`
+	DeletedBy string `json:"deleted_by"`
+	PurgeAt   string `json:"purge_at"`
+}
+
+type ListTrashResponse struct {
+	Eggs []TrashedEgg `json:"eggs"`
+}
+
+func (h *Handlers) BreakEgg(ctx context.Context, request events.APIGatewayV2HTTPRequest) (events.APIGatewayV2HTTPResponse, error) {
+	// Every delete and restore, including denied ones, goes in the vault's audit log
+	return h.serve(h.breakEgg, h.audited(actions.AuditBreak))(ctx, request)
+}
+
+func (h *Handlers) breakEgg(ctx context.Context, r *Request) (Response, error) {
+	// DELETE /eggs:batch breaks several eggs at once
+	if r.RouteKey == breakEggsRoute {
+		return h.breakEggs(ctx, r)
+	}
+
+	// Get parameters from path
+	owner := r.PathParameters["owner"]
+	secretID := r.PathParameters["secretId"]
+	if owner == "" || (secretID == "" && r.RouteKey != listTrashRoute) {
+		return Response{}, invalidRequest("owner and secretId parameters are required")
+	}
+
+	// ?project=&env= select the namespace the secret lives in
+	namespace, err := namespaceParam(r)
+	if err != nil {
+		return Response{}, err
+	}
+
+	// ?purge=true deletes for good instead of moving to the trash
+	purge := r.QueryStringParameters["purge"] == "true"
+
+	// If-Match only deletes the egg if it is still at that version
+	expected, err := expectedVersion(r)
+	if err != nil {
+		return Response{}, err
+	}
+	if expected == actions.NoVersion {
+		return Response{}, invalidRequest("If-None-Match isn't supported when deleting an egg")
+	}
+
+	r.Audit.Vault = owner
+	required := actions.RoleWriter
+	switch {
+	case r.RouteKey == listTrashRoute:
+		r.Audit.Action = actions.AuditList
+		required = actions.RoleReader
+	case r.RouteKey == restoreEggRoute:
+		r.Audit.Action = actions.AuditUndelete
+	case purge:
+		r.Audit.Action = actions.AuditPurge
+	}
+	if secretID != "" {
+		r.Audit.SecretID = namespace.SecretID(secretID)
+	}
+
+	// Listing the trash needs the reader role on a team vault, changing it
+	// needs writer; everyone owns their own vault
+	if err := h.authorize(ctx, r, owner, required, "Forbidden: you don't have "+string(required)+" access to this vault"); err != nil {
+		return Response{}, err
+	}
+
+	switch {
+	case r.RouteKey == listTrashRoute:
+		return h.listTrash(ctx, owner, namespace)
+	case r.RouteKey == restoreEggRoute:
+		return h.restoreEgg(ctx, r, owner)
+	case purge:
+		return h.purgeEgg(ctx, r, owner, expected)
+	default:
+		return h.trashEgg(ctx, r, owner, expected)
+	}
+}
+
+// trashEgg moves an egg and its history into the trash for TrashRetention.
+func (h *Handlers) trashEgg(ctx context.Context, r *Request, owner string, expected int) (Response, error) {
+	egg, err := h.trash(ctx, r, owner, r.Audit.SecretID, expected)
+	if err != nil {
+		return Response{}, err
+	}
+	r.Audit.Version = egg.Version
+
+	response := newBreakEggResponse("Egg moved to the trash", owner, r.Audit.SecretID)
+	response.PurgeAt = egg.Expiry()
+	return ok(response)
+}
+
+// trash moves one egg to the trash on behalf of the caller.
+func (h *Handlers) trash(ctx context.Context, r *Request, owner, secretID string, expected int) (actions.Egg, error) {
+	egg, err := actions.TrashEgg(ctx, h.Repo, owner, secretID, r.Caller.ID, h.TrashRetention, expected)
+	if errors.Is(err, actions.ErrEggNotFound) {
+		return egg, notFound("Egg not found")
+	}
+	if errors.Is(err, actions.ErrEggConflict) {
+		return egg, versionConflict(expected)
+	}
+	if err != nil {
+		return egg, internalError("Failed to delete egg", err)
+	}
+	return egg, nil
+}
+
+// purgeEgg permanently deletes an egg and its history, whether or not it is
+// in the trash.
+func (h *Handlers) purgeEgg(ctx context.Context, r *Request, owner string, expected int) (Response, error) {
+	egg, err := h.findPurgeable(ctx, owner, r.Audit.SecretID)
+	if err != nil {
+		return Response{}, err
+	}
+	r.Audit.Version = egg.Version // Note which version is being destroyed
+
+	err = h.Repo.BreakEgg(ctx, owner, r.Audit.SecretID, expected)
+	if errors.Is(err, actions.ErrEggConflict) {
+		return Response{}, versionConflict(expected)
+	}
+	if err != nil {
+		return Response{}, internalError("Failed to delete egg", err)
+	}
+	return ok(newBreakEggResponse("Egg deleted permanently", owner, r.Audit.SecretID))
+}
+
+// findPurgeable returns the egg a purge would delete, or notFound if neither
+// it nor any of its history is left. History can outlive an expired current
+// row, in which case the egg is zero but there is still something to purge.
+func (h *Handlers) findPurgeable(ctx context.Context, owner, secretID string) (actions.Egg, error) {
+	egg, err := h.Repo.GetEgg(ctx, owner, secretID)
+	if err == nil {
+		return egg, nil
+	}
+	if !errors.Is(err, actions.ErrEggNotFound) {
+		return egg, internalError("Failed to read egg", err)
+	}
+	versions, err := h.Repo.ListEggVersions(ctx, owner, secretID)
+	if err != nil {
+		return egg, internalError("Failed to read egg", err)
+	}
+	if len(versions) == 0 {
+		return egg, notFound("Egg not found")
+	}
+	return egg, nil
+}
+
+func (h *Handlers) restoreEgg(ctx context.Context, r *Request, owner string) (Response, error) {
+	egg, err := actions.RestoreTrashedEgg(ctx, h.Repo, owner, r.Audit.SecretID)
+	if errors.Is(err, actions.ErrEggNotFound) {
+		return Response{}, notFound("Egg not found in the trash")
+	}
+	if errors.Is(err, actions.ErrEggConflict) {
+		return Response{}, errConcurrentUpdate
+	}
+	if err != nil {
+		return Response{}, internalError("Failed to restore egg", err)
+	}
+	r.Audit.Version = egg.Version
+
+	return ok(newBreakEggResponse("Egg restored from the trash", owner, r.Audit.SecretID))
+}
+
+// listTrash returns the metadata of the trashed eggs in namespace, or of the
+// whole vault if namespace is zero.
+func (h *Handlers) listTrash(ctx context.Context, owner string, namespace actions.Namespace) (Response, error) {
+	var eggs []actions.Egg
+	var err error
+	if namespace.IsZero() {
+		eggs, err = h.Repo.GetAllEggs(ctx, owner)
+	} else {
+		eggs, err = h.Repo.GetEggsWithPrefix(ctx, owner, namespace.Prefix())
+	}
+	if err != nil {
+		return Response{}, internalError("Failed to retrieve trash", err)
+	}
+
+	response := ListTrashResponse{Eggs: []TrashedEgg{}}
+	for _, egg := range actions.TrashedEggs(eggs, time.Now()) {
+		eggNamespace, name := actions.SplitSecretID(egg.SecretID)
+		response.Eggs = append(response.Eggs, TrashedEgg{
+			Owner:     egg.Owner,
+			SecretID:  name,
+			Project:   eggNamespace.Project,
+			Env:       eggNamespace.Env,
+			Version:   egg.Version,
+			DeletedAt: egg.DeletedAt,
+			DeletedBy: egg.DeletedBy,
+			PurgeAt:   egg.Expiry(),
+		})
+	}
+	return ok(response)
+}
+
+// newBreakEggResponse reports the egg by name, with its project and env split
+// out of the stored SecretID.
+func newBreakEggResponse(message, owner, secretID string) BreakEggResponse {
+	namespace, name := actions.SplitSecretID(secretID)
+	return BreakEggResponse{
+		Message:  message,
+		Owner:    owner,
+		SecretID: name,
+		Project:  namespace.Project,
+		Env:      namespace.Env,
+	}
+}
@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+func newBreakRequest(caller, owner, secretID string, purge bool) events.APIGatewayV2HTTPRequest {
+	request := newTestRequest(caller)
+	request.RouteKey = breakEggRoute
+	request.PathParameters = map[string]string{"owner": owner, "secretId": secretID}
+	if purge {
+		request.QueryStringParameters = map[string]string{"purge": "true"}
+	}
+	return request
+}
+
+func TestPurgeMissingEgg(t *testing.T) {
+	ctx := context.Background()
+	h := newTestHandlers(t)
+
+	response, _ := h.BreakEgg(ctx, newBreakRequest("alice", "alice", "NEVER_LAID", true))
+	if body := decodeError(t, response); response.StatusCode != 404 || body.Code != CodeNotFound {
+		t.Errorf("purging a missing egg: got %d %+v, want 404 %s", response.StatusCode, body, CodeNotFound)
+	}
+
+	// A trashed egg is still there to purge, and then it's gone
+	if response, _ := h.PutEgg(ctx, newPutRequest("alice", "API_KEY", "sk-1")); response.StatusCode != 201 {
+		t.Fatalf("PutEgg: %d %s", response.StatusCode, response.Body)
+	}
+	if response, _ := h.BreakEgg(ctx, newBreakRequest("alice", "alice", "API_KEY", false)); response.StatusCode != 200 {
+		t.Fatalf("trashing: %d %s", response.StatusCode, response.Body)
+	}
+	if response, _ := h.BreakEgg(ctx, newBreakRequest("alice", "alice", "API_KEY", true)); response.StatusCode != 200 {
+		t.Errorf("purging a trashed egg: %d %s", response.StatusCode, response.Body)
+	}
+	if response, _ := h.BreakEgg(ctx, newBreakRequest("alice", "alice", "API_KEY", true)); response.StatusCode != 404 {
+		t.Errorf("purging twice: got %d, want 404", response.StatusCode)
+	}
+}
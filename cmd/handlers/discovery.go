@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// discoveryRoute serves the discovery document. It is public: clients read
+// it before they know where to log in.
+const discoveryRoute = "GET /.well-known/eggcarton"
+
+// Versions of the API, advertised in the discovery document. APIVersion goes
+// up when the API changes in a way older clients can't follow, and
+// MinAPIVersion when a version they rely on is no longer served.
+const (
+	APIVersion    = 1
+	MinAPIVersion = 1
+)
+
+// Features lists the optional parts of the API this deployment serves, so
+// clients can tell what they may use without probing.
+var Features = []string{
+	"namespaces",
+	"versions",
+	"trash",
+	"expiry",
+	"preconditions",
+	"teams",
+	"audit",
+	"batch",
+	"pagination",
+}
+
+// Deployment is what the discovery document tells clients about where this
+// deployment lives.
+type Deployment struct {
+	APIEndpoint string           // Derived from the request if empty
+	Cognito     DiscoveryCognito // Left out of the document if ClientID is empty
+}
+
+// DeploymentFromEnv reads the deployment from API_ENDPOINT, and
+// COGNITO_USER_POOL_ID, COGNITO_CLIENT_ID, COGNITO_DOMAIN and COGNITO_REGION.
+func DeploymentFromEnv() Deployment {
+	return Deployment{
+		APIEndpoint: strings.TrimSuffix(os.Getenv("API_ENDPOINT"), "/"),
+		Cognito: DiscoveryCognito{
+			UserPoolID: os.Getenv("COGNITO_USER_POOL_ID"),
+			ClientID:   os.Getenv("COGNITO_CLIENT_ID"),
+			Domain:     os.Getenv("COGNITO_DOMAIN"),
+			Region:     os.Getenv("COGNITO_REGION"),
+		},
+	}
+}
+
+// DiscoveryCognito is the user pool clients log in with.
+type DiscoveryCognito struct {
+	UserPoolID string `json:"user_pool_id"`
+	ClientID   string `json:"client_id"`
+	Domain     string `json:"domain"` // Hosted UI domain, e.g. name.auth.us-west-1.amazoncognito.com
+	Region     string `json:"region"`
+}
+
+type DiscoveryResponse struct {
+	Service       string            `json:"service"`         // Always "eggcarton"
+	APIVersion    int               `json:"api_version"`     // Newest API version served
+	MinAPIVersion int               `json:"min_api_version"` // Oldest API version still served
+	APIEndpoint   string            `json:"api_endpoint"`    // Base URL of every other route
+	Cognito       *DiscoveryCognito `json:"cognito,omitempty"`
+	Features      []string          `json:"features"`
+}
+
+// IsPublic reports whether a route is served without authentication.
+func IsPublic(routeKey string) bool {
+	return routeKey == discoveryRoute
+}
+
+// Discovery serves GET /.well-known/eggcarton.
+func (h *Handlers) Discovery(ctx context.Context, request events.APIGatewayV2HTTPRequest) (events.APIGatewayV2HTTPResponse, error) {
+	return h.handle(h.discovery, recoverPanics)(ctx, request)
+}
+
+func (h *Handlers) discovery(ctx context.Context, r *Request) (Response, error) {
+	response := DiscoveryResponse{
+		Service:       "eggcarton",
+		APIVersion:    APIVersion,
+		MinAPIVersion: MinAPIVersion,
+		APIEndpoint:   h.Deployment.APIEndpoint,
+		Features:      Features,
+	}
+	if response.APIEndpoint == "" {
+		response.APIEndpoint = baseURL(r)
+	}
+	if h.Deployment.Cognito.ClientID != "" {
+		cognito := h.Deployment.Cognito
+		response.Cognito = &cognito
+	}
+	return Response{
+		StatusCode: http.StatusOK,
+		Body:       response,
+		Headers:    map[string]string{"Cache-Control": "public, max-age=300"},
+	}, nil
+}
+
+// baseURL is the URL the request was sent to, up to the stage, e.g.
+// https://abc123.execute-api.us-west-1.amazonaws.com/dev.
+func baseURL(r *Request) string {
+	scheme := r.Headers["x-forwarded-proto"]
+	if scheme == "" {
+		scheme = "https"
+	}
+	host := r.RequestContext.DomainName
+	if host == "" {
+		host = r.Headers["host"]
+	}
+	url := scheme + "://" + host
+	if stage := r.RequestContext.Stage; stage != "" && stage != "$default" {
+		url += "/" + stage
+	}
+	return url
+}
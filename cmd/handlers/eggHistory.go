@@ -0,0 +1,189 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/owenHochwald/egg-carton/cmd/actions"
+)
+
+// Routes served by EggHistory
+const (
+	listVersionsRoute   = "GET /eggs/{owner}/{secretId}/versions"
+	getVersionRoute     = "GET /eggs/{owner}/{secretId}/versions/{version}"
+	restoreVersionRoute = "POST /eggs/{owner}/{secretId}/versions/{version}/restore"
+)
+
+type VersionResponse struct {
+	Owner     string `json:"owner"`
+	SecretID  string `json:"secret_id"`
+	Project   string `json:"project,omitempty"`
+	Env       string `json:"env,omitempty"`
+	Version   int    `json:"version"`
+	CreatedAt string `json:"created_at"`
+	CreatedBy string `json:"created_by"`
+	ExpiresAt string `json:"expires_at,omitempty"`
+	Plaintext string `json:"plaintext,omitempty"` // Only set when fetching a single version
+}
+
+type ListVersionsResponse struct {
+	Versions []VersionResponse `json:"versions"`
+}
+
+type RestoreVersionResponse struct {
+	Message         string `json:"message"`
+	Owner           string `json:"owner"`
+	SecretID        string `json:"secret_id"`
+	Project         string `json:"project,omitempty"`
+	Env             string `json:"env,omitempty"`
+	RestoredVersion int    `json:"restored_version"`
+	Version         int    `json:"version"`
+	CreatedAt       string `json:"created_at"`
+}
+
+func (h *Handlers) EggHistory(ctx context.Context, request events.APIGatewayV2HTTPRequest) (events.APIGatewayV2HTTPResponse, error) {
+	// Every read and restore, including denied ones, goes in the vault's audit log
+	return h.serve(h.eggHistory, h.audited(actions.AuditHistory))(ctx, request)
+}
+
+func (h *Handlers) eggHistory(ctx context.Context, r *Request) (Response, error) {
+	// Get parameters from path
+	owner := r.PathParameters["owner"]
+	secretID := r.PathParameters["secretId"]
+	if owner == "" || secretID == "" {
+		return Response{}, invalidRequest("owner and secretId parameters are required")
+	}
+
+	// ?project=&env= select the namespace the secret lives in
+	namespace, err := namespaceParam(r)
+	if err != nil {
+		return Response{}, err
+	}
+	secretID = namespace.SecretID(secretID)
+
+	r.Audit.Vault = owner
+	r.Audit.SecretID = secretID
+	switch r.RouteKey {
+	case getVersionRoute:
+		r.Audit.Action = actions.AuditGet
+	case restoreVersionRoute:
+		r.Audit.Action = actions.AuditRestore
+	}
+
+	// Reading history needs the reader role on a team vault, restoring needs writer
+	required := actions.RoleReader
+	if r.RouteKey == restoreVersionRoute {
+		required = actions.RoleWriter
+	}
+	if err := h.authorize(ctx, r, owner, required, "Forbidden: you don't have access to this vault"); err != nil {
+		return Response{}, err
+	}
+
+	// A trashed egg's history is in the trash with it
+	if current, err := h.Repo.GetEgg(ctx, owner, secretID); err == nil && current.Trashed() {
+		return Response{}, notFound("Egg is in the trash")
+	}
+
+	if r.RouteKey == listVersionsRoute {
+		return h.listVersions(ctx, owner, secretID)
+	}
+
+	version, err := strconv.Atoi(r.PathParameters["version"])
+	if err != nil || version < 1 {
+		return Response{}, invalidRequest("version must be a positive integer")
+	}
+	r.Audit.Version = version
+
+	switch r.RouteKey {
+	case getVersionRoute:
+		return h.getVersion(ctx, r, owner, secretID, version)
+	case restoreVersionRoute:
+		return h.restoreVersion(ctx, owner, secretID, version, r.Caller.ID)
+	default:
+		return Response{}, notFound("Route not found")
+	}
+}
+
+// listVersions returns version metadata only; nothing is decrypted.
+func (h *Handlers) listVersions(ctx context.Context, owner, secretID string) (Response, error) {
+	versions, err := h.Repo.ListEggVersions(ctx, owner, secretID)
+	if err != nil {
+		return Response{}, internalError("Failed to retrieve versions", err)
+	}
+	if len(versions) == 0 {
+		return Response{}, notFound("Egg not found")
+	}
+
+	response := ListVersionsResponse{Versions: []VersionResponse{}}
+	for _, egg := range versions {
+		response.Versions = append(response.Versions, newVersionResponse(egg, ""))
+	}
+	return ok(response)
+}
+
+func (h *Handlers) getVersion(ctx context.Context, r *Request, owner, secretID string, version int) (Response, error) {
+	egg, err := h.Repo.GetEggVersion(ctx, owner, secretID, version)
+	if errors.Is(err, actions.ErrEggNotFound) {
+		return Response{}, notFound("Version not found")
+	}
+	if err != nil {
+		return Response{}, internalError("Failed to retrieve version", err)
+	}
+	if egg.Expired(time.Now()) {
+		return Response{}, expired("Version has expired")
+	}
+
+	plaintext, err := h.open(ctx, r, egg)
+	if err != nil {
+		return Response{}, err
+	}
+	return ok(newVersionResponse(egg, string(plaintext)))
+}
+
+func (h *Handlers) restoreVersion(ctx context.Context, owner, secretID string, version int, restoredBy string) (Response, error) {
+	egg, err := actions.RestoreEggVersion(ctx, h.Repo, owner, secretID, version, restoredBy)
+	if errors.Is(err, actions.ErrEggNotFound) {
+		return Response{}, notFound("Version not found")
+	}
+	if errors.Is(err, actions.ErrEggExpired) {
+		return Response{}, expired("Version has expired and can't be restored")
+	}
+	if errors.Is(err, actions.ErrEggConflict) {
+		return Response{}, errConcurrentUpdate
+	}
+	if err != nil {
+		return Response{}, internalError("Failed to restore version", err)
+	}
+
+	namespace, name := actions.SplitSecretID(secretID)
+	return ok(RestoreVersionResponse{
+		Message:         "Egg restored successfully",
+		Owner:           owner,
+		SecretID:        name,
+		Project:         namespace.Project,
+		Env:             namespace.Env,
+		RestoredVersion: version,
+		Version:         egg.Version,
+		CreatedAt:       egg.CreatedAt,
+	})
+}
+
+// newVersionResponse reports a version by name, with its project and env split
+// out of the stored SecretID.
+func newVersionResponse(egg actions.Egg, plaintext string) VersionResponse {
+	namespace, name := actions.SplitSecretID(egg.SecretID)
+	return VersionResponse{
+		Owner:     egg.Owner,
+		SecretID:  name,
+		Project:   namespace.Project,
+		Env:       namespace.Env,
+		Version:   egg.Version,
+		CreatedAt: egg.CreatedAt,
+		CreatedBy: egg.CreatedBy,
+		ExpiresAt: egg.Expiry(),
+		Plaintext: plaintext,
+	}
+}
@@ -0,0 +1,139 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/aws/smithy-go"
+)
+
+// Error codes sent in ErrorResponse. Clients match on them, so a code never
+// changes meaning once shipped; add a new one instead. The README documents
+// them under Error Responses.
+const (
+	CodeInvalidRequest = "invalid_request" // 400
+	CodeUnauthorized   = "unauthorized"    // 401
+	CodeForbidden      = "forbidden"       // 403
+	CodeNotFound       = "not_found"       // 404
+	CodeConflict       = "conflict"        // 409
+	CodeTrashed        = "trashed"         // 409
+	CodeExpired        = "expired"         // 410
+	CodeIncompatible   = "incompatible"    // 400
+	CodeThrottled      = "throttled"       // 429
+	CodeInternal       = "internal_error"  // 500
+)
+
+// ErrorResponse is the body of every error response.
+type ErrorResponse struct {
+	Code      string `json:"code"`
+	Message   string `json:"message"` // Human-readable, may change between releases
+	RequestID string `json:"request_id,omitempty"`
+	Retryable bool   `json:"retryable"` // Whether the same request may succeed if sent again
+	Error     string `json:"error"`     // Same as Message, for clients that predate it
+}
+
+// Error is an error an endpoint reports to the caller. Err, if set, is the
+// underlying cause: it is logged but never sent.
+type Error struct {
+	StatusCode int
+	Code       string
+	Message    string
+	Retryable  bool
+	Err        error
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+func invalidRequest(message string) *Error {
+	return &Error{StatusCode: http.StatusBadRequest, Code: CodeInvalidRequest, Message: message}
+}
+
+func unauthorized(message string) *Error {
+	return &Error{StatusCode: http.StatusUnauthorized, Code: CodeUnauthorized, Message: message}
+}
+
+func forbidden(message string) *Error {
+	return &Error{StatusCode: http.StatusForbidden, Code: CodeForbidden, Message: message}
+}
+
+func notFound(message string) *Error {
+	return &Error{StatusCode: http.StatusNotFound, Code: CodeNotFound, Message: message}
+}
+
+func expired(message string) *Error {
+	return &Error{StatusCode: http.StatusGone, Code: CodeExpired, Message: message}
+}
+
+func conflict(message string) *Error {
+	return &Error{StatusCode: http.StatusConflict, Code: CodeConflict, Message: message}
+}
+
+// trashed reports a write to a secret that is in the trash, which would
+// otherwise have to purge it.
+func trashed(message string) *Error {
+	return &Error{StatusCode: http.StatusConflict, Code: CodeTrashed, Message: message}
+}
+
+// incompatible reports a request written against an API version this
+// deployment doesn't serve.
+func incompatible(message string) *Error {
+	return &Error{StatusCode: http.StatusBadRequest, Code: CodeIncompatible, Message: message}
+}
+
+// internalError reports a failure of ours. The caller only sees message; err
+// goes to the logs. Failures caused by AWS throttling us are reported as
+// throttled, since backing off and retrying will get through.
+func internalError(message string, err error) *Error {
+	if isThrottled(err) {
+		return &Error{StatusCode: http.StatusTooManyRequests, Code: CodeThrottled, Message: message + ": too many requests, please retry", Retryable: true, Err: err}
+	}
+	return &Error{StatusCode: http.StatusInternalServerError, Code: CodeInternal, Message: message, Err: err}
+}
+
+// throttlingCodes are the AWS error codes for exceeding a request rate.
+var throttlingCodes = map[string]bool{
+	"ThrottlingException":                    true, // KMS, DynamoDB
+	"ProvisionedThroughputExceededException": true, // DynamoDB
+	"RequestLimitExceeded":                   true, // DynamoDB
+	"TooManyRequestsException":               true,
+}
+
+func isThrottled(err error) bool {
+	var apiErr smithy.APIError
+	return errors.As(err, &apiErr) && throttlingCodes[apiErr.ErrorCode()]
+}
+
+// errConcurrentUpdate reports losing a race with another writer of the egg.
+var errConcurrentUpdate = &Error{
+	StatusCode: http.StatusConflict,
+	Code:       CodeConflict,
+	Message:    "Egg was modified concurrently, please retry",
+	Retryable:  true,
+}
+
+// errTeamConcurrentUpdate reports losing a race with other changes to a
+// team's membership.
+var errTeamConcurrentUpdate = &Error{
+	StatusCode: http.StatusConflict,
+	Code:       CodeConflict,
+	Message:    "Team was modified concurrently, please retry",
+	Retryable:  true,
+}
+
+// asError returns err as an *Error, treating anything else as internal.
+func asError(err error) *Error {
+	var apiErr *Error
+	if errors.As(err, &apiErr) {
+		return apiErr
+	}
+	return internalError("Internal server error", err)
+}
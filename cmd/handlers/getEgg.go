@@ -0,0 +1,196 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/owenHochwald/egg-carton/cmd/actions"
+	"github.com/owenHochwald/egg-carton/pkg/crypto"
+)
+
+type GetEggResponse struct {
+	Owner     string `json:"owner"`
+	SecretID  string `json:"secret_id"` // Name within the project and env
+	Project   string `json:"project,omitempty"`
+	Env       string `json:"env,omitempty"`
+	Plaintext string `json:"plaintext"` // Decrypted secret
+	Version   int    `json:"version"`
+	CreatedAt string `json:"created_at"`
+	ExpiresAt string `json:"expires_at,omitempty"`
+}
+
+type GetEggsResponse struct {
+	Eggs       []GetEggResponse `json:"eggs"`
+	NextCursor string           `json:"next_cursor,omitempty"` // Pass as ?cursor= for the next page
+}
+
+// EggMetadata describes an egg without its value
+type EggMetadata struct {
+	Owner     string `json:"owner"`
+	SecretID  string `json:"secret_id"`
+	Project   string `json:"project,omitempty"`
+	Env       string `json:"env,omitempty"`
+	Version   int    `json:"version"`
+	Size      int    `json:"size"` // Plaintext length in bytes
+	CreatedAt string `json:"created_at"`
+	ExpiresAt string `json:"expires_at,omitempty"`
+}
+
+type ListEggsResponse struct {
+	Eggs       []EggMetadata `json:"eggs"`
+	NextCursor string        `json:"next_cursor,omitempty"` // Pass as ?cursor= for the next page
+}
+
+func (h *Handlers) GetEgg(ctx context.Context, request events.APIGatewayV2HTTPRequest) (events.APIGatewayV2HTTPResponse, error) {
+	// Every read, including denied ones, goes in the vault's audit log
+	return h.serve(h.getEggs, h.audited(actions.AuditList))(ctx, request)
+}
+
+func (h *Handlers) getEggs(ctx context.Context, r *Request) (Response, error) {
+	// Get owner from path parameter
+	owner := r.PathParameters["owner"]
+	if owner == "" {
+		return Response{}, invalidRequest("owner parameter is required")
+	}
+
+	// ?project=&env= scope the request to one namespace
+	namespace, err := namespaceParam(r)
+	if err != nil {
+		return Response{}, err
+	}
+
+	r.Audit.Vault = owner
+	secretID := r.PathParameters["secretId"]
+	if secretID != "" {
+		r.Audit.Action = actions.AuditGet
+		r.Audit.SecretID = namespace.SecretID(secretID)
+	}
+
+	// Members of a team vault need at least the reader role; everyone owns their own vault
+	if err := h.authorize(ctx, r, owner, actions.RoleReader, "Forbidden: you don't have read access to this vault"); err != nil {
+		return Response{}, err
+	}
+
+	// GET /eggs/{owner}/{secretId} fetches and decrypts a single egg
+	if secretID != "" {
+		return h.getOneEgg(ctx, r, owner, namespace.SecretID(secretID))
+	}
+
+	// ?limit= and ?cursor= page through the vault
+	limit, cursor, paged, err := pageParams(r)
+	if err != nil {
+		return Response{}, err
+	}
+
+	// Retrieve the owner's eggs, only those in the namespace if one was given
+	var eggs []actions.Egg
+	var next string
+	switch {
+	case paged:
+		eggs, next, err = h.Repo.ListEggs(ctx, owner, namespace.Prefix(), cursor, limit)
+	case namespace.IsZero():
+		eggs, err = h.Repo.GetAllEggs(ctx, owner)
+	default:
+		eggs, err = h.Repo.GetEggsWithPrefix(ctx, owner, namespace.Prefix())
+	}
+	if errors.Is(err, actions.ErrInvalidCursor) {
+		return Response{}, invalidRequest("cursor must come from next_cursor of the same listing")
+	}
+	if err != nil {
+		return Response{}, internalError("Failed to retrieve eggs", err)
+	}
+
+	// Expired and trashed eggs are gone as far as callers are concerned,
+	// even before DynamoDB's TTL gets round to deleting them. They are
+	// dropped after paging, so a page can come back short or even empty
+	// with a next_cursor.
+	eggs = actions.LiveEggs(eggs, time.Now())
+
+	// ?fields=meta lists the vault without touching KMS
+	if r.QueryStringParameters["fields"] == "meta" {
+		response := listEggs(eggs)
+		response.NextCursor = next
+		return ok(response)
+	}
+
+	// Decrypt each egg
+	var decryptedEggs []GetEggResponse
+	for _, egg := range eggs {
+		plaintext, err := h.open(ctx, r, egg)
+		if err != nil {
+			// Skip this egg but continue with others
+			r.Log.Warn("skipping egg", "error", err.Error())
+			continue
+		}
+		decryptedEggs = append(decryptedEggs, newGetEggResponse(egg, string(plaintext)))
+	}
+
+	// Return all decrypted eggs
+	return ok(GetEggsResponse{Eggs: decryptedEggs, NextCursor: next})
+}
+
+// getOneEgg does a point lookup of one egg, so only that egg's data key is
+// sent to KMS.
+func (h *Handlers) getOneEgg(ctx context.Context, r *Request, owner, secretID string) (Response, error) {
+	egg, err := h.Repo.GetEgg(ctx, owner, secretID)
+	if errors.Is(err, actions.ErrEggNotFound) {
+		return Response{}, notFound("Egg not found")
+	}
+	if err != nil {
+		return Response{}, internalError("Failed to retrieve egg", err)
+	}
+
+	r.Audit.Version = egg.Version
+	if egg.Trashed() {
+		return Response{}, notFound("Egg is in the trash")
+	}
+	if egg.Expired(time.Now()) {
+		return Response{}, expired("Egg has expired")
+	}
+
+	plaintext, err := h.open(ctx, r, egg)
+	if err != nil {
+		return Response{}, err
+	}
+	response, err := ok(newGetEggResponse(egg, string(plaintext)))
+	response.Headers = etag(egg)
+	return response, err
+}
+
+// listEggs returns the metadata of every egg. Nothing is decrypted, so this
+// costs no KMS calls and no plaintext leaves the vault.
+func listEggs(eggs []actions.Egg) ListEggsResponse {
+	response := ListEggsResponse{Eggs: []EggMetadata{}}
+	for _, egg := range eggs {
+		namespace, name := actions.SplitSecretID(egg.SecretID)
+		response.Eggs = append(response.Eggs, EggMetadata{
+			Owner:     egg.Owner,
+			SecretID:  name,
+			Project:   namespace.Project,
+			Env:       namespace.Env,
+			Version:   egg.Version,
+			Size:      crypto.PlaintextSize(egg.Ciphertext),
+			CreatedAt: egg.CreatedAt,
+			ExpiresAt: egg.Expiry(),
+		})
+	}
+	return response
+}
+
+// newGetEggResponse reports the egg by name, with its project and env split
+// out of the stored SecretID.
+func newGetEggResponse(egg actions.Egg, plaintext string) GetEggResponse {
+	namespace, name := actions.SplitSecretID(egg.SecretID)
+	return GetEggResponse{
+		Owner:     egg.Owner,
+		SecretID:  name,
+		Project:   namespace.Project,
+		Env:       namespace.Env,
+		Plaintext: plaintext,
+		Version:   egg.Version,
+		CreatedAt: egg.CreatedAt,
+		ExpiresAt: egg.Expiry(),
+	}
+}
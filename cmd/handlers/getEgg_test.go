@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/owenHochwald/egg-carton/pkg/crypto"
+)
+
+// lockedKeys refuses to unwrap data keys, standing in for KMS denying
+// decryption
+type lockedKeys struct {
+	crypto.KeyProvider
+}
+
+func (lockedKeys) DecryptDataKey(ctx context.Context, encryptedKey []byte, encryptionContext map[string]string) ([]byte, error) {
+	return nil, errors.New("decryption not allowed")
+}
+
+func TestListEggsMetadata(t *testing.T) {
+	ctx := context.Background()
+	h := newTestHandlers(t)
+	for _, secret := range []struct{ id, plaintext string }{
+		{"DB_URL", "postgres://user:hunter2@db"},
+		{"API_KEY", "sk-live-123456"},
+	} {
+		if response, _ := h.PutEgg(ctx, newPutRequest("alice", secret.id, secret.plaintext)); response.StatusCode != 201 {
+			t.Fatalf("PutEgg: %d %s", response.StatusCode, response.Body)
+		}
+	}
+	stored, _ := h.Repo.GetEgg(ctx, "alice", "DB_URL")
+
+	// Listing metadata never needs a data key
+	h.Keys = lockedKeys{h.Keys}
+	request := newTestRequest("alice")
+	request.RouteKey = "GET /eggs/{owner}"
+	request.PathParameters = map[string]string{"owner": "alice"}
+	request.QueryStringParameters = map[string]string{"fields": "meta"}
+	response, _ := h.GetEgg(ctx, request)
+	if response.StatusCode != 200 {
+		t.Fatalf("GetEgg: %d %s", response.StatusCode, response.Body)
+	}
+
+	for _, leak := range []string{"hunter2", "sk-live", "plaintext", "ciphertext", "encrypted_data_key"} {
+		if strings.Contains(strings.ToLower(response.Body), leak) {
+			t.Errorf("metadata listing contains %q: %s", leak, response.Body)
+		}
+	}
+	for _, material := range []string{
+		base64.StdEncoding.EncodeToString(stored.Ciphertext),
+		base64.StdEncoding.EncodeToString(stored.EncryptedDataKey),
+	} {
+		if strings.Contains(response.Body, material) {
+			t.Errorf("metadata listing carries stored key material: %s", response.Body)
+		}
+	}
+
+	var body ListEggsResponse
+	if err := json.Unmarshal([]byte(response.Body), &body); err != nil {
+		t.Fatal(err)
+	}
+	want := []EggMetadata{
+		{Owner: "alice", SecretID: "API_KEY", Version: 1, Size: len("sk-live-123456")},
+		{Owner: "alice", SecretID: "DB_URL", Version: 1, Size: len("postgres://user:hunter2@db")},
+	}
+	if len(body.Eggs) != len(want) {
+		t.Fatalf("got %d eggs, want %d: %+v", len(body.Eggs), len(want), body.Eggs)
+	}
+	for i, egg := range body.Eggs {
+		egg.CreatedAt = ""
+		if egg != want[i] {
+			t.Errorf("egg %d: got %+v, want %+v", i, egg, want[i])
+		}
+	}
+
+	// Without fields=meta the same listing has to decrypt, and can't
+	delete(request.QueryStringParameters, "fields")
+	response, _ = h.GetEgg(ctx, request)
+	var full GetEggsResponse
+	json.Unmarshal([]byte(response.Body), &full)
+	if len(full.Eggs) != 0 {
+		t.Errorf("decrypted %d eggs with locked keys", len(full.Eggs))
+	}
+}
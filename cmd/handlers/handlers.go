@@ -0,0 +1,61 @@
+// Package handlers holds the HTTP API of egg-carton as API Gateway (HTTP API,
+// payload v2) handlers. Each Lambda in cmd/lambda serves one of them, and
+// cmd/server serves all of them from a single local process.
+package handlers
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/owenHochwald/egg-carton/cmd/actions"
+	"github.com/owenHochwald/egg-carton/pkg/crypto"
+)
+
+// Handler serves one API Gateway request.
+type Handler func(ctx context.Context, request events.APIGatewayV2HTTPRequest) (events.APIGatewayV2HTTPResponse, error)
+
+// Handlers holds what the handlers share. Keys is only needed by the
+// handlers that encrypt or decrypt eggs (PutEgg, GetEgg and EggHistory), and
+// AuditKey by every handler that records or verifies audit events.
+type Handlers struct {
+	Repo           actions.EggActions
+	Keys           crypto.KeyProvider
+	AuditKey       crypto.MACKey
+	TrashRetention time.Duration // How long BreakEgg keeps eggs in the trash
+	Logger         *slog.Logger  // JSON to stdout if nil
+	Deployment     Deployment    // Advertised by Discovery
+}
+
+func (h *Handlers) logger() *slog.Logger {
+	if h.Logger == nil {
+		return slog.New(slog.NewJSONHandler(os.Stdout, nil))
+	}
+	return h.Logger
+}
+
+// Routes maps every API Gateway route key to the handler serving it.
+func (h *Handlers) Routes() map[string]Handler {
+	return map[string]Handler{
+		"POST /eggs":                   h.PutEgg,
+		putEggsRoute:                   h.PutEgg,
+		"GET /eggs/{owner}":            h.GetEgg,
+		"GET /eggs/{owner}/{secretId}": h.GetEgg,
+		breakEggRoute:                  h.BreakEgg,
+		breakEggsRoute:                 h.BreakEgg,
+		listTrashRoute:                 h.BreakEgg,
+		restoreEggRoute:                h.BreakEgg,
+		listVersionsRoute:              h.EggHistory,
+		getVersionRoute:                h.EggHistory,
+		restoreVersionRoute:            h.EggHistory,
+		createTeamRoute:                h.Teams,
+		listTeamsRoute:                 h.Teams,
+		listMembersRoute:               h.Teams,
+		putMemberRoute:                 h.Teams,
+		removeMemberRoute:              h.Teams,
+		"GET /audit":                   h.Audit,
+		discoveryRoute:                 h.Discovery,
+	}
+}
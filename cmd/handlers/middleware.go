@@ -0,0 +1,333 @@
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/owenHochwald/egg-carton/cmd/actions"
+	"github.com/owenHochwald/egg-carton/pkg/crypto"
+)
+
+// Request is an API Gateway request on its way through the middleware.
+type Request struct {
+	events.APIGatewayV2HTTPRequest
+	ID     string              // Request ID, sent back in X-Request-Id and error bodies
+	Caller Caller              // Set by authenticate
+	Audit  *actions.AuditEvent // Set by audited; endpoints fill in Vault, SecretID and Version
+	Log    *slog.Logger        // Tagged with the request ID, route and caller
+
+	// Decrypted is set once the endpoint has decrypted a secret, so audited
+	// withholds the response if its event can't be recorded.
+	Decrypted bool
+
+	// AuditItems, if a batch endpoint sets it, is recorded by audited in
+	// place of Audit: one event per secret, each with its own outcome. Audit
+	// is still recorded if the batch as a whole fails.
+	AuditItems []AuditItem
+}
+
+// AuditItem is the audit event of one secret in a batch request.
+type AuditItem struct {
+	Event      actions.AuditEvent
+	StatusCode int // The status the secret's own request would have had
+}
+
+// Caller is the identity of an authenticated caller.
+type Caller struct {
+	ID     string            // The token's sub claim
+	Claims map[string]string // Every claim, as passed by API Gateway
+}
+
+// Response is a successful response; Body is sent as JSON.
+type Response struct {
+	StatusCode int
+	Body       any
+	Headers    map[string]string // Sent alongside the ones serve sets
+}
+
+func ok(body any) (Response, error) {
+	return Response{StatusCode: http.StatusOK, Body: body}, nil
+}
+
+func created(body any) (Response, error) {
+	return Response{StatusCode: http.StatusCreated, Body: body}, nil
+}
+
+// Endpoint serves one request. Errors that aren't an *Error are reported to
+// the caller as internal errors.
+type Endpoint func(ctx context.Context, r *Request) (Response, error)
+
+// Middleware wraps an endpoint with behaviour shared by several routes.
+type Middleware func(next Endpoint) Endpoint
+
+// serve turns endpoint into a Handler. Every request has its panics
+// recovered, its API version checked and its caller authenticated before the
+// given middleware and the endpoint run. Responses are JSON, errors use ErrorResponse, and each request
+// is logged once it completes.
+func (h *Handlers) serve(endpoint Endpoint, middleware ...Middleware) Handler {
+	return h.handle(endpoint, append([]Middleware{recoverPanics, checkAPIVersion, authenticate}, middleware...)...)
+}
+
+// handle turns endpoint into a Handler, running only the given middleware
+// before it. Public routes use it directly, skipping authenticate.
+func (h *Handlers) handle(endpoint Endpoint, middleware ...Middleware) Handler {
+	for i := len(middleware) - 1; i >= 0; i-- {
+		endpoint = middleware[i](endpoint)
+	}
+
+	return func(ctx context.Context, request events.APIGatewayV2HTTPRequest) (events.APIGatewayV2HTTPResponse, error) {
+		start := time.Now()
+		r := h.newRequest(request)
+		response, err := endpoint(ctx, r)
+
+		statusCode, body := response.StatusCode, response.Body
+		if err != nil {
+			apiErr := asError(err)
+			if apiErr.StatusCode >= 500 {
+				r.Log.Error(apiErr.Message, "error", err.Error())
+			}
+			statusCode, body = apiErr.StatusCode, ErrorResponse{
+				Code:      apiErr.Code,
+				Message:   apiErr.Message,
+				RequestID: r.ID,
+				Retryable: apiErr.Retryable,
+				Error:     apiErr.Message,
+			}
+		}
+		r.Log.Info("request", "status", statusCode, "duration_ms", time.Since(start).Milliseconds())
+
+		headers := map[string]string{
+			"Content-Type": "application/json",
+			"X-Request-Id": r.ID,
+		}
+		if err == nil {
+			for name, value := range response.Headers {
+				headers[name] = value
+			}
+		}
+		responseBody, _ := json.Marshal(body)
+		return events.APIGatewayV2HTTPResponse{
+			StatusCode: statusCode,
+			Body:       string(responseBody),
+			Headers:    headers,
+		}, nil
+	}
+}
+
+// newRequest starts r on its way. The request ID is API Gateway's, or the
+// caller's X-Request-Id if there is none (as when run locally).
+func (h *Handlers) newRequest(request events.APIGatewayV2HTTPRequest) *Request {
+	id := request.RequestContext.RequestID
+	if id == "" {
+		id = request.Headers["x-request-id"]
+	}
+	if id == "" {
+		id = newRequestID()
+	}
+	request.RequestContext.RequestID = id
+
+	return &Request{
+		APIGatewayV2HTTPRequest: request,
+		ID:                      id,
+		Log:                     h.logger().With("request_id", id, "route", request.RouteKey),
+	}
+}
+
+func newRequestID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		panic("unable to generate request ID: " + err.Error())
+	}
+	return hex.EncodeToString(b)
+}
+
+// recoverPanics turns a panic into an internal error, so one bad request
+// can't take the function down.
+func recoverPanics(next Endpoint) Endpoint {
+	return func(ctx context.Context, r *Request) (response Response, err error) {
+		defer func() {
+			if p := recover(); p != nil {
+				err = internalError("Internal server error", fmt.Errorf("panic: %v\n%s", p, debug.Stack()))
+			}
+		}()
+		return next(ctx, r)
+	}
+}
+
+// apiVersionHeader carries the API version a client wrote its request
+// against. Requests without it, from older CLIs and curl, are served as the
+// current version.
+const apiVersionHeader = "x-egg-api-version"
+
+// checkAPIVersion refuses requests written against an API version outside
+// MinAPIVersion to APIVersion, rather than guessing at what they mean.
+func checkAPIVersion(next Endpoint) Endpoint {
+	return func(ctx context.Context, r *Request) (Response, error) {
+		header, sent := r.Headers[apiVersionHeader]
+		if !sent {
+			return next(ctx, r)
+		}
+		version, err := strconv.Atoi(header)
+		if err != nil || version < MinAPIVersion || version > APIVersion {
+			return Response{}, incompatible(fmt.Sprintf("API version %q isn't served here; this deployment serves versions %d to %d", header, MinAPIVersion, APIVersion))
+		}
+		return next(ctx, r)
+	}
+}
+
+// authenticate identifies the caller from the JWT claims API Gateway passes
+// after validating the token.
+func authenticate(next Endpoint) Endpoint {
+	return func(ctx context.Context, r *Request) (Response, error) {
+		authorizer := r.RequestContext.Authorizer
+		if authorizer == nil || authorizer.JWT == nil || authorizer.JWT.Claims["sub"] == "" {
+			return Response{}, unauthorized("Unauthorized: user ID not found in token")
+		}
+		r.Caller = Caller{ID: authorizer.JWT.Claims["sub"], Claims: authorizer.JWT.Claims}
+		r.Log = r.Log.With("caller", r.Caller.ID)
+		return next(ctx, r)
+	}
+}
+
+// audited records every request, including denied and failed ones, in the
+// audit log of the vault the endpoint names in r.Audit.
+//
+// A request that decrypted a secret (see Request.Decrypted) fails closed: if
+// its event can't be appended the plaintext is withheld and the caller gets a
+// 500, so no successful decrypt goes unaudited. Every other request fails open, keeping
+// the API up through an audit outage; the gap is logged as
+// "audit_append_failed" for alerting.
+func (h *Handlers) audited(action string) Middleware {
+	return func(next Endpoint) Endpoint {
+		return func(ctx context.Context, r *Request) (response Response, err error) {
+			event := actions.NewAuditEvent(r.APIGatewayV2HTTPRequest, action)
+			r.Audit = &event
+			// Deferred so that panics are recorded too
+			recorded := false
+			defer func() {
+				if !recorded {
+					h.recordAudit(ctx, r, statusCode(response, err))
+				}
+			}()
+
+			response, err = next(ctx, r)
+			recorded = true
+			auditErr := h.recordAudit(ctx, r, statusCode(response, err))
+			if auditErr != nil && err == nil && r.Decrypted {
+				return Response{}, internalError("Failed to record audit event", auditErr)
+			}
+			return response, err
+		}
+	}
+}
+
+// recordAudit appends r's audit events for a response of status: one per
+// item of a batch, or r.Audit alone. Each failure is logged, and the last is
+// returned.
+func (h *Handlers) recordAudit(ctx context.Context, r *Request, status int) error {
+	events := slices.Clone(r.AuditItems)
+	if len(events) == 0 || status >= 300 {
+		events = append(events, AuditItem{Event: *r.Audit, StatusCode: status})
+	}
+	var failed error
+	for _, item := range events {
+		if err := actions.RecordAudit(ctx, h.Repo, h.AuditKey, item.Event, item.StatusCode); err != nil {
+			r.Log.Error("audit_append_failed", "vault", item.Event.Vault, "action", item.Event.Action,
+				"secret_id", item.Event.SecretID, "status", item.StatusCode, "error", err.Error())
+			failed = err
+		}
+	}
+	return failed
+}
+
+// statusCode is the status the caller will see for an endpoint's result.
+func statusCode(response Response, err error) int {
+	if err != nil {
+		return asError(err).StatusCode
+	}
+	if response.StatusCode == 0 {
+		return http.StatusInternalServerError // Panicked
+	}
+	return response.StatusCode
+}
+
+// authorize checks that the caller holds at least required on owner's vault,
+// reporting denied if not.
+func (h *Handlers) authorize(ctx context.Context, r *Request, owner string, required actions.Role, denied string) error {
+	err := actions.Authorize(ctx, h.Repo, r.Caller.Claims, owner, required)
+	if errors.Is(err, actions.ErrForbidden) {
+		return forbidden(denied)
+	}
+	if err != nil {
+		return internalError("Failed to check vault access", err)
+	}
+	return nil
+}
+
+// decodeBody unmarshals the JSON request body into v.
+func decodeBody(r *Request, v any) error {
+	if err := json.Unmarshal([]byte(r.Body), v); err != nil {
+		return invalidRequest("Invalid request body")
+	}
+	return nil
+}
+
+// namespaceParam reads the namespace selected by ?project= and ?env=.
+func namespaceParam(r *Request) (actions.Namespace, error) {
+	namespace, err := actions.NewNamespace(r.QueryStringParameters["project"], r.QueryStringParameters["env"])
+	if err != nil {
+		return namespace, invalidRequest(err.Error())
+	}
+	return namespace, nil
+}
+
+// Page sizes for ?limit=
+const (
+	defaultPageSize = 100
+	maxPageSize     = 1000
+)
+
+// pageParams reads ?limit= and ?cursor=. paged is false if the request sent
+// neither; such requests get every result at once, as clients that predate
+// pagination expect.
+func pageParams(r *Request) (limit int, cursor string, paged bool, err error) {
+	limitParam, hasLimit := r.QueryStringParameters["limit"]
+	cursor, hasCursor := r.QueryStringParameters["cursor"]
+	if !hasLimit && !hasCursor {
+		return 0, "", false, nil
+	}
+	limit = defaultPageSize
+	if hasLimit {
+		limit, err = strconv.Atoi(limitParam)
+		if err != nil || limit < 1 || limit > maxPageSize {
+			return 0, "", false, invalidRequest(fmt.Sprintf("limit must be a number from 1 to %d", maxPageSize))
+		}
+	}
+	return limit, cursor, true, nil
+}
+
+// open decrypts egg, unwrapping its data key with the key provider, and
+// marks r as carrying plaintext.
+func (h *Handlers) open(ctx context.Context, r *Request, egg actions.Egg) ([]byte, error) {
+	dataKey, err := h.Keys.DecryptDataKey(ctx, egg.EncryptedDataKey, egg.EncryptionContext())
+	if err != nil {
+		return nil, internalError("Failed to decrypt data key", fmt.Errorf("%s: %w", egg.SecretID, err))
+	}
+	plaintext, err := crypto.Open(egg.Ciphertext, dataKey, egg.AdditionalData())
+	if err != nil {
+		return nil, internalError("Failed to decrypt data", fmt.Errorf("%s: %w", egg.SecretID, err))
+	}
+	r.Decrypted = true
+	return plaintext, nil
+}
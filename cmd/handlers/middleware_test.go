@@ -0,0 +1,189 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/smithy-go"
+	"github.com/owenHochwald/egg-carton/cmd/actions"
+)
+
+func newTestRequest(caller string) events.APIGatewayV2HTTPRequest {
+	request := events.APIGatewayV2HTTPRequest{RouteKey: "GET /test", Headers: map[string]string{"x-request-id": "req-1"}}
+	request.RequestContext.Authorizer = &events.APIGatewayV2HTTPRequestContextAuthorizerDescription{
+		JWT: &events.APIGatewayV2HTTPRequestContextAuthorizerJWTDescription{Claims: map[string]string{"sub": caller}},
+	}
+	return request
+}
+
+func TestServe(t *testing.T) {
+	ctx := context.Background()
+	repo := actions.NewMemoryEggRepository()
+	h := &Handlers{Repo: repo, AuditKey: newAuditKey(t), Logger: slog.New(slog.NewJSONHandler(io.Discard, nil))}
+
+	var caller string
+	handler := h.serve(func(ctx context.Context, r *Request) (Response, error) {
+		caller = r.Caller.ID
+		r.Audit.Vault = r.Caller.ID
+		switch r.QueryStringParameters["fail"] {
+		case "missing":
+			return Response{}, notFound("Egg not found")
+		case "panic":
+			panic("boom")
+		}
+		return ok(map[string]string{"hello": r.Caller.ID})
+	}, h.audited(actions.AuditGet))
+
+	response, _ := handler(ctx, newTestRequest("alice"))
+	if response.StatusCode != 200 || response.Body != `{"hello":"alice"}` || caller != "alice" {
+		t.Errorf("unexpected response %+v", response)
+	}
+	if response.Headers["X-Request-Id"] != "req-1" {
+		t.Errorf("request ID not propagated: %v", response.Headers)
+	}
+
+	// Errors use the envelope, with the request ID
+	request := newTestRequest("alice")
+	request.QueryStringParameters = map[string]string{"fail": "missing"}
+	response, _ = handler(ctx, request)
+	var body ErrorResponse
+	if err := json.Unmarshal([]byte(response.Body), &body); err != nil {
+		t.Fatal(err)
+	}
+	if response.StatusCode != 404 || body != (ErrorResponse{Code: CodeNotFound, Message: "Egg not found", RequestID: "req-1", Error: "Egg not found"}) {
+		t.Errorf("unexpected error response %d %+v", response.StatusCode, body)
+	}
+
+	// Panics become internal errors, without leaking the panic
+	request.QueryStringParameters = map[string]string{"fail": "panic"}
+	response, _ = handler(ctx, request)
+	if err := json.Unmarshal([]byte(response.Body), &body); err != nil {
+		t.Fatal(err)
+	}
+	if response.StatusCode != 500 || body.Code != CodeInternal || body.Error != "Internal server error" {
+		t.Errorf("unexpected panic response %d %+v", response.StatusCode, body)
+	}
+
+	// Unauthenticated requests never reach the endpoint
+	caller = ""
+	response, _ = handler(ctx, newTestRequest(""))
+	if response.StatusCode != 401 || caller != "" {
+		t.Errorf("unauthenticated request served: %+v", response)
+	}
+
+	// Every authenticated request was audited, the panic included
+	events, err := repo.ListAuditEvents(ctx, "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var outcomes []string
+	for _, event := range events {
+		outcomes = append(outcomes, event.Outcome)
+	}
+	if len(outcomes) != 3 || outcomes[0] != actions.OutcomeSuccess || outcomes[1] != actions.OutcomeNotFound || outcomes[2] != actions.OutcomeError {
+		t.Errorf("unexpected audit outcomes %v", outcomes)
+	}
+	if events[0].RequestID != "req-1" {
+		t.Errorf("request ID not in audit event: %+v", events[0])
+	}
+}
+
+// brokenAudit fails every audit append, as an unreachable table would
+type brokenAudit struct {
+	actions.EggActions
+}
+
+func (brokenAudit) PutAuditEvent(ctx context.Context, event actions.AuditEvent) error {
+	return errors.New("put failed")
+}
+
+func TestAuditFailsClosed(t *testing.T) {
+	ctx := context.Background()
+	h := newTestHandlers(t)
+	if response, _ := h.PutEgg(ctx, newPutRequest("alice", "API_KEY", "sk-live-123456")); response.StatusCode != 201 {
+		t.Fatalf("PutEgg: %d %s", response.StatusCode, response.Body)
+	}
+	var logs bytes.Buffer
+	h.Logger = slog.New(slog.NewJSONHandler(&logs, nil))
+	h.Repo = brokenAudit{h.Repo}
+
+	// Reads that decrypt withhold the secret when they can't be audited
+	get := newTestRequest("alice")
+	get.RouteKey = "GET /eggs/{owner}/{secretId}"
+	get.PathParameters = map[string]string{"owner": "alice", "secretId": "API_KEY"}
+	list := newTestRequest("alice")
+	list.RouteKey = "GET /eggs/{owner}"
+	list.PathParameters = map[string]string{"owner": "alice"}
+	for _, request := range []events.APIGatewayV2HTTPRequest{get, list} {
+		response, _ := h.GetEgg(ctx, request)
+		if response.StatusCode != 500 || strings.Contains(response.Body, "sk-live") {
+			t.Errorf("%s: got %d %s, want 500 without the secret", request.RouteKey, response.StatusCode, response.Body)
+		}
+	}
+
+	// Everything else still goes through
+	list.QueryStringParameters = map[string]string{"fields": "meta"}
+	if response, _ := h.GetEgg(ctx, list); response.StatusCode != 200 {
+		t.Errorf("metadata listing: got %d %s, want 200", response.StatusCode, response.Body)
+	}
+	if response, _ := h.PutEgg(ctx, newPutRequest("alice", "DB_URL", "postgres://")); response.StatusCode != 201 {
+		t.Errorf("PutEgg: got %d %s, want 201", response.StatusCode, response.Body)
+	}
+
+	// Each gap is logged for alerting
+	if n := strings.Count(logs.String(), `"msg":"audit_append_failed"`); n != 4 {
+		t.Errorf("got %d audit_append_failed logs, want 4:\n%s", n, logs.String())
+	}
+}
+
+func TestInternalErrorThrottled(t *testing.T) {
+	err := internalError("Failed to store egg", fmt.Errorf("put: %w", &smithy.GenericAPIError{Code: "ProvisionedThroughputExceededException"}))
+	if err.StatusCode != 429 || err.Code != CodeThrottled || !err.Retryable {
+		t.Errorf("throttling not reported as throttled: %+v", err)
+	}
+	if err := internalError("Failed to store egg", errors.New("boom")); err.StatusCode != 500 || err.Code != CodeInternal || err.Retryable {
+		t.Errorf("unexpected internal error %+v", err)
+	}
+}
+
+func TestCheckAPIVersion(t *testing.T) {
+	h := &Handlers{Repo: actions.NewMemoryEggRepository(), AuditKey: newAuditKey(t), Logger: slog.New(slog.NewJSONHandler(io.Discard, nil))}
+	handler := h.serve(func(ctx context.Context, r *Request) (Response, error) {
+		return ok(map[string]string{})
+	})
+
+	tests := []struct {
+		header string
+		want   int
+	}{
+		{"", 200}, // Not sent
+		{fmt.Sprint(MinAPIVersion), 200},
+		{fmt.Sprint(APIVersion), 200},
+		{fmt.Sprint(APIVersion + 1), 400},
+		{fmt.Sprint(MinAPIVersion - 1), 400},
+		{"v1", 400},
+	}
+	for _, tt := range tests {
+		request := newTestRequest("alice")
+		if tt.header != "" {
+			request.Headers[apiVersionHeader] = tt.header
+		}
+		response, _ := handler(context.Background(), request)
+		if response.StatusCode != tt.want {
+			t.Errorf("version %q: status %d, want %d", tt.header, response.StatusCode, tt.want)
+			continue
+		}
+		var body ErrorResponse
+		if tt.want != 200 && (json.Unmarshal([]byte(response.Body), &body) != nil || body.Code != CodeIncompatible) {
+			t.Errorf("version %q: unexpected error %s", tt.header, response.Body)
+		}
+	}
+}
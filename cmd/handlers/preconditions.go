@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/owenHochwald/egg-carton/cmd/actions"
+)
+
+// An egg's ETag is its version, so a client that read version 3 can write
+// with If-Match: "3" and only succeed if nobody has written since.
+
+func etag(egg actions.Egg) map[string]string {
+	return map[string]string{"ETag": strconv.Quote(strconv.Itoa(egg.Version))}
+}
+
+// expectedVersion reads the request's preconditions: If-None-Match: * for
+// create-only writes, If-Match with the version the caller last saw, or
+// neither for an unconditional write.
+func expectedVersion(r *Request) (int, error) {
+	if r.Headers["if-none-match"] == "*" {
+		return actions.NoVersion, nil
+	}
+	match := r.Headers["if-match"]
+	if match == "" {
+		return actions.AnyVersion, nil
+	}
+	match = strings.TrimPrefix(strings.TrimSpace(match), "W/")
+	version, err := strconv.Atoi(strings.Trim(match, `"`))
+	if err != nil || version < 1 {
+		return 0, invalidRequest("If-Match must be an egg version such as \"3\"")
+	}
+	return version, nil
+}
+
+// versionConflict reports a write whose precondition didn't hold.
+func versionConflict(expected int) *Error {
+	switch expected {
+	case actions.AnyVersion:
+		return errConcurrentUpdate
+	case actions.NoVersion:
+		return conflict("Egg already exists")
+	default:
+		return conflict(fmt.Sprintf("Egg has changed since version %d", expected))
+	}
+}
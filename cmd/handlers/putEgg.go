@@ -0,0 +1,200 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/owenHochwald/egg-carton/cmd/actions"
+	"github.com/owenHochwald/egg-carton/pkg/crypto"
+)
+
+type PutEggRequest struct {
+	SecretID  string `json:"secret_id"`
+	Plaintext string `json:"plaintext"`
+	Project   string `json:"project,omitempty"`
+	Env       string `json:"env,omitempty"`
+	Team      string `json:"team,omitempty"`       // Lay into this team's vault instead of your own
+	ExpiresAt string `json:"expires_at,omitempty"` // RFC 3339; the egg never expires if empty
+}
+
+type PutEggResponse struct {
+	Message   string `json:"message"`
+	Owner     string `json:"owner"`
+	SecretID  string `json:"secret_id"`
+	Project   string `json:"project,omitempty"`
+	Env       string `json:"env,omitempty"`
+	Version   int    `json:"version"`
+	CreatedAt string `json:"created_at"`
+	ExpiresAt string `json:"expires_at,omitempty"`
+}
+
+func (h *Handlers) PutEgg(ctx context.Context, request events.APIGatewayV2HTTPRequest) (events.APIGatewayV2HTTPResponse, error) {
+	// Every write, including denied ones, goes in the vault's audit log
+	return h.serve(h.putEgg, h.audited(actions.AuditPut))(ctx, request)
+}
+
+func (h *Handlers) putEgg(ctx context.Context, r *Request) (Response, error) {
+	// POST /eggs:batch lays several eggs at once
+	if r.RouteKey == putEggsRoute {
+		return h.putEggs(ctx, r)
+	}
+
+	var req PutEggRequest
+	if err := decodeBody(r, &req); err != nil {
+		return Response{}, err
+	}
+
+	// Validate input
+	if req.SecretID == "" || req.Plaintext == "" {
+		return Response{}, invalidRequest("secret_id and plaintext are required")
+	}
+	expiresAt, err := parseExpiry(req.ExpiresAt)
+	if err != nil {
+		return Response{}, err
+	}
+
+	// Scope the secret to its project and environment, if any
+	namespace, err := actions.NewNamespace(req.Project, req.Env)
+	if err == nil {
+		err = actions.ValidateSecretName(req.SecretID)
+	}
+	if err != nil {
+		return Response{}, invalidRequest(err.Error())
+	}
+
+	// If-None-Match: * only creates, If-Match only replaces that version
+	precondition, err := expectedVersion(r)
+	if err != nil {
+		return Response{}, err
+	}
+
+	// Secrets go in the caller's own vault unless a team is named, which
+	// needs the writer role
+	owner, err := vaultOwner(r, req.Team)
+	if err != nil {
+		return Response{}, err
+	}
+	r.Audit.Vault = owner
+	r.Audit.SecretID = namespace.SecretID(req.SecretID)
+	if err := h.authorize(ctx, r, owner, actions.RoleWriter, "Forbidden: you don't have write access to this vault"); err != nil {
+		return Response{}, err
+	}
+
+	expected, err := h.prepareOverwrite(ctx, owner, r.Audit.SecretID, precondition)
+	if err != nil {
+		return Response{}, err
+	}
+	egg, err := h.sealEgg(ctx, r, owner, r.Audit.SecretID, req.Plaintext, expiresAt)
+	if err != nil {
+		return Response{}, err
+	}
+
+	// Store in DynamoDB as a new version, keeping the previous value in history
+	egg, err = h.Repo.PutEgg(ctx, egg, expected)
+	r.Audit.Version = egg.Version
+	if errors.Is(err, actions.ErrEggConflict) {
+		return Response{}, versionConflict(precondition)
+	}
+	if err != nil {
+		return Response{}, internalError("Failed to store egg", err)
+	}
+
+	response, err := created(PutEggResponse{
+		Message:   "Egg stored successfully",
+		Owner:     owner,
+		SecretID:  req.SecretID,
+		Project:   namespace.Project,
+		Env:       namespace.Env,
+		Version:   egg.Version,
+		CreatedAt: egg.CreatedAt,
+		ExpiresAt: egg.Expiry(),
+	})
+	response.Headers = etag(egg)
+	return response, err
+}
+
+// parseExpiry reads an RFC 3339 expiry, which has to be in the future. An
+// empty expiry is 0, meaning never.
+func parseExpiry(expiresAt string) (int64, error) {
+	if expiresAt == "" {
+		return 0, nil
+	}
+	expiry, err := time.Parse(time.RFC3339, expiresAt)
+	if err != nil || !expiry.After(time.Now()) {
+		return 0, invalidRequest("expires_at must be an RFC 3339 time in the future")
+	}
+	return expiry.Unix(), nil
+}
+
+// vaultOwner is the vault a write goes to: the caller's own, or team's if
+// one is named.
+func vaultOwner(r *Request, team string) (string, error) {
+	if team == "" {
+		return r.Caller.ID, nil
+	}
+	if err := actions.ValidateTeamName(team); err != nil {
+		return "", invalidRequest(err.Error())
+	}
+	return actions.TeamOwner(team), nil
+}
+
+// prepareOverwrite checks that a secret can be laid over and returns the
+// version to expect when writing it. The precondition is checked against the
+// current row first, so a stale write is reported as a conflict before
+// anything else. A trashed egg is refused rather than purged, so a write
+// can't take away the chance to restore it. Expired eggs count as absent for
+// If-None-Match. Nothing is changed here; the write itself checks the
+// precondition again.
+func (h *Handlers) prepareOverwrite(ctx context.Context, owner, secretID string, precondition int) (int, error) {
+	current, err := h.Repo.GetEgg(ctx, owner, secretID)
+	if errors.Is(err, actions.ErrEggNotFound) {
+		return precondition, nil
+	}
+	if err != nil {
+		return 0, internalError("Failed to read egg", err)
+	}
+	now := time.Now()
+	switch {
+	case precondition == actions.NoVersion && !current.Expired(now) && !current.Trashed():
+		return 0, versionConflict(precondition)
+	case precondition > 0 && max(current.Version, 1) != precondition:
+		return 0, versionConflict(precondition)
+	case current.Trashed() && !current.Expired(now):
+		return 0, trashed("Egg is in the trash: run egg restore or egg break --purge first")
+	case precondition == actions.NoVersion && current.Expired(now):
+		return max(current.Version, 1), nil
+	}
+	return precondition, nil
+}
+
+// sealEgg encrypts plaintext into a new egg laid by the caller. Bound eggs
+// tie both the data key and the ciphertext to this owner and secret ID, so
+// they can't be copied onto another row and still decrypt.
+func (h *Handlers) sealEgg(ctx context.Context, r *Request, owner, secretID, plaintext string, expiresAt int64) (actions.Egg, error) {
+	egg := actions.Egg{
+		Owner:     owner, // From JWT token, or the team
+		SecretID:  secretID,
+		CreatedAt: time.Now().Format(time.RFC3339),
+		CreatedBy: r.Caller.ID,
+		Bound:     true,
+		ExpiresAt: expiresAt,
+	}
+
+	// Generate a data key (KMS unless KEY_PROVIDER says otherwise)
+	dataKey, err := h.Keys.GenerateDataKey(ctx, egg.EncryptionContext())
+	if err != nil {
+		return egg, internalError("Failed to generate encryption key", err)
+	}
+
+	// Seal the plaintext in an AES-256-GCM envelope with the plaintext data key
+	ciphertext, err := crypto.Seal(crypto.AES256GCM, dataKey.Plaintext, dataKey.KeyRef, []byte(plaintext), egg.AdditionalData())
+	if err != nil {
+		return egg, internalError("Failed to encrypt data", err)
+	}
+	egg.Ciphertext = ciphertext
+	egg.EncryptedDataKey = dataKey.Encrypted
+	egg.KeyID = dataKey.KeyRef
+	return egg, nil
+}
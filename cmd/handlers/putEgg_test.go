@@ -0,0 +1,173 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/owenHochwald/egg-carton/cmd/actions"
+	"github.com/owenHochwald/egg-carton/pkg/crypto"
+)
+
+func newTestHandlers(t *testing.T) *Handlers {
+	keys, err := crypto.NewLocalKeyProvider(filepath.Join(t.TempDir(), "master.key"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &Handlers{
+		Repo:           actions.NewMemoryEggRepository(),
+		Keys:           keys,
+		AuditKey:       newAuditKey(t),
+		TrashRetention: time.Hour,
+		Logger:         slog.New(slog.NewJSONHandler(io.Discard, nil)),
+	}
+}
+
+func newAuditKey(t *testing.T) *crypto.LocalMACKey {
+	t.Helper()
+	key, err := crypto.NewLocalMACKey(filepath.Join(t.TempDir(), "audit.key"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func newPutRequest(caller, secretID, plaintext string) events.APIGatewayV2HTTPRequest {
+	request := newTestRequest(caller)
+	request.RouteKey = "POST /eggs"
+	body, _ := json.Marshal(PutEggRequest{SecretID: secretID, Plaintext: plaintext})
+	request.Body = string(body)
+	return request
+}
+
+func decodeError(t *testing.T, response events.APIGatewayV2HTTPResponse) ErrorResponse {
+	t.Helper()
+	var body ErrorResponse
+	if err := json.Unmarshal([]byte(response.Body), &body); err != nil {
+		t.Fatalf("decode error body %q: %v", response.Body, err)
+	}
+	return body
+}
+
+func TestPutEggOverTrash(t *testing.T) {
+	ctx := context.Background()
+	h := newTestHandlers(t)
+	if response, _ := h.PutEgg(ctx, newPutRequest("alice", "API_KEY", "old")); response.StatusCode != 201 {
+		t.Fatalf("PutEgg: %d %s", response.StatusCode, response.Body)
+	}
+	if _, err := actions.TrashEgg(ctx, h.Repo, "alice", "API_KEY", "alice", time.Hour, actions.AnyVersion); err != nil {
+		t.Fatalf("TrashEgg: %v", err)
+	}
+
+	// Laying over the trashed secret is refused and the trash is untouched
+	response, _ := h.PutEgg(ctx, newPutRequest("alice", "API_KEY", "new"))
+	if body := decodeError(t, response); response.StatusCode != 409 || body.Code != CodeTrashed || body.Retryable {
+		t.Errorf("PutEgg over trash: got %d %+v, want 409 %s", response.StatusCode, body, CodeTrashed)
+	}
+	if current, err := h.Repo.GetEgg(ctx, "alice", "API_KEY"); err != nil || !current.Trashed() || current.Version != 1 {
+		t.Errorf("trashed egg: got %+v, %v, want version 1 still in the trash", current, err)
+	}
+	if versions, _ := h.Repo.ListEggVersions(ctx, "alice", "API_KEY"); len(versions) != 1 {
+		t.Errorf("trashed history: got %d versions, want 1", len(versions))
+	}
+
+	// Once restored it can be laid over again
+	if _, err := actions.RestoreTrashedEgg(ctx, h.Repo, "alice", "API_KEY"); err != nil {
+		t.Fatalf("RestoreTrashedEgg: %v", err)
+	}
+	if response, _ := h.PutEgg(ctx, newPutRequest("alice", "API_KEY", "new")); response.StatusCode != 201 {
+		t.Errorf("PutEgg after restore: %d %s", response.StatusCode, response.Body)
+	}
+}
+
+func TestPutEggPreconditionFirst(t *testing.T) {
+	ctx := context.Background()
+	h := newTestHandlers(t)
+	for range 2 {
+		if response, _ := h.PutEgg(ctx, newPutRequest("alice", "API_KEY", "value")); response.StatusCode != 201 {
+			t.Fatalf("PutEgg: %d %s", response.StatusCode, response.Body)
+		}
+	}
+	if _, err := actions.TrashEgg(ctx, h.Repo, "alice", "API_KEY", "alice", time.Hour, actions.AnyVersion); err != nil {
+		t.Fatalf("TrashEgg: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		header   string
+		value    string
+		wantCode string
+	}{
+		{"stale If-Match", "if-match", `"1"`, CodeConflict},
+		{"current If-Match", "if-match", `"2"`, CodeTrashed},
+		{"If-None-Match", "if-none-match", "*", CodeTrashed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := newPutRequest("alice", "API_KEY", "new")
+			request.Headers[tt.header] = tt.value
+			response, _ := h.PutEgg(ctx, request)
+			if body := decodeError(t, response); response.StatusCode != 409 || body.Code != tt.wantCode {
+				t.Errorf("got %d %+v, want 409 %s", response.StatusCode, body, tt.wantCode)
+			}
+
+			// Whatever the outcome, the trashed secret is left as it was
+			if current, err := h.Repo.GetEgg(ctx, "alice", "API_KEY"); err != nil || !current.Trashed() || current.Version != 2 {
+				t.Errorf("trashed egg: got %+v, %v, want version 2 still in the trash", current, err)
+			}
+			if versions, _ := h.Repo.ListEggVersions(ctx, "alice", "API_KEY"); len(versions) != 2 {
+				t.Errorf("trashed history: got %d versions, want 2", len(versions))
+			}
+		})
+	}
+}
+
+// brokenReads fails every read of a current row, as a throttled or
+// unreachable table would
+type brokenReads struct {
+	actions.EggActions
+}
+
+func (brokenReads) GetEgg(ctx context.Context, owner, secretID string) (actions.Egg, error) {
+	return actions.Egg{}, errors.New("read failed")
+}
+
+func TestPutEggReadError(t *testing.T) {
+	ctx := context.Background()
+	h := newTestHandlers(t)
+	if response, _ := h.PutEgg(ctx, newPutRequest("alice", "API_KEY", "old")); response.StatusCode != 201 {
+		t.Fatalf("PutEgg: %d %s", response.StatusCode, response.Body)
+	}
+	if _, err := actions.TrashEgg(ctx, h.Repo, "alice", "API_KEY", "alice", time.Hour, actions.AnyVersion); err != nil {
+		t.Fatalf("TrashEgg: %v", err)
+	}
+	repo := h.Repo
+	h.Repo = brokenReads{repo}
+
+	// A failed read isn't taken for a missing egg, on either put path
+	response, _ := h.PutEgg(ctx, newPutRequest("alice", "API_KEY", "new"))
+	if body := decodeError(t, response); response.StatusCode != 500 || body.Code != CodeInternal {
+		t.Errorf("PutEgg: got %d %+v, want 500 %s", response.StatusCode, body, CodeInternal)
+	}
+	request := newTestRequest("alice")
+	request.RouteKey = putEggsRoute
+	request.Body = `{"eggs": [{"secret_id": "API_KEY", "plaintext": "new"}]}`
+	response, _ = h.PutEgg(ctx, request)
+	var batch BatchResponse
+	if err := json.Unmarshal([]byte(response.Body), &batch); err != nil {
+		t.Fatal(err)
+	}
+	if batch.Failed != 1 || batch.Results[0].Code != CodeInternal {
+		t.Errorf("batch PutEgg: got %+v, want one internal error", batch)
+	}
+
+	if current, err := repo.GetEgg(ctx, "alice", "API_KEY"); err != nil || !current.Trashed() || current.Version != 1 {
+		t.Errorf("trashed egg: got %+v, %v, want version 1 still in the trash", current, err)
+	}
+}
@@ -0,0 +1,175 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/owenHochwald/egg-carton/cmd/actions"
+)
+
+// Routes served by Teams
+const (
+	createTeamRoute   = "POST /teams"
+	listTeamsRoute    = "GET /teams"
+	listMembersRoute  = "GET /teams/{team}/members"
+	putMemberRoute    = "PUT /teams/{team}/members/{member}"
+	removeMemberRoute = "DELETE /teams/{team}/members/{member}"
+)
+
+type CreateTeamRequest struct {
+	Team string `json:"team"`
+}
+
+type PutMemberRequest struct {
+	Role actions.Role `json:"role"`
+}
+
+type TeamResponse struct {
+	Team  string       `json:"team"`
+	Owner string       `json:"owner"` // Vault owner to use in /eggs/{owner} paths
+	Role  actions.Role `json:"role"`  // The caller's role
+}
+
+type ListTeamsResponse struct {
+	Teams []TeamResponse `json:"teams"`
+}
+
+type ListMembersResponse struct {
+	Members []actions.TeamMember `json:"members"`
+}
+
+func (h *Handlers) Teams(ctx context.Context, request events.APIGatewayV2HTTPRequest) (events.APIGatewayV2HTTPResponse, error) {
+	return h.serve(h.teams)(ctx, request)
+}
+
+func (h *Handlers) teams(ctx context.Context, r *Request) (Response, error) {
+	switch r.RouteKey {
+	case createTeamRoute:
+		return h.createTeam(ctx, r)
+	case listTeamsRoute:
+		return h.listTeams(ctx, r.Caller.ID)
+	}
+
+	team := r.PathParameters["team"]
+	if err := actions.ValidateTeamName(team); err != nil {
+		return Response{}, invalidRequest(err.Error())
+	}
+
+	// Any member may see who else is in the team; only admins change it
+	required := actions.RoleAdmin
+	if r.RouteKey == listMembersRoute {
+		required = actions.RoleReader
+	}
+	if err := h.authorize(ctx, r, actions.TeamOwner(team), required, "Forbidden: you need the "+string(required)+" role in this team"); err != nil {
+		return Response{}, err
+	}
+
+	switch r.RouteKey {
+	case listMembersRoute:
+		return h.listMembers(ctx, team)
+	case putMemberRoute:
+		return h.putMember(ctx, r, team, r.PathParameters["member"])
+	case removeMemberRoute:
+		return h.removeMember(ctx, team, r.PathParameters["member"])
+	default:
+		return Response{}, notFound("Route not found")
+	}
+}
+
+// createTeam registers a new team with the caller as its first admin.
+func (h *Handlers) createTeam(ctx context.Context, r *Request) (Response, error) {
+	var req CreateTeamRequest
+	if err := decodeBody(r, &req); err != nil {
+		return Response{}, err
+	}
+	if err := actions.ValidateTeamName(req.Team); err != nil {
+		return Response{}, invalidRequest(err.Error())
+	}
+
+	err := h.Repo.CreateTeam(ctx, actions.TeamMember{
+		Team:    req.Team,
+		Member:  r.Caller.ID,
+		Role:    actions.RoleAdmin,
+		AddedBy: r.Caller.ID,
+		AddedAt: time.Now().Format(time.RFC3339),
+	})
+	if errors.Is(err, actions.ErrTeamExists) {
+		return Response{}, conflict("Team already exists")
+	}
+	if err != nil {
+		return Response{}, internalError("Failed to create team", err)
+	}
+
+	return created(TeamResponse{Team: req.Team, Owner: actions.TeamOwner(req.Team), Role: actions.RoleAdmin})
+}
+
+// listTeams returns the teams the caller is a member of.
+func (h *Handlers) listTeams(ctx context.Context, caller string) (Response, error) {
+	memberships, err := h.Repo.ListMemberTeams(ctx, caller)
+	if err != nil {
+		return Response{}, internalError("Failed to list teams", err)
+	}
+
+	response := ListTeamsResponse{Teams: []TeamResponse{}}
+	for _, m := range memberships {
+		response.Teams = append(response.Teams, TeamResponse{Team: m.Team, Owner: actions.TeamOwner(m.Team), Role: m.Role})
+	}
+	return ok(response)
+}
+
+func (h *Handlers) listMembers(ctx context.Context, team string) (Response, error) {
+	members, err := h.Repo.ListTeamMembers(ctx, team)
+	if err != nil {
+		return Response{}, internalError("Failed to list members", err)
+	}
+	if members == nil {
+		members = []actions.TeamMember{}
+	}
+	return ok(ListMembersResponse{Members: members})
+}
+
+// putMember adds a member or changes their role.
+func (h *Handlers) putMember(ctx context.Context, r *Request, team, member string) (Response, error) {
+	var req PutMemberRequest
+	if err := decodeBody(r, &req); err != nil {
+		return Response{}, err
+	}
+	if member == "" || !req.Role.Valid() {
+		return Response{}, invalidRequest("member and a role of reader, writer or admin are required")
+	}
+
+	m := actions.TeamMember{
+		Team:    team,
+		Member:  member,
+		Role:    req.Role,
+		AddedBy: r.Caller.ID,
+		AddedAt: time.Now().Format(time.RFC3339),
+	}
+	err := h.Repo.PutTeamMember(ctx, m)
+	if errors.Is(err, actions.ErrLastAdmin) {
+		return Response{}, conflict(err.Error())
+	}
+	if errors.Is(err, actions.ErrTeamConflict) {
+		return Response{}, errTeamConcurrentUpdate
+	}
+	if err != nil {
+		return Response{}, internalError("Failed to update member", err)
+	}
+	return ok(m)
+}
+
+func (h *Handlers) removeMember(ctx context.Context, team, member string) (Response, error) {
+	err := h.Repo.RemoveTeamMember(ctx, team, member)
+	if errors.Is(err, actions.ErrLastAdmin) {
+		return Response{}, conflict(err.Error())
+	}
+	if errors.Is(err, actions.ErrTeamConflict) {
+		return Response{}, errTeamConcurrentUpdate
+	}
+	if err != nil {
+		return Response{}, internalError("Failed to remove member", err)
+	}
+	return ok(map[string]string{"message": "Member removed", "team": team, "member": member})
+}
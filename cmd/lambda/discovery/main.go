@@ -0,0 +1,18 @@
+package main
+
+import (
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/owenHochwald/egg-carton/cmd/handlers"
+)
+
+var h handlers.Handlers
+
+func init() {
+	// What the discovery document advertises (COGNITO_* from Terraform; the
+	// API endpoint comes from the request unless API_ENDPOINT is set)
+	h.Deployment = handlers.DeploymentFromEnv()
+}
+
+func main() {
+	lambda.Start(h.Discovery)
+}
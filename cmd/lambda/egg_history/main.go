@@ -0,0 +1,36 @@
+package main
+
+import (
+	"context"
+
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/owenHochwald/egg-carton/cmd/actions"
+	"github.com/owenHochwald/egg-carton/cmd/handlers"
+)
+
+var h handlers.Handlers
+
+func init() {
+	// Initialize the storage backend (DynamoDB unless STORAGE_BACKEND says otherwise)
+	var err error
+	h.Repo, err = actions.NewEggActionsFromEnv(context.TODO())
+	if err != nil {
+		panic("unable to initialize storage: " + err.Error())
+	}
+
+	// Initialize the data key provider (KMS unless KEY_PROVIDER says otherwise)
+	h.Keys, err = actions.NewKeyProviderFromEnv(context.TODO())
+	if err != nil {
+		panic("unable to initialize key provider: " + err.Error())
+	}
+
+	// Initialize the audit signing key (KMS unless KEY_PROVIDER says otherwise)
+	h.AuditKey, err = actions.NewAuditKeyFromEnv(context.TODO())
+	if err != nil {
+		panic("unable to initialize audit key: " + err.Error())
+	}
+}
+
+func main() {
+	lambda.Start(h.EggHistory)
+}
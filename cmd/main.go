@@ -32,15 +32,15 @@ func main() {
 		CreatedAt:        time.Now().Format(time.RFC3339),
 	}
 
-	err = eggRepo.PutEgg(context.TODO(), newEgg)
+	storedEgg, err := eggRepo.PutEgg(context.TODO(), newEgg, actions.AnyVersion)
 	if err != nil {
 		log.Printf("Failed to put egg: %v\n", err)
 	} else {
-		log.Println("Successfully stored egg")
+		log.Printf("Successfully stored egg version %d\n", storedEgg.Version)
 	}
 
 	// Example: Retrieve an egg
-	egg, err := eggRepo.GetEgg(context.TODO(), "USER#example")
+	egg, err := eggRepo.GetEgg(context.TODO(), "USER#example", "SECRET#DEMO_KEY")
 	if err != nil {
 		log.Printf("Failed to get egg: %v\n", err)
 	} else {
@@ -0,0 +1,248 @@
+// Command rewrap_keys rotates eggs onto a new KMS master key. Every egg
+// (current value and every history version) whose data key is not already
+// wrapped under the target key is re-wrapped with KMS ReEncrypt, so neither
+// data keys nor secrets are ever decrypted outside KMS. The ciphertext itself
+// is untouched; only EncryptedDataKey and KeyID change.
+//
+// Progress is saved to a state file after every page. If the job is
+// interrupted, running it again resumes from the last completed page. Eggs
+// already on the target key are skipped, so re-running after completion only
+// picks up stragglers.
+//
+// Point the Lambdas' KMS_KEY_ID at the new key first, keeping the old one in
+// their policy (kms_key_arn and retiring_kms_key_arns in Terraform), so that
+// eggs written during the job are already on it and the rest still open. The job uses the same environment as the
+// Lambdas (STORAGE_BACKEND, TABLE_NAME, ...).
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+	"github.com/owenHochwald/egg-carton/cmd/actions"
+	"github.com/owenHochwald/egg-carton/pkg/crypto"
+)
+
+// progress is persisted between runs so an interrupted job can resume.
+type progress struct {
+	KeyARN    string `json:"key_arn"`
+	Cursor    string `json:"cursor"`
+	Pages     int    `json:"pages"`
+	Scanned   int    `json:"scanned"`
+	Rewrapped int    `json:"rewrapped"`
+	Current   int    `json:"current"` // Already on the target key
+	Skipped   int    `json:"skipped"` // Changed concurrently
+	Failed    int    `json:"failed"`
+}
+
+// rewrapper re-wraps a data key under the job's target key, returning the
+// new wrapped key and the ID of the key wrapping it. *crypto.KMSKeyProvider
+// is one.
+type rewrapper interface {
+	ReEncryptDataKey(ctx context.Context, encryptedKey []byte, encryptionContext map[string]string) ([]byte, string, error)
+}
+
+// errInterrupted stops a job between pages; its progress is saved.
+var errInterrupted = errors.New("interrupted")
+
+// job re-wraps every egg of repo onto keyARN.
+type job struct {
+	repo      actions.EggActions
+	keys      rewrapper
+	keyARN    string
+	pageSize  int
+	statePath string
+	dryRun    bool
+	restart   bool
+}
+
+func main() {
+	keyID := flag.String("key-id", "", "KMS key ID, ARN or alias to re-wrap data keys under (required)")
+	dryRun := flag.Bool("dry-run", false, "report eggs that need re-wrapping without changing them")
+	pageSize := flag.Int("page-size", 100, "eggs to read per page")
+	statePath := flag.String("state", "rewrap_keys.state", "file recording progress so the job can resume")
+	restart := flag.Bool("restart", false, "ignore saved progress and start from the beginning")
+	flag.Parse()
+	if *keyID == "" {
+		log.Fatal("-key-id is required")
+	}
+
+	ctx := context.Background()
+	cfg, err := config.LoadDefaultConfig(ctx)
+	if err != nil {
+		log.Fatal(err)
+	}
+	keyProvider := crypto.NewKMSKeyProvider(kms.NewFromConfig(cfg), *keyID)
+	keyARN, err := keyProvider.KeyARN(ctx)
+	if err != nil {
+		log.Fatalf("Failed to resolve %s: %v", *keyID, err)
+	}
+
+	eggRepo, err := actions.NewEggActionsFromEnv(ctx)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Stop cleanly between pages on Ctrl-C so the state file stays accurate
+	interrupted, stop := signal.NotifyContext(ctx, os.Interrupt)
+	defer stop()
+
+	j := job{
+		repo:      eggRepo,
+		keys:      keyProvider,
+		keyARN:    keyARN,
+		pageSize:  *pageSize,
+		statePath: *statePath,
+		dryRun:    *dryRun,
+		restart:   *restart,
+	}
+	state, err := j.run(interrupted)
+	if errors.Is(err, errInterrupted) {
+		log.Printf("Interrupted; run again to resume from page %d", state.Pages+1)
+		os.Exit(1)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("Done: scanned %d eggs, re-wrapped %d, already current %d, skipped %d, failed %d",
+		state.Scanned, state.Rewrapped, state.Current, state.Skipped, state.Failed)
+	if state.Failed > 0 {
+		os.Exit(1)
+	}
+}
+
+// run re-wraps page after page, resuming from the state file unless told to
+// restart, until the scan is done or ctx is cancelled. Work on the page in
+// hand runs on without ctx, so a cancelled job stops between pages with
+// errInterrupted and its progress saved.
+func (j *job) run(ctx context.Context) (progress, error) {
+	state := progress{KeyARN: j.keyARN}
+	if !j.restart && !j.dryRun {
+		saved, err := loadProgress(j.statePath)
+		if err != nil {
+			return state, fmt.Errorf("failed to read %s: %w", j.statePath, err)
+		}
+		if saved != nil && saved.KeyARN == j.keyARN {
+			state = *saved
+			log.Printf("Resuming after %d pages (%d eggs scanned)", state.Pages, state.Scanned)
+		} else if saved != nil {
+			log.Printf("Ignoring saved progress for %s", saved.KeyARN)
+		}
+	}
+
+	work := context.WithoutCancel(ctx)
+	log.Printf("Re-wrapping data keys under %s", j.keyARN)
+	for {
+		eggs, next, err := j.repo.ScanEggs(work, state.Cursor, j.pageSize)
+		if err != nil {
+			return state, fmt.Errorf("failed to scan eggs: %w", err)
+		}
+
+		for _, egg := range eggs {
+			state.Scanned++
+			if egg.KeyID == j.keyARN {
+				state.Current++
+				continue
+			}
+			if j.dryRun {
+				log.Printf("Would re-wrap %s/%s version %d (key %q)", egg.Owner, egg.SecretID, egg.Version, egg.KeyID)
+				state.Rewrapped++
+				continue
+			}
+
+			err := j.rewrapEgg(work, egg)
+			if errors.Is(err, actions.ErrEggConflict) {
+				// Rewritten concurrently; a re-run picks it up if needed
+				log.Printf("Skipping %s/%s version %d: changed during re-wrap", egg.Owner, egg.SecretID, egg.Version)
+				state.Skipped++
+				continue
+			}
+			if err != nil {
+				log.Printf("Failed to re-wrap %s/%s version %d: %v", egg.Owner, egg.SecretID, egg.Version, err)
+				state.Failed++
+				continue
+			}
+			state.Rewrapped++
+		}
+
+		state.Pages++
+		state.Cursor = next
+		log.Printf("Page %d: scanned %d, re-wrapped %d, already current %d, skipped %d, failed %d",
+			state.Pages, state.Scanned, state.Rewrapped, state.Current, state.Skipped, state.Failed)
+
+		if next == "" {
+			break
+		}
+		if !j.dryRun {
+			if err := saveProgress(j.statePath, state); err != nil {
+				return state, fmt.Errorf("failed to save progress: %w", err)
+			}
+		}
+		if ctx.Err() != nil {
+			return state, errInterrupted
+		}
+	}
+
+	if !j.dryRun {
+		if err := os.Remove(j.statePath); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			log.Printf("Failed to remove %s: %v", j.statePath, err)
+		}
+	}
+	return state, nil
+}
+
+// rewrapEgg moves one egg's data key onto the target key and records the key
+// that now wraps it. UpdateEgg only writes if the egg is still at the version
+// read, so a value laid meanwhile is never overwritten with the old one.
+func (j *job) rewrapEgg(ctx context.Context, egg actions.Egg) error {
+	if strings.HasPrefix(egg.KeyID, "local:") && !strings.HasPrefix(j.keyARN, "local:") {
+		return errors.New("data key is wrapped by a local master key, not KMS")
+	}
+
+	encryptedKey, keyARN, err := j.keys.ReEncryptDataKey(ctx, egg.EncryptedDataKey, egg.EncryptionContext())
+	if err != nil {
+		return err
+	}
+	egg.EncryptedDataKey = encryptedKey
+	egg.KeyID = keyARN
+	return j.repo.UpdateEgg(ctx, egg)
+}
+
+// loadProgress returns the saved progress, or nil if there is none.
+func loadProgress(path string) (*progress, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var state progress
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, err
+	}
+	return &state, nil
+}
+
+func saveProgress(path string, state progress) error {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	// Write then rename so an interruption never leaves a torn file
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
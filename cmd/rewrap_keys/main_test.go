@@ -0,0 +1,216 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/owenHochwald/egg-carton/cmd/actions"
+	"github.com/owenHochwald/egg-carton/pkg/crypto"
+)
+
+func TestMain(m *testing.M) {
+	log.SetOutput(io.Discard)
+	os.Exit(m.Run())
+}
+
+// localRotation stands in for KMS ReEncrypt, moving data keys from one local
+// master key to another. before, if set, runs ahead of every re-wrap.
+type localRotation struct {
+	from   crypto.KeyProvider
+	to     *crypto.LocalKeyProvider
+	before func(owner, secretID string)
+}
+
+func (r *localRotation) ReEncryptDataKey(ctx context.Context, encryptedKey []byte, encryptionContext map[string]string) ([]byte, string, error) {
+	if r.before != nil {
+		r.before(encryptionContext["Owner"], encryptionContext["SecretID"])
+	}
+	return r.to.ReEncryptDataKey(ctx, r.from, encryptedKey, encryptionContext)
+}
+
+func localKey(t *testing.T, name string) *crypto.LocalKeyProvider {
+	t.Helper()
+	keys, err := crypto.NewLocalKeyProvider(filepath.Join(t.TempDir(), name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return keys
+}
+
+// layEgg seals plaintext under keys and stores it as the newest version.
+func layEgg(t *testing.T, repo actions.EggActions, keys crypto.KeyProvider, owner, secretID, plaintext string) actions.Egg {
+	t.Helper()
+	ctx := context.Background()
+	egg := actions.Egg{Owner: owner, SecretID: secretID, Bound: true}
+	dataKey, err := keys.GenerateDataKey(ctx, egg.EncryptionContext())
+	if err != nil {
+		t.Fatal(err)
+	}
+	egg.Ciphertext, err = crypto.Seal(crypto.AES256GCM, dataKey.Plaintext, dataKey.KeyRef, []byte(plaintext), egg.AdditionalData())
+	if err != nil {
+		t.Fatal(err)
+	}
+	egg.EncryptedDataKey = dataKey.Encrypted
+	egg.KeyID = dataKey.KeyRef
+	egg, err = repo.PutEgg(ctx, egg, actions.AnyVersion)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return egg
+}
+
+// open decrypts egg with keys, failing the test if it can't.
+func open(t *testing.T, keys crypto.KeyProvider, egg actions.Egg) string {
+	t.Helper()
+	dataKey, err := keys.DecryptDataKey(context.Background(), egg.EncryptedDataKey, egg.EncryptionContext())
+	if err != nil {
+		t.Fatalf("%s version %d: %v", egg.SecretID, egg.Version, err)
+	}
+	plaintext, err := crypto.Open(egg.Ciphertext, dataKey, egg.AdditionalData())
+	if err != nil {
+		t.Fatalf("%s version %d: %v", egg.SecretID, egg.Version, err)
+	}
+	return string(plaintext)
+}
+
+func TestRunResumes(t *testing.T) {
+	ctx := context.Background()
+	repo := actions.NewMemoryEggRepository()
+	oldKey, newKey := localKey(t, "old.key"), localKey(t, "new.key")
+	for i := range 5 {
+		layEgg(t, repo, oldKey, "alice", fmt.Sprintf("KEY_%d", i), fmt.Sprintf("secret-%d", i))
+	}
+	layEgg(t, repo, oldKey, "alice", "KEY_0", "secret-0b") // History too
+	all, _, err := repo.ScanEggs(ctx, "", 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows := len(all)
+
+	// Interrupt after the first page: it is saved, and the job stops
+	interrupted, cancel := context.WithCancel(ctx)
+	rotation := &localRotation{from: oldKey, to: newKey, before: func(string, string) { cancel() }}
+	j := job{repo: repo, keys: rotation, keyARN: newKey.KeyRef(), pageSize: 2, statePath: filepath.Join(t.TempDir(), "state")}
+	state, err := j.run(interrupted)
+	if !errors.Is(err, errInterrupted) {
+		t.Fatalf("run = %v, want errInterrupted", err)
+	}
+	saved, err := loadProgress(j.statePath)
+	if err != nil || saved == nil || saved.Pages != 1 || saved.Cursor == "" || *saved != state {
+		t.Fatalf("saved progress %+v (%v), want page 1 of %+v", saved, err, state)
+	}
+
+	// Resuming picks up where the first run stopped, scanning nothing twice
+	rotation.before = nil
+	state, err = j.run(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state.Scanned != rows || state.Rewrapped != rows || state.Pages != (rows+1)/2 || state.Failed != 0 {
+		t.Errorf("unexpected totals %+v", state)
+	}
+	if _, err := os.Stat(j.statePath); !os.IsNotExist(err) {
+		t.Errorf("state file left behind after the job finished: %v", err)
+	}
+
+	// Every row, history included, is now on the new key alone
+	eggs, _, err := repo.ScanEggs(ctx, "", 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, egg := range eggs {
+		if egg.KeyID != newKey.KeyRef() {
+			t.Errorf("%s version %d still on %s", egg.SecretID, egg.Version, egg.KeyID)
+		}
+		open(t, newKey, egg)
+		if _, err := oldKey.DecryptDataKey(ctx, egg.EncryptedDataKey, egg.EncryptionContext()); err == nil {
+			t.Errorf("%s version %d still opens with the old key", egg.SecretID, egg.Version)
+		}
+	}
+
+	// Running again finds nothing left to do
+	state, err = j.run(ctx)
+	if err != nil || state.Current != rows || state.Rewrapped != 0 {
+		t.Errorf("re-run: %+v, %v", state, err)
+	}
+}
+
+func TestRunIgnoresOtherKeysProgress(t *testing.T) {
+	repo := actions.NewMemoryEggRepository()
+	oldKey, newKey := localKey(t, "old.key"), localKey(t, "new.key")
+	layEgg(t, repo, oldKey, "alice", "API_KEY", "sk-1")
+
+	// Progress of a rotation onto some other key would skip every egg
+	j := job{repo: repo, keys: &localRotation{from: oldKey, to: newKey}, keyARN: newKey.KeyRef(), pageSize: 10, statePath: filepath.Join(t.TempDir(), "state")}
+	if err := saveProgress(j.statePath, progress{KeyARN: "local:other", Cursor: "zzz", Pages: 7}); err != nil {
+		t.Fatal(err)
+	}
+	state, err := j.run(context.Background())
+	if err != nil || state.Current != 0 || state.Rewrapped != state.Scanned {
+		t.Errorf("run: %+v, %v", state, err)
+	}
+
+	// As does -restart with the same key
+	if err := saveProgress(j.statePath, progress{KeyARN: newKey.KeyRef(), Cursor: "zzz", Pages: 7}); err != nil {
+		t.Fatal(err)
+	}
+	j.restart = true
+	state, err = j.run(context.Background())
+	if err != nil || state.Scanned == 0 || state.Current != state.Scanned {
+		t.Errorf("run -restart: %+v, %v", state, err)
+	}
+}
+
+func TestRunConcurrentWrite(t *testing.T) {
+	ctx := context.Background()
+	repo := actions.NewMemoryEggRepository()
+	oldKey, newKey := localKey(t, "old.key"), localKey(t, "new.key")
+	layEgg(t, repo, oldKey, "alice", "API_KEY", "sk-1")
+	layEgg(t, repo, oldKey, "alice", "DB_URL", "postgres://")
+
+	// Someone lays a new API_KEY, still under the old key as a Lambda not
+	// yet pointed at the new one would, while the job re-wraps it
+	laid := false
+	rotation := &localRotation{from: oldKey, to: newKey, before: func(owner, secretID string) {
+		if secretID == "API_KEY" && !laid {
+			laid = true
+			layEgg(t, repo, oldKey, owner, secretID, "sk-2")
+		}
+	}}
+	j := job{repo: repo, keys: rotation, keyARN: newKey.KeyRef(), pageSize: 10, statePath: filepath.Join(t.TempDir(), "state")}
+	state, err := j.run(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state.Skipped != 1 || state.Failed != 0 {
+		t.Errorf("unexpected totals %+v", state)
+	}
+
+	// The new value was kept, not overwritten with the old one re-wrapped
+	current, err := repo.GetEgg(ctx, "alice", "API_KEY")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if current.Version != 2 || open(t, oldKey, current) != "sk-2" {
+		t.Errorf("concurrent write lost: version %d", current.Version)
+	}
+
+	// A re-run picks up the straggler, current row and history alike
+	state, err = j.run(ctx)
+	if err != nil || state.Skipped != 0 || state.Rewrapped == 0 || state.Current+state.Rewrapped != state.Scanned {
+		t.Errorf("re-run: %+v, %v", state, err)
+	}
+	current, err = repo.GetEgg(ctx, "alice", "API_KEY")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if current.KeyID != newKey.KeyRef() || open(t, newKey, current) != "sk-2" {
+		t.Errorf("straggler not re-wrapped: %s", current.KeyID)
+	}
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// Dev mode headers naming the caller instead of a token
+const (
+	devUserHeader   = "X-Egg-User"
+	devGroupsHeader = "X-Egg-Groups" // Comma separated, e.g. eggcarton-backend-writer
+)
+
+var errUnauthorized = errors.New("missing or invalid bearer token")
+
+// authenticator turns a request's credentials into the JWT claims API Gateway
+// would have passed to the handlers. Tokens are HS256 JWTs signed with a
+// local secret, standing in for Cognito's access tokens.
+type authenticator struct {
+	secret []byte
+	dev    bool // Trust devUserHeader when there is no token
+}
+
+// loadSecret reads the signing secret at path, creating a random one with
+// 0600 permissions if the file doesn't exist yet.
+func loadSecret(path string) ([]byte, error) {
+	secret, err := os.ReadFile(path)
+	if err == nil {
+		return secret, nil
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	secret = make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(path, secret, 0600); err != nil {
+		return nil, err
+	}
+	return secret, nil
+}
+
+// mint signs a token for user, valid for ttl, granting groups.
+func (a authenticator) mint(user string, groups []string, ttl time.Duration) (string, error) {
+	claims := map[string]any{
+		"sub":       user,
+		"token_use": "access",
+		"iat":       time.Now().Unix(),
+		"exp":       time.Now().Add(ttl).Unix(),
+	}
+	if len(groups) > 0 {
+		claims["cognito:groups"] = groups
+	}
+	header, _ := json.Marshal(map[string]string{"alg": "HS256", "typ": "JWT"})
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	return signingInput + "." + a.sign(signingInput), nil
+}
+
+func (a authenticator) sign(signingInput string) string {
+	mac := hmac.New(sha256.New, a.secret)
+	mac.Write([]byte(signingInput))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// claims authenticates r. A bearer token must be signed with the local secret
+// and unexpired; without one, dev mode takes the caller from the dev headers.
+func (a authenticator) claims(r *http.Request) (map[string]string, error) {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		if user := r.Header.Get(devUserHeader); a.dev && user != "" {
+			claims := map[string]string{"sub": user}
+			if groups := r.Header.Get(devGroupsHeader); groups != "" {
+				claims["cognito:groups"] = "[" + strings.Join(strings.Split(groups, ","), " ") + "]"
+			}
+			return claims, nil
+		}
+		return nil, errUnauthorized
+	}
+	return a.verify(token, time.Now())
+}
+
+// verify checks token's signature and expiry and returns its claims flattened
+// the way API Gateway passes them: arrays become "[a b]".
+func (a authenticator) verify(token string, now time.Time) (map[string]string, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errUnauthorized
+	}
+	if !hmac.Equal([]byte(a.sign(parts[0]+"."+parts[1])), []byte(parts[2])) {
+		return nil, errUnauthorized
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, errUnauthorized
+	}
+	var raw map[string]any
+	if err := json.Unmarshal(payload, &raw); err != nil {
+		return nil, errUnauthorized
+	}
+	if exp, ok := raw["exp"].(float64); !ok || now.Unix() >= int64(exp) {
+		return nil, errUnauthorized
+	}
+
+	claims := make(map[string]string, len(raw))
+	for name, value := range raw {
+		switch value := value.(type) {
+		case string:
+			claims[name] = value
+		case float64:
+			claims[name] = fmt.Sprintf("%d", int64(value))
+		case []any:
+			items := make([]string, len(value))
+			for i, item := range value {
+				items[i] = fmt.Sprint(item)
+			}
+			claims[name] = "[" + strings.Join(items, " ") + "]"
+		default:
+			claims[name] = fmt.Sprint(value)
+		}
+	}
+	if claims["sub"] == "" {
+		return nil, errUnauthorized
+	}
+	return claims, nil
+}
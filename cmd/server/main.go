@@ -0,0 +1,237 @@
+// Command server runs the egg-carton API locally. It mounts the same handlers
+// as the Lambdas on a net/http router, converting each request into the API
+// Gateway event the handlers expect, so the CLI can be pointed at
+// http://localhost for development and integration tests:
+//
+//	go run ./cmd/server -dev
+//	EGG_API_ENDPOINT=http://localhost:8787 egg list
+//
+// Storage and data keys come from the same environment as the Lambdas
+// (STORAGE_BACKEND, KEY_PROVIDER, ...), except that they default to the bolt
+// backend and the local key provider so that no AWS account is needed.
+//
+// Requests are authenticated with HS256 bearer tokens signed by a local
+// secret; -mint-token USER prints one (store it with egg login --token). With
+// -dev, requests without a token may name their caller in the X-Egg-User
+// header instead (and their Cognito groups in X-Egg-Groups), so -dev only
+// listens on loopback addresses.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/owenHochwald/egg-carton/cmd/actions"
+	"github.com/owenHochwald/egg-carton/cmd/handlers"
+)
+
+func main() {
+	addr := flag.String("addr", "127.0.0.1:8787", "address to listen on")
+	secretPath := flag.String("secret", "eggcarton.secret", "file holding the token signing secret, created on first use")
+	dev := flag.Bool("dev", false, "accept the "+devUserHeader+" header in place of a bearer token")
+	mintUser := flag.String("mint-token", "", "print a bearer token for this user and exit")
+	mintGroups := flag.String("groups", "", "comma separated Cognito groups to put in a minted token")
+	mintTTL := flag.Duration("ttl", 24*time.Hour, "how long a minted token is valid")
+	flag.Parse()
+
+	// Anyone who can reach a -dev server can claim to be any user
+	if *dev && !isLoopback(*addr) {
+		log.Fatalf("-dev trusts the %s header, so it only listens on loopback addresses, not %s", devUserHeader, *addr)
+	}
+
+	secret, err := loadSecret(*secretPath)
+	if err != nil {
+		log.Fatalf("Failed to load %s: %v", *secretPath, err)
+	}
+	auth := authenticator{secret: secret, dev: *dev}
+
+	if *mintUser != "" {
+		var groups []string
+		if *mintGroups != "" {
+			groups = strings.Split(*mintGroups, ",")
+		}
+		token, err := auth.mint(*mintUser, groups, *mintTTL)
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Println(token)
+		return
+	}
+
+	// Default to storage and keys that live on this machine
+	if os.Getenv("STORAGE_BACKEND") == "" {
+		os.Setenv("STORAGE_BACKEND", "bolt")
+	}
+	if os.Getenv("KEY_PROVIDER") == "" {
+		os.Setenv("KEY_PROVIDER", "local")
+	}
+
+	ctx := context.Background()
+	h := handlers.Handlers{}
+	h.Repo, err = actions.NewEggActionsFromEnv(ctx)
+	if err != nil {
+		log.Fatalf("Failed to initialize storage: %v", err)
+	}
+	h.Keys, err = actions.NewKeyProviderFromEnv(ctx)
+	if err != nil {
+		log.Fatalf("Failed to initialize key provider: %v", err)
+	}
+	h.AuditKey, err = actions.NewAuditKeyFromEnv(ctx)
+	if err != nil {
+		log.Fatalf("Failed to initialize audit key: %v", err)
+	}
+	h.TrashRetention, err = actions.TrashRetentionFromEnv()
+	if err != nil {
+		log.Fatal(err)
+	}
+	// Tokens are minted locally, so there is no Cognito to advertise unless
+	// COGNITO_CLIENT_ID and friends are set
+	h.Deployment = handlers.DeploymentFromEnv()
+
+	mux := http.NewServeMux()
+	for routeKey, handler := range h.Routes() {
+		mux.Handle(routeKey, serve(routeKey, handler, auth))
+	}
+
+	log.Printf("Serving the egg-carton API on %s (storage %s, keys %s)", *addr, os.Getenv("STORAGE_BACKEND"), os.Getenv("KEY_PROVIDER"))
+	log.Fatal(http.ListenAndServe(*addr, mux))
+}
+
+// isLoopback reports whether addr only accepts connections from this machine.
+// An empty host listens on every interface.
+func isLoopback(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// serve adapts handler to net/http. Like the API Gateway JWT authorizer, it
+// rejects unauthenticated requests before they reach the handler, except on
+// public routes.
+func serve(routeKey string, handler handlers.Handler, auth authenticator) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var claims map[string]string
+		if !handlers.IsPublic(routeKey) {
+			var err error
+			if claims, err = auth.claims(r); err != nil {
+				writeJSONError(w, http.StatusUnauthorized, handlers.CodeUnauthorized, "Unauthorized")
+				return
+			}
+		}
+
+		request, err := newRequest(r, routeKey, claims)
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, handlers.CodeInvalidRequest, "Failed to read request body")
+			return
+		}
+
+		response, err := handler(r.Context(), request)
+		if err != nil {
+			log.Printf("Handler for %s failed: %v", routeKey, err)
+			writeJSONError(w, http.StatusInternalServerError, handlers.CodeInternal, "Internal Server Error")
+			return
+		}
+		for name, value := range response.Headers {
+			w.Header().Set(name, value)
+		}
+		statusCode := response.StatusCode
+		if statusCode == 0 {
+			statusCode = http.StatusOK
+		}
+		w.WriteHeader(statusCode)
+		io.WriteString(w, response.Body)
+	})
+}
+
+// newRequest converts r into the event API Gateway would send for routeKey.
+// The request ID is left to the handlers, which take the caller's
+// X-Request-Id if there is one.
+func newRequest(r *http.Request, routeKey string, claims map[string]string) (events.APIGatewayV2HTTPRequest, error) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return events.APIGatewayV2HTTPRequest{}, err
+	}
+
+	// API Gateway lowercases header names and joins repeated values with commas
+	headers := make(map[string]string, len(r.Header))
+	for name, values := range r.Header {
+		headers[strings.ToLower(name)] = strings.Join(values, ",")
+	}
+	if headers["x-forwarded-proto"] == "" {
+		headers["x-forwarded-proto"] = "http"
+		if r.TLS != nil {
+			headers["x-forwarded-proto"] = "https"
+		}
+	}
+	query := make(map[string]string)
+	for name, values := range r.URL.Query() {
+		query[name] = strings.Join(values, ",")
+	}
+	pathParameters := make(map[string]string)
+	for _, segment := range strings.Split(routeKey, "/") {
+		if name, ok := strings.CutPrefix(segment, "{"); ok {
+			name = strings.TrimSuffix(name, "}")
+			pathParameters[name] = r.PathValue(name)
+		}
+	}
+
+	request := events.APIGatewayV2HTTPRequest{
+		Version:               "2.0",
+		RouteKey:              routeKey,
+		RawPath:               r.URL.Path,
+		RawQueryString:        r.URL.RawQuery,
+		Headers:               headers,
+		QueryStringParameters: query,
+		PathParameters:        pathParameters,
+		Body:                  string(body),
+	}
+	request.RequestContext.RouteKey = routeKey
+	request.RequestContext.DomainName = r.Host
+	request.RequestContext.Stage = "$default"
+	request.RequestContext.Time = time.Now().UTC().Format(time.RFC3339)
+	request.RequestContext.TimeEpoch = time.Now().UnixMilli()
+	request.RequestContext.HTTP = events.APIGatewayV2HTTPRequestContextHTTPDescription{
+		Method:    r.Method,
+		Path:      r.URL.Path,
+		Protocol:  r.Proto,
+		SourceIP:  sourceIP(r),
+		UserAgent: r.UserAgent(),
+	}
+	request.RequestContext.Authorizer = &events.APIGatewayV2HTTPRequestContextAuthorizerDescription{
+		JWT: &events.APIGatewayV2HTTPRequestContextAuthorizerJWTDescription{Claims: claims},
+	}
+	return request, nil
+}
+
+// sourceIP strips the port from the client address.
+func sourceIP(r *http.Request) string {
+	i := strings.LastIndex(r.RemoteAddr, ":")
+	if i < 0 {
+		return r.RemoteAddr
+	}
+	return strings.Trim(r.RemoteAddr[:i], "[]")
+}
+
+// writeJSONError reports errors from before the handler runs in the
+// handlers' error envelope.
+func writeJSONError(w http.ResponseWriter, statusCode int, code, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(handlers.ErrorResponse{Code: code, Message: message, Error: message})
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/owenHochwald/egg-carton/cmd/handlers"
+)
+
+func TestTokens(t *testing.T) {
+	auth := authenticator{secret: []byte("test-secret")}
+	token, err := auth.mint("alice", []string{"eggcarton-backend-writer", "admins"}, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	claims, err := auth.verify(token, time.Now())
+	if err != nil {
+		t.Fatalf("verify minted token: %v", err)
+	}
+	if claims["sub"] != "alice" || claims["cognito:groups"] != "[eggcarton-backend-writer admins]" {
+		t.Errorf("unexpected claims %v", claims)
+	}
+
+	if _, err := auth.verify(token, time.Now().Add(2*time.Hour)); err == nil {
+		t.Error("expired token was accepted")
+	}
+	other := authenticator{secret: []byte("other-secret")}
+	if _, err := other.verify(token, time.Now()); err == nil {
+		t.Error("token signed with another secret was accepted")
+	}
+	parts := strings.Split(token, ".")
+	forged, _ := other.mint("mallory", nil, time.Hour)
+	if _, err := auth.verify(parts[0]+"."+strings.Split(forged, ".")[1]+"."+parts[2], time.Now()); err == nil {
+		t.Error("token with a swapped payload was accepted")
+	}
+}
+
+func TestDevHeaders(t *testing.T) {
+	r := httptest.NewRequest("GET", "/teams", nil)
+	r.Header.Set(devUserHeader, "bob")
+	r.Header.Set(devGroupsHeader, "eggcarton-ops-reader")
+
+	if _, err := (authenticator{secret: []byte("s")}).claims(r); err == nil {
+		t.Error("dev header accepted outside dev mode")
+	}
+	claims, err := authenticator{secret: []byte("s"), dev: true}.claims(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims["sub"] != "bob" || claims["cognito:groups"] != "[eggcarton-ops-reader]" {
+		t.Errorf("unexpected claims %v", claims)
+	}
+}
+
+func TestIsLoopback(t *testing.T) {
+	tests := []struct {
+		addr string
+		want bool
+	}{
+		{"127.0.0.1:8787", true},
+		{"localhost:8787", true},
+		{"[::1]:8787", true},
+		{":8787", false},
+		{"0.0.0.0:8787", false},
+		{"192.168.1.20:8787", false},
+		{"8787", false},
+	}
+	for _, tt := range tests {
+		if got := isLoopback(tt.addr); got != tt.want {
+			t.Errorf("isLoopback(%q) = %v, want %v", tt.addr, got, tt.want)
+		}
+	}
+}
+
+func TestNewRequest(t *testing.T) {
+	const routeKey = "GET /eggs/{owner}/{secretId}/versions/{version}"
+	var got map[string]string
+	mux := http.NewServeMux()
+	mux.HandleFunc(routeKey, func(w http.ResponseWriter, r *http.Request) {
+		request, err := newRequest(r, routeKey, map[string]string{"sub": "alice"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = request.PathParameters
+		if request.QueryStringParameters["project"] != "api" || request.Headers["user-agent"] != "egg-test" {
+			t.Errorf("unexpected request %+v", request)
+		}
+		if request.RequestContext.Authorizer.JWT.Claims["sub"] != "alice" || request.RequestContext.HTTP.Method != "GET" {
+			t.Errorf("unexpected request context %+v", request.RequestContext)
+		}
+	})
+
+	r := httptest.NewRequest("GET", "/eggs/alice/DB_URL/versions/3?project=api", nil)
+	r.Header.Set("User-Agent", "egg-test")
+	mux.ServeHTTP(httptest.NewRecorder(), r)
+	if got["owner"] != "alice" || got["secretId"] != "DB_URL" || got["version"] != "3" {
+		t.Errorf("unexpected path parameters %v", got)
+	}
+}
+
+func TestDiscovery(t *testing.T) {
+	h := handlers.Handlers{Logger: slog.New(slog.NewJSONHandler(io.Discard, nil))}
+	mux := http.NewServeMux()
+	for routeKey, handler := range h.Routes() {
+		mux.Handle(routeKey, serve(routeKey, handler, authenticator{secret: []byte("s")}))
+	}
+
+	// Served without a token, pointing back at the server
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("GET", "http://localhost:8787/.well-known/eggcarton", nil))
+	var document handlers.DiscoveryResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &document); err != nil {
+		t.Fatalf("status %d: %v", w.Code, err)
+	}
+	if w.Code != http.StatusOK || document.Service != "eggcarton" || document.APIEndpoint != "http://localhost:8787" || document.Cognito != nil {
+		t.Errorf("unexpected document %d %+v", w.Code, document)
+	}
+	if document.APIVersion != handlers.APIVersion || len(document.Features) == 0 {
+		t.Errorf("versions or features missing: %+v", document)
+	}
+
+	// Every other route still needs one
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("GET", "/teams", nil))
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("expected 401 without a token, got %d", w.Code)
+	}
+}
@@ -2,11 +2,16 @@ module github.com/owenHochwald/egg-carton
 
 go 1.25.4
 
-require github.com/aws/aws-sdk-go-v2 v1.41.1
+require (
+	github.com/aws/aws-sdk-go-v2 v1.41.1
+	go.etcd.io/bbolt v1.4.3
+	golang.org/x/crypto v0.45.0
+)
 
 require (
 	github.com/aws/aws-sdk-go-v2/service/dynamodbstreams v1.32.10 // indirect
 	github.com/aws/aws-sdk-go-v2/service/internal/endpoint-discovery v1.11.17 // indirect
+	golang.org/x/sys v0.38.0 // indirect
 )
 
 require (
@@ -26,5 +31,5 @@ require (
 	github.com/aws/aws-sdk-go-v2/service/sso v1.30.9 // indirect
 	github.com/aws/aws-sdk-go-v2/service/ssooidc v1.35.13 // indirect
 	github.com/aws/aws-sdk-go-v2/service/sts v1.41.6 // indirect
-	github.com/aws/smithy-go v1.24.0 // indirect
+	github.com/aws/smithy-go v1.24.0
 )
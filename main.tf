@@ -29,6 +29,12 @@ resource "aws_dynamodb_table" "egg_carton" {
     type = "S"
   }
 
+  # Eggs laid with an expiry are deleted some time after ExpiresAt
+  ttl {
+    attribute_name = "ExpiresAt"
+    enabled        = true
+  }
+
   server_side_encryption {
     enabled     = true
     kms_key_arn = aws_kms_key.vault_master.arn
@@ -45,6 +51,24 @@ resource "aws_kms_key" "vault_master" {
   enable_key_rotation     = true
 
 }
+
+# The key new data keys are wrapped under, and the old ones eggs may still be
+# wrapped under mid-rotation
+locals {
+  active_kms_key_arn    = var.kms_key_arn != "" ? var.kms_key_arn : aws_kms_key.vault_master.arn
+  retiring_kms_key_arns = [for arn in var.retiring_kms_key_arns : arn if arn != local.active_kms_key_arn]
+}
+
+# Signs the audit chain. HMAC key material never leaves KMS: the Lambda role
+# can still sign events, but forging one takes a kms:GenerateMac call that
+# CloudTrail records, not just write access to the table.
+resource "aws_kms_key" "audit_mac" {
+  description              = "HMAC key for signing the audit log"
+  key_usage                = "GENERATE_VERIFY_MAC"
+  customer_master_key_spec = "HMAC_256"
+  deletion_window_in_days  = 7
+}
+
 resource "aws_kms_alias" "vault_master_alias" {
   name          = "alias/eggcarton-master"
   target_key_id = aws_kms_key.vault_master.key_id
@@ -87,10 +111,13 @@ resource "aws_iam_policy" "lambda_dynamodb_policy" {
         Effect = "Allow"
         Action = [
           "dynamodb:GetItem",
+          "dynamodb:BatchGetItem",
           "dynamodb:PutItem",
           "dynamodb:DeleteItem",
           "dynamodb:Query",
           "dynamodb:Scan",
+          "dynamodb:BatchWriteItem",
+          "dynamodb:ConditionCheckItem",
           "dynamodb:ExecuteStatement",
           "dynamodb:PartiQLInsert",
           "dynamodb:PartiQLSelect",
@@ -98,6 +125,28 @@ resource "aws_iam_policy" "lambda_dynamodb_policy" {
           "dynamodb:PartiQLDelete"
         ]
         Resource = aws_dynamodb_table.egg_carton.arn
+      },
+      {
+        # Events on AUDIT# partitions can't be updated or deleted. Every
+        # handler appends events, so PutItem stays allowed and this role can
+        # still overwrite a stored event with a plain put. The hash chain
+        # alone wouldn't show that, as hashes can be recomputed; the MAC on
+        # each event, made with the audit_mac key, would (see
+        # VerifyAuditChain)
+        Effect = "Deny"
+        Action = [
+          "dynamodb:DeleteItem",
+          "dynamodb:UpdateItem",
+          "dynamodb:BatchWriteItem",
+          "dynamodb:PartiQLUpdate",
+          "dynamodb:PartiQLDelete"
+        ]
+        Resource = aws_dynamodb_table.egg_carton.arn
+        Condition = {
+          "ForAnyValue:StringLike" = {
+            "dynamodb:LeadingKeys" = ["AUDIT#*"]
+          }
+        }
       }
     ]
   })
@@ -110,18 +159,35 @@ resource "aws_iam_policy" "lambda_kms_policy" {
 
   policy = jsonencode({
     Version = "2012-10-17"
-    Statement = [
-      {
-        Effect = "Allow"
-        Action = [
-          "kms:Decrypt",
-          "kms:Encrypt",
-          "kms:GenerateDataKey",
-          "kms:DescribeKey"
-        ]
-        Resource = aws_kms_key.vault_master.arn
-      }
-    ]
+    Statement = concat(
+      [
+        {
+          Effect = "Allow"
+          Action = [
+            "kms:Decrypt",
+            "kms:Encrypt",
+            "kms:GenerateDataKey",
+            "kms:DescribeKey"
+          ]
+          Resource = local.active_kms_key_arn
+        }
+      ],
+      [
+        {
+          Effect   = "Allow"
+          Action   = ["kms:GenerateMac", "kms:VerifyMac"]
+          Resource = aws_kms_key.audit_mac.arn
+        }
+      ],
+      # Only unwrapping: nothing new is wrapped under a retiring key
+      [
+        for arn in local.retiring_kms_key_arns : {
+          Effect   = "Allow"
+          Action   = ["kms:Decrypt", "kms:DescribeKey"]
+          Resource = arn
+        }
+      ]
+    )
   })
 }
 
@@ -153,8 +219,9 @@ resource "aws_lambda_function" "put_egg" {
 
   environment {
     variables = {
-      TABLE_NAME = aws_dynamodb_table.egg_carton.name
-      KMS_KEY_ID = aws_kms_key.vault_master.key_id
+      TABLE_NAME   = aws_dynamodb_table.egg_carton.name
+      KMS_KEY_ID   = local.active_kms_key_arn
+      AUDIT_KEY_ID = aws_kms_key.audit_mac.arn
     }
   }
 
@@ -175,8 +242,9 @@ resource "aws_lambda_function" "get_egg" {
 
   environment {
     variables = {
-      TABLE_NAME = aws_dynamodb_table.egg_carton.name
-      KMS_KEY_ID = aws_kms_key.vault_master.key_id
+      TABLE_NAME   = aws_dynamodb_table.egg_carton.name
+      KMS_KEY_ID   = local.active_kms_key_arn
+      AUDIT_KEY_ID = aws_kms_key.audit_mac.arn
     }
   }
 
@@ -197,8 +265,101 @@ resource "aws_lambda_function" "break_egg" {
 
   environment {
     variables = {
-      TABLE_NAME = aws_dynamodb_table.egg_carton.name
-      KMS_KEY_ID = aws_kms_key.vault_master.key_id
+      TABLE_NAME      = aws_dynamodb_table.egg_carton.name
+      KMS_KEY_ID      = local.active_kms_key_arn
+      TRASH_RETENTION = var.trash_retention
+      AUDIT_KEY_ID    = aws_kms_key.audit_mac.arn
+    }
+  }
+
+  tags = {
+    Project = "EggCarton"
+  }
+}
+
+resource "aws_lambda_function" "egg_history" {
+  filename      = "lambda/egg_history.zip"
+  function_name = "eggcarton_egg_history"
+  role          = aws_iam_role.lambda_exec.arn
+  handler       = "bootstrap"
+  runtime       = "provided.al2023"
+  timeout       = 30
+
+  source_code_hash = fileexists("lambda/egg_history.zip") ? filebase64sha256("lambda/egg_history.zip") : null
+
+  environment {
+    variables = {
+      TABLE_NAME   = aws_dynamodb_table.egg_carton.name
+      KMS_KEY_ID   = local.active_kms_key_arn
+      AUDIT_KEY_ID = aws_kms_key.audit_mac.arn
+    }
+  }
+
+  tags = {
+    Project = "EggCarton"
+  }
+}
+
+resource "aws_lambda_function" "teams" {
+  filename      = "lambda/teams.zip"
+  function_name = "eggcarton_teams"
+  role          = aws_iam_role.lambda_exec.arn
+  handler       = "bootstrap"
+  runtime       = "provided.al2023"
+  timeout       = 30
+
+  source_code_hash = fileexists("lambda/teams.zip") ? filebase64sha256("lambda/teams.zip") : null
+
+  environment {
+    variables = {
+      TABLE_NAME   = aws_dynamodb_table.egg_carton.name
+      AUDIT_KEY_ID = aws_kms_key.audit_mac.arn
+    }
+  }
+
+  tags = {
+    Project = "EggCarton"
+  }
+}
+
+resource "aws_lambda_function" "audit" {
+  filename      = "lambda/audit.zip"
+  function_name = "eggcarton_audit"
+  role          = aws_iam_role.lambda_exec.arn
+  handler       = "bootstrap"
+  runtime       = "provided.al2023"
+  timeout       = 30
+
+  source_code_hash = fileexists("lambda/audit.zip") ? filebase64sha256("lambda/audit.zip") : null
+
+  environment {
+    variables = {
+      TABLE_NAME   = aws_dynamodb_table.egg_carton.name
+      AUDIT_KEY_ID = aws_kms_key.audit_mac.arn
+    }
+  }
+
+  tags = {
+    Project = "EggCarton"
+  }
+}
+
+resource "aws_lambda_function" "discovery" {
+  filename      = "lambda/discovery.zip"
+  function_name = "eggcarton_discovery"
+  role          = aws_iam_role.lambda_exec.arn
+  handler       = "bootstrap"
+  runtime       = "provided.al2023"
+  timeout       = 10
+
+  source_code_hash = fileexists("lambda/discovery.zip") ? filebase64sha256("lambda/discovery.zip") : null
+
+  environment {
+    variables = {
+      COGNITO_USER_POOL_ID = aws_cognito_user_pool.eggcarton_pool.id
+      COGNITO_CLIENT_ID    = aws_cognito_user_pool_client.eggcarton_client.id
+      COGNITO_DOMAIN       = "${aws_cognito_user_pool_domain.eggcarton_domain.domain}.auth.${var.aws_region}.amazoncognito.com"
+      COGNITO_REGION       = var.aws_region
     }
   }
 
@@ -282,6 +443,34 @@ resource "aws_apigatewayv2_integration" "break_egg" {
   payload_format_version = "2.0"
 }
 
+resource "aws_apigatewayv2_integration" "egg_history" {
+  api_id                 = aws_apigatewayv2_api.eggcarton_api.id
+  integration_type       = "AWS_PROXY"
+  integration_uri        = aws_lambda_function.egg_history.invoke_arn
+  payload_format_version = "2.0"
+}
+
+resource "aws_apigatewayv2_integration" "teams" {
+  api_id                 = aws_apigatewayv2_api.eggcarton_api.id
+  integration_type       = "AWS_PROXY"
+  integration_uri        = aws_lambda_function.teams.invoke_arn
+  payload_format_version = "2.0"
+}
+
+resource "aws_apigatewayv2_integration" "audit" {
+  api_id                 = aws_apigatewayv2_api.eggcarton_api.id
+  integration_type       = "AWS_PROXY"
+  integration_uri        = aws_lambda_function.audit.invoke_arn
+  payload_format_version = "2.0"
+}
+
+resource "aws_apigatewayv2_integration" "discovery" {
+  api_id                 = aws_apigatewayv2_api.eggcarton_api.id
+  integration_type       = "AWS_PROXY"
+  integration_uri        = aws_lambda_function.discovery.invoke_arn
+  payload_format_version = "2.0"
+}
+
 # API Gateway Routes with Cognito Authorization
 resource "aws_apigatewayv2_route" "put_egg" {
   api_id             = aws_apigatewayv2_api.eggcarton_api.id
@@ -291,6 +480,14 @@ resource "aws_apigatewayv2_route" "put_egg" {
   authorizer_id      = aws_apigatewayv2_authorizer.cognito.id
 }
 
+resource "aws_apigatewayv2_route" "put_eggs" {
+  api_id             = aws_apigatewayv2_api.eggcarton_api.id
+  route_key          = "POST /eggs:batch"
+  target             = "integrations/${aws_apigatewayv2_integration.put_egg.id}"
+  authorization_type = "JWT"
+  authorizer_id      = aws_apigatewayv2_authorizer.cognito.id
+}
+
 resource "aws_apigatewayv2_route" "get_egg" {
   api_id             = aws_apigatewayv2_api.eggcarton_api.id
   route_key          = "GET /eggs/{owner}"
@@ -299,6 +496,14 @@ resource "aws_apigatewayv2_route" "get_egg" {
   authorizer_id      = aws_apigatewayv2_authorizer.cognito.id
 }
 
+resource "aws_apigatewayv2_route" "get_single_egg" {
+  api_id             = aws_apigatewayv2_api.eggcarton_api.id
+  route_key          = "GET /eggs/{owner}/{secretId}"
+  target             = "integrations/${aws_apigatewayv2_integration.get_egg.id}"
+  authorization_type = "JWT"
+  authorizer_id      = aws_apigatewayv2_authorizer.cognito.id
+}
+
 resource "aws_apigatewayv2_route" "break_egg" {
   api_id             = aws_apigatewayv2_api.eggcarton_api.id
   route_key          = "DELETE /eggs/{owner}/{secretId}"
@@ -307,6 +512,110 @@ resource "aws_apigatewayv2_route" "break_egg" {
   authorizer_id      = aws_apigatewayv2_authorizer.cognito.id
 }
 
+resource "aws_apigatewayv2_route" "break_eggs" {
+  api_id             = aws_apigatewayv2_api.eggcarton_api.id
+  route_key          = "DELETE /eggs:batch"
+  target             = "integrations/${aws_apigatewayv2_integration.break_egg.id}"
+  authorization_type = "JWT"
+  authorizer_id      = aws_apigatewayv2_authorizer.cognito.id
+}
+
+resource "aws_apigatewayv2_route" "list_trash" {
+  api_id             = aws_apigatewayv2_api.eggcarton_api.id
+  route_key          = "GET /trash/{owner}"
+  target             = "integrations/${aws_apigatewayv2_integration.break_egg.id}"
+  authorization_type = "JWT"
+  authorizer_id      = aws_apigatewayv2_authorizer.cognito.id
+}
+
+resource "aws_apigatewayv2_route" "restore_trashed_egg" {
+  api_id             = aws_apigatewayv2_api.eggcarton_api.id
+  route_key          = "POST /trash/{owner}/{secretId}/restore"
+  target             = "integrations/${aws_apigatewayv2_integration.break_egg.id}"
+  authorization_type = "JWT"
+  authorizer_id      = aws_apigatewayv2_authorizer.cognito.id
+}
+
+resource "aws_apigatewayv2_route" "list_egg_versions" {
+  api_id             = aws_apigatewayv2_api.eggcarton_api.id
+  route_key          = "GET /eggs/{owner}/{secretId}/versions"
+  target             = "integrations/${aws_apigatewayv2_integration.egg_history.id}"
+  authorization_type = "JWT"
+  authorizer_id      = aws_apigatewayv2_authorizer.cognito.id
+}
+
+resource "aws_apigatewayv2_route" "get_egg_version" {
+  api_id             = aws_apigatewayv2_api.eggcarton_api.id
+  route_key          = "GET /eggs/{owner}/{secretId}/versions/{version}"
+  target             = "integrations/${aws_apigatewayv2_integration.egg_history.id}"
+  authorization_type = "JWT"
+  authorizer_id      = aws_apigatewayv2_authorizer.cognito.id
+}
+
+resource "aws_apigatewayv2_route" "restore_egg_version" {
+  api_id             = aws_apigatewayv2_api.eggcarton_api.id
+  route_key          = "POST /eggs/{owner}/{secretId}/versions/{version}/restore"
+  target             = "integrations/${aws_apigatewayv2_integration.egg_history.id}"
+  authorization_type = "JWT"
+  authorizer_id      = aws_apigatewayv2_authorizer.cognito.id
+}
+
+resource "aws_apigatewayv2_route" "create_team" {
+  api_id             = aws_apigatewayv2_api.eggcarton_api.id
+  route_key          = "POST /teams"
+  target             = "integrations/${aws_apigatewayv2_integration.teams.id}"
+  authorization_type = "JWT"
+  authorizer_id      = aws_apigatewayv2_authorizer.cognito.id
+}
+
+resource "aws_apigatewayv2_route" "list_teams" {
+  api_id             = aws_apigatewayv2_api.eggcarton_api.id
+  route_key          = "GET /teams"
+  target             = "integrations/${aws_apigatewayv2_integration.teams.id}"
+  authorization_type = "JWT"
+  authorizer_id      = aws_apigatewayv2_authorizer.cognito.id
+}
+
+resource "aws_apigatewayv2_route" "list_team_members" {
+  api_id             = aws_apigatewayv2_api.eggcarton_api.id
+  route_key          = "GET /teams/{team}/members"
+  target             = "integrations/${aws_apigatewayv2_integration.teams.id}"
+  authorization_type = "JWT"
+  authorizer_id      = aws_apigatewayv2_authorizer.cognito.id
+}
+
+resource "aws_apigatewayv2_route" "put_team_member" {
+  api_id             = aws_apigatewayv2_api.eggcarton_api.id
+  route_key          = "PUT /teams/{team}/members/{member}"
+  target             = "integrations/${aws_apigatewayv2_integration.teams.id}"
+  authorization_type = "JWT"
+  authorizer_id      = aws_apigatewayv2_authorizer.cognito.id
+}
+
+resource "aws_apigatewayv2_route" "remove_team_member" {
+  api_id             = aws_apigatewayv2_api.eggcarton_api.id
+  route_key          = "DELETE /teams/{team}/members/{member}"
+  target             = "integrations/${aws_apigatewayv2_integration.teams.id}"
+  authorization_type = "JWT"
+  authorizer_id      = aws_apigatewayv2_authorizer.cognito.id
+}
+
+resource "aws_apigatewayv2_route" "audit" {
+  api_id             = aws_apigatewayv2_api.eggcarton_api.id
+  route_key          = "GET /audit"
+  target             = "integrations/${aws_apigatewayv2_integration.audit.id}"
+  authorization_type = "JWT"
+  authorizer_id      = aws_apigatewayv2_authorizer.cognito.id
+}
+
+# Public discovery document, read by egg init before the user has logged in
+resource "aws_apigatewayv2_route" "discovery" {
+  api_id             = aws_apigatewayv2_api.eggcarton_api.id
+  route_key          = "GET /.well-known/eggcarton"
+  target             = "integrations/${aws_apigatewayv2_integration.discovery.id}"
+  authorization_type = "NONE"
+}
+
 # Lambda Permissions for API Gateway
 resource "aws_lambda_permission" "put_egg" {
   statement_id  = "AllowExecutionFromAPIGateway"
@@ -332,6 +641,38 @@ resource "aws_lambda_permission" "break_egg" {
   source_arn    = "${aws_apigatewayv2_api.eggcarton_api.execution_arn}/*/*"
 }
 
+resource "aws_lambda_permission" "egg_history" {
+  statement_id  = "AllowExecutionFromAPIGateway"
+  action        = "lambda:InvokeFunction"
+  function_name = aws_lambda_function.egg_history.function_name
+  principal     = "apigateway.amazonaws.com"
+  source_arn    = "${aws_apigatewayv2_api.eggcarton_api.execution_arn}/*/*"
+}
+
+resource "aws_lambda_permission" "teams" {
+  statement_id  = "AllowExecutionFromAPIGateway"
+  action        = "lambda:InvokeFunction"
+  function_name = aws_lambda_function.teams.function_name
+  principal     = "apigateway.amazonaws.com"
+  source_arn    = "${aws_apigatewayv2_api.eggcarton_api.execution_arn}/*/*"
+}
+
+resource "aws_lambda_permission" "audit" {
+  statement_id  = "AllowExecutionFromAPIGateway"
+  action        = "lambda:InvokeFunction"
+  function_name = aws_lambda_function.audit.function_name
+  principal     = "apigateway.amazonaws.com"
+  source_arn    = "${aws_apigatewayv2_api.eggcarton_api.execution_arn}/*/*"
+}
+
+resource "aws_lambda_permission" "discovery" {
+  statement_id  = "AllowExecutionFromAPIGateway"
+  action        = "lambda:InvokeFunction"
+  function_name = aws_lambda_function.discovery.function_name
+  principal     = "apigateway.amazonaws.com"
+  source_arn    = "${aws_apigatewayv2_api.eggcarton_api.execution_arn}/*/*"
+}
+
 # Outputs
 output "api_endpoint" {
   description = "API Gateway endpoint URL"
@@ -343,7 +684,16 @@ output "kms_key_id" {
   value       = aws_kms_key.vault_master.key_id
 }
 
+output "kms_key_arn" {
+  description = "KMS key new data keys are wrapped under"
+  value       = local.active_kms_key_arn
+}
+
 output "dynamodb_table_name" {
   description = "DynamoDB table name"
   value       = aws_dynamodb_table.egg_carton.name
-}
\ No newline at end of file
+}
+output "egg_init" {
+  description = "Command that configures the CLI for this deployment"
+  value       = "egg init --endpoint ${aws_apigatewayv2_stage.eggcarton_stage.invoke_url}"
+}
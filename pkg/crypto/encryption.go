@@ -4,13 +4,17 @@ import (
 	"crypto/aes"
 	"crypto/cipher"
 	"crypto/rand"
+	"encoding/binary"
 	"errors"
 	"io"
 )
 
 // EncryptWithAESGCM encrypts plaintext using AES-256-GCM with the provided key.
+// New code should use Seal, which records the algorithm alongside the data.
+// additionalData is authenticated but not encrypted; the same bytes must be
+// passed to DecryptWithAESGCM. It may be nil.
 // Returns: ciphertext (includes nonce prepended) or error
-func EncryptWithAESGCM(plaintext []byte, key []byte) ([]byte, error) {
+func EncryptWithAESGCM(plaintext []byte, key []byte, additionalData []byte) ([]byte, error) {
 	block, err := aes.NewCipher(key)
 	if err != nil {
 		return nil, err
@@ -27,13 +31,14 @@ func EncryptWithAESGCM(plaintext []byte, key []byte) ([]byte, error) {
 	}
 
 	// Seal appends the ciphertext to nonce, so we get: nonce || ciphertext || tag
-	ciphertext := gcm.Seal(nonce, nonce, plaintext, nil)
+	ciphertext := gcm.Seal(nonce, nonce, plaintext, additionalData)
 	return ciphertext, nil
 }
 
 // DecryptWithAESGCM decrypts ciphertext using AES-256-GCM with the provided key.
 // Expects ciphertext format: nonce || encrypted_data || tag
-func DecryptWithAESGCM(ciphertext []byte, key []byte) ([]byte, error) {
+// Fails if additionalData differs from what the ciphertext was sealed with.
+func DecryptWithAESGCM(ciphertext []byte, key []byte, additionalData []byte) ([]byte, error) {
 	block, err := aes.NewCipher(key)
 	if err != nil {
 		return nil, err
@@ -50,10 +55,32 @@ func DecryptWithAESGCM(ciphertext []byte, key []byte) ([]byte, error) {
 	}
 
 	nonce, ciphertext := ciphertext[:nonceSize], ciphertext[nonceSize:]
-	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, additionalData)
 	if err != nil {
 		return nil, err
 	}
 
 	return plaintext, nil
 }
+
+// gcmOverhead is the bytes EncryptWithAESGCM adds around the plaintext: a
+// 12-byte nonce and a 16-byte authentication tag.
+const gcmOverhead = 12 + 16
+
+// EncryptionContext is the KMS encryption context that binds a data key to
+// one secret. KMS refuses to decrypt the key under any other context.
+func EncryptionContext(owner, secretID string) map[string]string {
+	return map[string]string{"Owner": owner, "SecretID": secretID}
+}
+
+// AdditionalData is the AES-GCM additional data that binds a ciphertext to
+// one secret. Each field is length-prefixed so that no two (owner, secretID)
+// pairs encode to the same bytes.
+func AdditionalData(owner, secretID string) []byte {
+	aad := make([]byte, 0, 8+len(owner)+len(secretID))
+	aad = binary.BigEndian.AppendUint32(aad, uint32(len(owner)))
+	aad = append(aad, owner...)
+	aad = binary.BigEndian.AppendUint32(aad, uint32(len(secretID)))
+	aad = append(aad, secretID...)
+	return aad
+}
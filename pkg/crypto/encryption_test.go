@@ -0,0 +1,52 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto/rand"
+	"testing"
+)
+
+func TestAESGCMRoundTrip(t *testing.T) {
+	key := make([]byte, 32)
+	rand.Read(key)
+	aad := AdditionalData("alice", "API_KEY")
+
+	ciphertext, err := EncryptWithAESGCM([]byte("sk-secret"), key, aad)
+	if err != nil {
+		t.Fatalf("EncryptWithAESGCM: %v", err)
+	}
+	if got := PlaintextSize(ciphertext); got != len("sk-secret") {
+		t.Errorf("PlaintextSize = %d, want %d", got, len("sk-secret"))
+	}
+
+	plaintext, err := DecryptWithAESGCM(ciphertext, key, aad)
+	if err != nil {
+		t.Fatalf("DecryptWithAESGCM: %v", err)
+	}
+	if !bytes.Equal(plaintext, []byte("sk-secret")) {
+		t.Errorf("got %q, want sk-secret", plaintext)
+	}
+}
+
+func TestAESGCMRejectsMismatchedBinding(t *testing.T) {
+	key := make([]byte, 32)
+	rand.Read(key)
+	ciphertext, err := EncryptWithAESGCM([]byte("sk-secret"), key, AdditionalData("alice", "API_KEY"))
+	if err != nil {
+		t.Fatalf("EncryptWithAESGCM: %v", err)
+	}
+
+	for _, tc := range []struct {
+		name string
+		aad  []byte
+	}{
+		{"other owner", AdditionalData("mallory", "API_KEY")},
+		{"other secret", AdditionalData("alice", "DB_URL")},
+		{"shifted boundary", AdditionalData("aliceA", "PI_KEY")},
+		{"no binding", nil},
+	} {
+		if _, err := DecryptWithAESGCM(ciphertext, key, tc.aad); err == nil {
+			t.Errorf("%s: decryption succeeded, want failure", tc.name)
+		}
+	}
+}
@@ -0,0 +1,205 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+// Envelope layout (all integers big-endian):
+//
+//	magic        4 bytes  "EGG\x00"
+//	version      1 byte   format version, currently 1
+//	algorithm    1 byte   Algorithm ID
+//	key ref len  2 bytes
+//	key ref      n bytes  which key wrapped the data key, e.g. a KMS key ID
+//	nonce len    1 byte
+//	nonce        n bytes
+//	ciphertext   rest     sealed plaintext including the AEAD tag
+//
+// Everything before the ciphertext is authenticated as part of the AEAD
+// additional data, so the header can't be altered without Open failing.
+//
+// Blobs that don't start with the magic are legacy EncryptWithAESGCM output
+// (nonce || ciphertext || tag) and are still accepted by Open.
+
+// EnvelopeVersion is the format version Seal writes.
+const EnvelopeVersion = 1
+
+var envelopeMagic = []byte("EGG\x00")
+
+// Algorithm identifies the AEAD cipher an envelope was sealed with.
+type Algorithm byte
+
+const (
+	AES256GCM         Algorithm = 1
+	XChaCha20Poly1305 Algorithm = 2
+)
+
+func (a Algorithm) String() string {
+	switch a {
+	case AES256GCM:
+		return "AES-256-GCM"
+	case XChaCha20Poly1305:
+		return "XChaCha20-Poly1305"
+	default:
+		return fmt.Sprintf("Algorithm(%d)", byte(a))
+	}
+}
+
+// ErrInvalidEnvelope is returned when a blob has the envelope magic but a
+// malformed header.
+var ErrInvalidEnvelope = errors.New("invalid envelope")
+
+// Envelope is a decoded ciphertext blob.
+type Envelope struct {
+	Version    byte
+	Algorithm  Algorithm
+	KeyRef     string
+	Nonce      []byte
+	Ciphertext []byte // Includes the AEAD tag
+	// Legacy is set for blobs written before the envelope format existed.
+	// Only Nonce and Ciphertext are meaningful for them.
+	Legacy bool
+
+	header []byte
+}
+
+// Seal encrypts plaintext with key under algorithm and returns a
+// self-describing envelope. keyRef records which key wrapped the data key and
+// may be empty. additionalData is authenticated alongside the header and must
+// be passed again to Open.
+func Seal(algorithm Algorithm, key []byte, keyRef string, plaintext, additionalData []byte) ([]byte, error) {
+	aead, err := newAEAD(algorithm, key)
+	if err != nil {
+		return nil, err
+	}
+	if len(keyRef) > 0xffff {
+		return nil, fmt.Errorf("key reference too long")
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+
+	header := make([]byte, 0, len(envelopeMagic)+5+len(keyRef)+len(nonce))
+	header = append(header, envelopeMagic...)
+	header = append(header, EnvelopeVersion, byte(algorithm))
+	header = binary.BigEndian.AppendUint16(header, uint16(len(keyRef)))
+	header = append(header, keyRef...)
+	header = append(header, byte(len(nonce)))
+	header = append(header, nonce...)
+
+	return aead.Seal(header, nonce, plaintext, envelopeAAD(header, additionalData)), nil
+}
+
+// Open decrypts an envelope produced by Seal, or a legacy blob produced by
+// EncryptWithAESGCM. It fails if additionalData differs from what was sealed.
+func Open(blob, key, additionalData []byte) ([]byte, error) {
+	envelope, err := ParseEnvelope(blob)
+	if err != nil {
+		return nil, err
+	}
+	if envelope.Legacy {
+		return DecryptWithAESGCM(blob, key, additionalData)
+	}
+
+	aead, err := newAEAD(envelope.Algorithm, key)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := aead.Open(nil, envelope.Nonce, envelope.Ciphertext, envelopeAAD(envelope.header, additionalData))
+	if err != nil {
+		// A legacy blob whose random nonce happens to start with the magic
+		if legacy, legacyErr := DecryptWithAESGCM(blob, key, additionalData); legacyErr == nil {
+			return legacy, nil
+		}
+		return nil, err
+	}
+	return plaintext, nil
+}
+
+// ParseEnvelope decodes the header of a blob without decrypting it.
+func ParseEnvelope(blob []byte) (*Envelope, error) {
+	if !bytes.HasPrefix(blob, envelopeMagic) {
+		return parseLegacy(blob), nil
+	}
+
+	rest := blob[len(envelopeMagic):]
+	if len(rest) < 4 {
+		return nil, ErrInvalidEnvelope
+	}
+	envelope := &Envelope{Version: rest[0], Algorithm: Algorithm(rest[1])}
+	if envelope.Version != EnvelopeVersion {
+		return nil, fmt.Errorf("%w: unsupported format version %d", ErrInvalidEnvelope, envelope.Version)
+	}
+
+	keyRefLen := int(binary.BigEndian.Uint16(rest[2:4]))
+	rest = rest[4:]
+	if len(rest) < keyRefLen+1 {
+		return nil, ErrInvalidEnvelope
+	}
+	envelope.KeyRef = string(rest[:keyRefLen])
+	rest = rest[keyRefLen:]
+
+	nonceLen := int(rest[0])
+	rest = rest[1:]
+	if len(rest) < nonceLen {
+		return nil, ErrInvalidEnvelope
+	}
+	envelope.Nonce = rest[:nonceLen]
+	envelope.Ciphertext = rest[nonceLen:]
+	envelope.header = blob[:len(blob)-len(envelope.Ciphertext)]
+	return envelope, nil
+}
+
+// PlaintextSize reports how many bytes of plaintext a blob holds, without
+// decrypting it.
+func PlaintextSize(blob []byte) int {
+	envelope, err := ParseEnvelope(blob)
+	if err != nil || envelope.Legacy {
+		return max(len(blob)-gcmOverhead, 0)
+	}
+	return max(len(envelope.Ciphertext)-tagSize, 0)
+}
+
+// tagSize is the authentication tag length of every supported algorithm.
+const tagSize = 16
+
+func parseLegacy(blob []byte) *Envelope {
+	envelope := &Envelope{Algorithm: AES256GCM, Legacy: true}
+	if len(blob) >= 12 {
+		envelope.Nonce, envelope.Ciphertext = blob[:12], blob[12:]
+	}
+	return envelope
+}
+
+func newAEAD(algorithm Algorithm, key []byte) (cipher.AEAD, error) {
+	switch algorithm {
+	case AES256GCM:
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, err
+		}
+		return cipher.NewGCM(block)
+	case XChaCha20Poly1305:
+		return chacha20poly1305.NewX(key)
+	default:
+		return nil, fmt.Errorf("unsupported algorithm %v", algorithm)
+	}
+}
+
+// envelopeAAD authenticates the header together with the caller's data.
+func envelopeAAD(header, additionalData []byte) []byte {
+	aad := make([]byte, 0, len(header)+len(additionalData))
+	aad = append(aad, header...)
+	return append(aad, additionalData...)
+}
@@ -0,0 +1,77 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto/rand"
+	"testing"
+)
+
+func TestEnvelopeRoundTrip(t *testing.T) {
+	key := make([]byte, 32)
+	rand.Read(key)
+	aad := AdditionalData("alice", "API_KEY")
+
+	for _, algorithm := range []Algorithm{AES256GCM, XChaCha20Poly1305} {
+		t.Run(algorithm.String(), func(t *testing.T) {
+			blob, err := Seal(algorithm, key, "alias/egg-carton", []byte("sk-secret"), aad)
+			if err != nil {
+				t.Fatalf("Seal: %v", err)
+			}
+
+			envelope, err := ParseEnvelope(blob)
+			if err != nil {
+				t.Fatalf("ParseEnvelope: %v", err)
+			}
+			if envelope.Legacy || envelope.Algorithm != algorithm || envelope.KeyRef != "alias/egg-carton" {
+				t.Errorf("got header %+v", envelope)
+			}
+			if got := PlaintextSize(blob); got != len("sk-secret") {
+				t.Errorf("PlaintextSize = %d, want %d", got, len("sk-secret"))
+			}
+
+			plaintext, err := Open(blob, key, aad)
+			if err != nil {
+				t.Fatalf("Open: %v", err)
+			}
+			if !bytes.Equal(plaintext, []byte("sk-secret")) {
+				t.Errorf("got %q, want sk-secret", plaintext)
+			}
+
+			if _, err := Open(blob, key, AdditionalData("mallory", "API_KEY")); err == nil {
+				t.Error("Open succeeded with mismatched additional data")
+			}
+		})
+	}
+}
+
+func TestEnvelopeRejectsTamperedHeader(t *testing.T) {
+	key := make([]byte, 32)
+	rand.Read(key)
+	blob, err := Seal(AES256GCM, key, "key-a", []byte("sk-secret"), nil)
+	if err != nil {
+		t.Fatalf("Seal: %v", err)
+	}
+
+	// Swap the recorded key reference for another of the same length
+	tampered := bytes.Replace(blob, []byte("key-a"), []byte("key-b"), 1)
+	if _, err := Open(tampered, key, nil); err == nil {
+		t.Error("Open succeeded with a tampered header")
+	}
+}
+
+func TestEnvelopeOpensLegacyCiphertext(t *testing.T) {
+	key := make([]byte, 32)
+	rand.Read(key)
+	legacy, err := EncryptWithAESGCM([]byte("sk-secret"), key, nil)
+	if err != nil {
+		t.Fatalf("EncryptWithAESGCM: %v", err)
+	}
+
+	plaintext, err := Open(legacy, key, nil)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if !bytes.Equal(plaintext, []byte("sk-secret")) {
+		t.Errorf("got %q, want sk-secret", plaintext)
+	}
+}
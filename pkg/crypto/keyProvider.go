@@ -0,0 +1,107 @@
+package crypto
+
+import (
+	"context"
+	"encoding/binary"
+	"maps"
+	"slices"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+)
+
+// DataKey is a freshly generated data encryption key.
+type DataKey struct {
+	Plaintext []byte // Use for one Seal, then discard
+	Encrypted []byte // Wrapped under the master key; safe to store
+	KeyRef    string // Which master key wrapped it
+}
+
+// KeyProvider generates and unwraps the per-secret data keys used for
+// envelope encryption. The encryption context binds a wrapped key to one
+// secret: unwrapping fails unless the same context is passed again.
+type KeyProvider interface {
+	GenerateDataKey(ctx context.Context, encryptionContext map[string]string) (DataKey, error)
+	DecryptDataKey(ctx context.Context, encryptedKey []byte, encryptionContext map[string]string) ([]byte, error)
+}
+
+// KMSKeyProvider wraps data keys with an AWS KMS key.
+type KMSKeyProvider struct {
+	client *kms.Client
+	keyID  string
+}
+
+// NewKMSKeyProvider returns a KeyProvider that generates data keys under
+// keyID, which may be a key ID, ARN or alias.
+func NewKMSKeyProvider(client *kms.Client, keyID string) *KMSKeyProvider {
+	return &KMSKeyProvider{client: client, keyID: keyID}
+}
+
+func (p *KMSKeyProvider) GenerateDataKey(ctx context.Context, encryptionContext map[string]string) (DataKey, error) {
+	resp, err := p.client.GenerateDataKey(ctx, &kms.GenerateDataKeyInput{
+		KeyId:             aws.String(p.keyID),
+		KeySpec:           "AES_256",
+		EncryptionContext: encryptionContext,
+	})
+	if err != nil {
+		return DataKey{}, err
+	}
+	return DataKey{
+		Plaintext: resp.Plaintext,
+		Encrypted: resp.CiphertextBlob,
+		KeyRef:    aws.ToString(resp.KeyId),
+	}, nil
+}
+
+func (p *KMSKeyProvider) DecryptDataKey(ctx context.Context, encryptedKey []byte, encryptionContext map[string]string) ([]byte, error) {
+	// The wrapped key records its KMS key, so KeyId isn't needed here
+	resp, err := p.client.Decrypt(ctx, &kms.DecryptInput{
+		CiphertextBlob:    encryptedKey,
+		EncryptionContext: encryptionContext,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Plaintext, nil
+}
+
+// ReEncryptDataKey re-wraps a data key under the provider's KMS key. The
+// plaintext data key never leaves KMS. It returns the new wrapped key and the
+// ARN of the key now wrapping it.
+func (p *KMSKeyProvider) ReEncryptDataKey(ctx context.Context, encryptedKey []byte, encryptionContext map[string]string) ([]byte, string, error) {
+	resp, err := p.client.ReEncrypt(ctx, &kms.ReEncryptInput{
+		CiphertextBlob:               encryptedKey,
+		SourceEncryptionContext:      encryptionContext,
+		DestinationKeyId:             aws.String(p.keyID),
+		DestinationEncryptionContext: encryptionContext,
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	return resp.CiphertextBlob, aws.ToString(resp.KeyId), nil
+}
+
+// KeyARN resolves the provider's key ID or alias to the key ARN that KMS
+// reports for the data keys it wraps.
+func (p *KMSKeyProvider) KeyARN(ctx context.Context) (string, error) {
+	resp, err := p.client.DescribeKey(ctx, &kms.DescribeKeyInput{KeyId: aws.String(p.keyID)})
+	if err != nil {
+		return "", err
+	}
+	return aws.ToString(resp.KeyMetadata.Arn), nil
+}
+
+// contextAAD encodes an encryption context as additional data. Keys are
+// sorted and every key and value is length-prefixed, so equal maps always
+// encode to the same bytes and different maps never do.
+func contextAAD(encryptionContext map[string]string) []byte {
+	var aad []byte
+	for _, k := range slices.Sorted(maps.Keys(encryptionContext)) {
+		v := encryptionContext[k]
+		aad = binary.BigEndian.AppendUint32(aad, uint32(len(k)))
+		aad = append(aad, k...)
+		aad = binary.BigEndian.AppendUint32(aad, uint32(len(v)))
+		aad = append(aad, v...)
+	}
+	return aad
+}
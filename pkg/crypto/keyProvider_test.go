@@ -0,0 +1,73 @@
+package crypto
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"testing"
+)
+
+func TestLocalKeyProviderRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "master.key")
+	provider, err := NewLocalKeyProvider(path)
+	if err != nil {
+		t.Fatalf("NewLocalKeyProvider: %v", err)
+	}
+	encryptionContext := EncryptionContext("alice", "API_KEY")
+	aad := AdditionalData("alice", "API_KEY")
+
+	// Lay: wrap a fresh data key and seal the secret with it
+	dataKey, err := provider.GenerateDataKey(ctx, encryptionContext)
+	if err != nil {
+		t.Fatalf("GenerateDataKey: %v", err)
+	}
+	if dataKey.KeyRef != provider.KeyRef() {
+		t.Errorf("KeyRef = %q, want %q", dataKey.KeyRef, provider.KeyRef())
+	}
+	blob, err := Seal(AES256GCM, dataKey.Plaintext, dataKey.KeyRef, []byte("sk-secret"), aad)
+	if err != nil {
+		t.Fatalf("Seal: %v", err)
+	}
+
+	// Hatch: reload the master key from disk, unwrap and open
+	reloaded, err := NewLocalKeyProvider(path)
+	if err != nil {
+		t.Fatalf("NewLocalKeyProvider reload: %v", err)
+	}
+	key, err := reloaded.DecryptDataKey(ctx, dataKey.Encrypted, encryptionContext)
+	if err != nil {
+		t.Fatalf("DecryptDataKey: %v", err)
+	}
+	plaintext, err := Open(blob, key, aad)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if !bytes.Equal(plaintext, []byte("sk-secret")) {
+		t.Errorf("got %q, want sk-secret", plaintext)
+	}
+
+	if _, err := reloaded.DecryptDataKey(ctx, dataKey.Encrypted, EncryptionContext("mallory", "API_KEY")); err == nil {
+		t.Error("DecryptDataKey succeeded under another encryption context")
+	}
+
+	other, err := NewLocalKeyProvider(filepath.Join(t.TempDir(), "other.key"))
+	if err != nil {
+		t.Fatalf("NewLocalKeyProvider other: %v", err)
+	}
+	if _, err := other.DecryptDataKey(ctx, dataKey.Encrypted, encryptionContext); err == nil {
+		t.Error("DecryptDataKey succeeded with another master key")
+	}
+
+	// Rotate: re-wrap onto the other master key, which can then unwrap it
+	rewrapped, keyRef, err := other.ReEncryptDataKey(ctx, reloaded, dataKey.Encrypted, encryptionContext)
+	if err != nil {
+		t.Fatalf("ReEncryptDataKey: %v", err)
+	}
+	if keyRef != other.KeyRef() {
+		t.Errorf("re-wrapped under %q, want %q", keyRef, other.KeyRef())
+	}
+	if key, err := other.DecryptDataKey(ctx, rewrapped, encryptionContext); err != nil || !bytes.Equal(key, dataKey.Plaintext) {
+		t.Errorf("re-wrapped key doesn't unwrap to the data key: %v", err)
+	}
+}
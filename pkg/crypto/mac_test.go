@@ -0,0 +1,41 @@
+package crypto
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func TestLocalMACKey(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "audit.key")
+	key, err := NewLocalMACKey(path)
+	if err != nil {
+		t.Fatalf("NewLocalMACKey: %v", err)
+	}
+	mac, err := key.GenerateMAC(ctx, []byte("event"))
+	if err != nil {
+		t.Fatalf("GenerateMAC: %v", err)
+	}
+
+	// The key is reloaded from disk, not regenerated
+	reloaded, err := NewLocalMACKey(path)
+	if err != nil {
+		t.Fatalf("NewLocalMACKey reload: %v", err)
+	}
+	if err := reloaded.VerifyMAC(ctx, []byte("event"), mac); err != nil {
+		t.Errorf("VerifyMAC: %v", err)
+	}
+	if err := reloaded.VerifyMAC(ctx, []byte("edited"), mac); !errors.Is(err, ErrInvalidMAC) {
+		t.Errorf("VerifyMAC of another message = %v, want ErrInvalidMAC", err)
+	}
+
+	other, err := NewLocalMACKey(filepath.Join(t.TempDir(), "other.key"))
+	if err != nil {
+		t.Fatalf("NewLocalMACKey other: %v", err)
+	}
+	if err := other.VerifyMAC(ctx, []byte("event"), mac); !errors.Is(err, ErrInvalidMAC) {
+		t.Errorf("VerifyMAC with another key = %v, want ErrInvalidMAC", err)
+	}
+}
@@ -16,6 +16,24 @@ variable "google_client_secret" {
   sensitive   = true
 }
 
+variable "trash_retention" {
+  description = "How long broken secrets stay in the trash before being purged (Go duration)"
+  type        = string
+  default     = "720h"
+}
+
+variable "kms_key_arn" {
+  description = "KMS key the Lambdas wrap new data keys under; empty for the vault_master key. Set it to the new key when rotating (see Key Rotation in the README)"
+  type        = string
+  default     = ""
+}
+
+variable "retiring_kms_key_arns" {
+  description = "Old KMS keys the Lambdas may still unwrap data keys with, until cmd/rewrap_keys has moved every egg off them"
+  type        = list(string)
+  default     = []
+}
+
 # Optional: Chrome Extension ID
 variable "chrome_extension_id" {
   description = "Chrome Extension ID for callback URL configuration"